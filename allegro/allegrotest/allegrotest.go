@@ -0,0 +1,206 @@
+// Package allegrotest runs Allegro from go test.
+//
+// Allegro must be initialized and driven from the main OS thread, but go test
+// runs each test on its own goroutine, which can land on any thread. Main
+// keeps the main thread for Allegro and runs the tests alongside it, and Do
+// runs code on that thread:
+//
+//	func TestMain(m *testing.M) {
+//		allegrotest.Main(m, image.Install, primitives.Install)
+//	}
+//
+//	func TestDraw(t *testing.T) {
+//		allegrotest.Do(func() {
+//			allegrotest.Target().AsTarget(func() {
+//				// draw and check the result
+//			})
+//		})
+//	}
+//
+// By default no display is created, Target is a memory bitmap, and the new
+// bitmap flags are set to MEMORY_BITMAP. Pass -allegrotest.display to go test
+// to create a small frameless OpenGL window instead, for tests that need video
+// bitmaps or GLSL shaders; if it can't be created, the tests fall back to
+// running headless. Allegro 5.2 has no way to create a display without
+// showing it.
+package allegrotest
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"runtime"
+	"sync/atomic"
+	"testing"
+
+	"github.com/dradtke/go-allegro/allegro"
+)
+
+// TargetSize is the width and height of the headless target bitmap.
+const TargetSize = 256
+
+var useDisplay = flag.Bool("allegrotest.display", false, "run Allegro tests with a display instead of a memory bitmap")
+
+var (
+	calls   chan call
+	display *allegro.Display
+	target  *allegro.Bitmap
+
+	// broken is set to 1 once the Allegro thread's goroutine has exited. It
+	// is read by Do from any goroutine.
+	broken int32
+)
+
+type call struct {
+	f    func()
+	done chan result
+}
+
+type result struct {
+	panicked bool
+	exited   bool
+	value    interface{}
+}
+
+func init() {
+	// Package initialization happens on the main thread, so lock it to the
+	// main goroutine before anything else gets a chance to run there.
+	runtime.LockOSThread()
+}
+
+// Addon installs an addon, such as image.Install or primitives.Install.
+// Addons whose Install doesn't return an error can be wrapped:
+//
+//	func() error { font.Install(); return nil }
+type Addon func() error
+
+// Main initializes Allegro on the main thread, installs the given addons,
+// creates the target, and then runs the tests, exiting with their result. It
+// should be called from TestMain.
+func Main(m *testing.M, addons ...Addon) {
+	flag.Parse()
+	code := 1
+	allegro.Run(func() {
+		if err := setup(addons); err != nil {
+			fmt.Fprintln(os.Stderr, "allegrotest:", err)
+			return
+		}
+		defer teardown()
+
+		calls = make(chan call)
+		go func() {
+			defer close(calls)
+			code = m.Run()
+		}()
+		for c := range calls {
+			run(c)
+			if atomic.LoadInt32(&broken) != 0 {
+				// The thread's goroutine is exiting; nothing more can
+				// run on it.
+				return
+			}
+		}
+	})
+	os.Exit(code)
+}
+
+func setup(addons []Addon) error {
+	for _, install := range addons {
+		if err := install(); err != nil {
+			return err
+		}
+	}
+	if *useDisplay {
+		allegro.SetNewDisplayFlags(allegro.WINDOWED | allegro.FRAMELESS | allegro.OPENGL | allegro.PROGRAMMABLE_PIPELINE)
+		if d, err := allegro.CreateDisplay(TargetSize, TargetSize); err == nil {
+			display = d
+			target = d.Backbuffer()
+			return nil
+		}
+		fmt.Fprintln(os.Stderr, "allegrotest: failed to create display, running headless")
+	}
+	allegro.SetNewBitmapFlags(allegro.MEMORY_BITMAP)
+	target = allegro.CreateBitmap(TargetSize, TargetSize)
+	if target == nil {
+		return errors.New("failed to create target bitmap")
+	}
+	allegro.SetTargetBitmap(target)
+	return nil
+}
+
+func teardown() {
+	if display != nil {
+		display.Destroy()
+	} else if target != nil {
+		allegro.SetTargetBitmap(nil)
+		target.Destroy()
+	}
+	display, target = nil, nil
+}
+
+// run calls c.f and sends the result to c.done, catching panics so that
+// they can be passed back to the caller. If c.f exits the goroutine, as
+// t.FailNow does, broken is set.
+func run(c call) {
+	returned := false
+	defer func() {
+		if returned {
+			return
+		}
+		if v := recover(); v != nil {
+			c.done <- result{panicked: true, value: v}
+			return
+		}
+		atomic.StoreInt32(&broken, 1)
+		c.done <- result{exited: true}
+	}()
+	c.f()
+	returned = true
+	c.done <- result{}
+}
+
+// Do runs f on the Allegro thread and waits for it to return. A panic in f
+// is passed on to the caller of Do.
+//
+// f must not call t.FailNow, t.Fatal, t.Skip or anything else that exits the
+// goroutine, since that would take the Allegro thread with it; use t.Error,
+// or return a value to the test and check it there. Calls to Do must not be
+// nested.
+func Do(f func()) {
+	if calls == nil {
+		panic("allegrotest: Do called without allegrotest.Main")
+	}
+	if atomic.LoadInt32(&broken) != 0 {
+		panic("allegrotest: Allegro thread has exited")
+	}
+	done := make(chan result, 1)
+	calls <- call{f: f, done: done}
+	switch r := <-done; {
+	case r.panicked:
+		panic(r.value)
+	case r.exited:
+		panic("allegrotest: function passed to Do exited the Allegro thread")
+	}
+}
+
+// Display returns the test display, or nil if the tests are running
+// headless.
+func Display() *allegro.Display {
+	return display
+}
+
+// Target returns the bitmap that tests should draw to: the display's
+// backbuffer if there is one, or otherwise a TargetSize x TargetSize memory
+// bitmap. It is the target bitmap when the tests start.
+func Target() *allegro.Bitmap {
+	return target
+}
+
+// RequireDisplay skips the test if it is running headless.
+func RequireDisplay(t testing.TB) {
+	t.Helper()
+	if display == nil {
+		t.Skip("test requires a display; run with -allegrotest.display")
+	}
+}
@@ -0,0 +1,184 @@
+package allegrotest
+
+import (
+	"flag"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dradtke/go-allegro/allegro"
+)
+
+var update = flag.Bool("allegrotest.update", false, "regenerate golden image fixtures")
+
+// GoldenOptions controls how closely a rendered image has to match its
+// fixture.
+type GoldenOptions struct {
+	// Tolerance is the largest difference allowed in any one channel of a
+	// pixel, out of 255, before the pixel counts as different.
+	Tolerance uint8
+
+	// MaxDiffRatio is the fraction of pixels, from 0 to 1, that may differ
+	// before the comparison fails.
+	MaxDiffRatio float64
+}
+
+// Golden renders f into a width x height memory bitmap on the Allegro
+// thread and compares the result with the fixture testdata/<name>.png. The
+// bitmap starts out cleared to transparent black.
+//
+// If go test is run with -allegrotest.update, the fixture is written instead.
+// On failure, the actual, expected and diff images are written to a temporary
+// directory and their paths are logged.
+func Golden(t testing.TB, name string, width, height int, opts GoldenOptions, f func()) {
+	t.Helper()
+	var actual *image.NRGBA
+	Do(func() {
+		actual = render(width, height, f)
+	})
+	if actual == nil {
+		t.Fatalf("%s: failed to create %dx%d bitmap", name, width, height)
+	}
+	CompareGolden(t, name, actual, opts)
+}
+
+// render draws into a new memory bitmap and copies out its contents. It
+// must be called on the Allegro thread.
+func render(width, height int, f func()) *image.NRGBA {
+	flags := allegro.NewBitmapFlags()
+	allegro.SetNewBitmapFlags(allegro.MEMORY_BITMAP)
+	bmp := allegro.CreateBitmap(width, height)
+	allegro.SetNewBitmapFlags(flags)
+	if bmp == nil {
+		return nil
+	}
+	defer bmp.Destroy()
+	bmp.AsTarget(func() {
+		allegro.ClearToColor(allegro.MapRGBA(0, 0, 0, 0))
+		f()
+	})
+	img := image.NewNRGBA(bmp.Bounds())
+	draw.Draw(img, img.Rect, bmp, image.Point{}, draw.Src)
+	return img
+}
+
+// CompareGolden compares an image with the fixture testdata/<name>.png, like
+// Golden, for images that were rendered some other way. A *allegro.Bitmap can
+// be passed directly, but since reading its pixels calls into Allegro, that
+// has to happen within Do.
+func CompareGolden(t testing.TB, name string, actual image.Image, opts GoldenOptions) {
+	t.Helper()
+	path := filepath.Join("testdata", filepath.FromSlash(name)+".png")
+	if *update {
+		if err := writePNG(path, actual); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("%s: %v; run with -allegrotest.update to create it", name, err)
+	}
+	expected, err := png.Decode(f)
+	f.Close()
+	if err != nil {
+		t.Fatalf("%s: failed to decode '%s': %v", name, path, err)
+	}
+
+	diff, count := compare(actual, expected, opts.Tolerance)
+	if diff == nil {
+		dir := writeFailure(t, name, actual, expected, nil)
+		t.Fatalf("%s: image is %v, but fixture is %v; images written to %s",
+			name, actual.Bounds().Size(), expected.Bounds().Size(), dir)
+	}
+	total := diff.Rect.Dx() * diff.Rect.Dy()
+	if ratio := float64(count) / float64(total); count > 0 && ratio > opts.MaxDiffRatio {
+		dir := writeFailure(t, name, actual, expected, diff)
+		t.Fatalf("%s: %d of %d pixels (%.2f%%) differ by more than %d; images written to %s",
+			name, count, total, ratio*100, opts.Tolerance, dir)
+	}
+}
+
+// compare returns an image highlighting the pixels of actual that differ
+// from expected by more than tolerance in any channel, and the number of such
+// pixels. Pixels that are fully transparent in both images always match. It
+// returns nil if the images are different sizes.
+func compare(actual, expected image.Image, tolerance uint8) (*image.NRGBA, int) {
+	ab, eb := actual.Bounds(), expected.Bounds()
+	if ab.Size() != eb.Size() {
+		return nil, 0
+	}
+	diff := image.NewNRGBA(image.Rect(0, 0, ab.Dx(), ab.Dy()))
+	count := 0
+	for y := 0; y < ab.Dy(); y++ {
+		for x := 0; x < ab.Dx(); x++ {
+			a := color.NRGBAModel.Convert(actual.At(ab.Min.X+x, ab.Min.Y+y)).(color.NRGBA)
+			e := color.NRGBAModel.Convert(expected.At(eb.Min.X+x, eb.Min.Y+y)).(color.NRGBA)
+			d := maxDelta(a, e)
+			if a.A == 0 && e.A == 0 {
+				d = 0
+			}
+			if d > tolerance {
+				count++
+				diff.SetNRGBA(x, y, color.NRGBA{0xFF, 0, 0xFF - d, 0xFF})
+			} else {
+				// Show matching pixels as a faded copy of the
+				// expected image, for context.
+				l := uint8((uint16(e.R)*3+uint16(e.G)*6+uint16(e.B))/10) / 4
+				diff.SetNRGBA(x, y, color.NRGBA{0xC0 + l, 0xC0 + l, 0xC0 + l, 0xFF})
+			}
+		}
+	}
+	return diff, count
+}
+
+func maxDelta(a, b color.NRGBA) uint8 {
+	var max uint8
+	for _, d := range [4][2]uint8{{a.R, b.R}, {a.G, b.G}, {a.B, b.B}, {a.A, b.A}} {
+		delta := d[0] - d[1]
+		if d[1] > d[0] {
+			delta = d[1] - d[0]
+		}
+		if delta > max {
+			max = delta
+		}
+	}
+	return max
+}
+
+// writeFailure writes the images from a failed comparison to a temporary
+// directory and returns its path. diff may be nil.
+func writeFailure(t testing.TB, name string, actual, expected image.Image, diff image.Image) string {
+	t.Helper()
+	dir := filepath.Join(os.TempDir(), "allegrotest", filepath.FromSlash(name))
+	images := map[string]image.Image{"actual.png": actual, "expected.png": expected}
+	if diff != nil {
+		images["diff.png"] = diff
+	}
+	for file, img := range images {
+		if err := writePNG(filepath.Join(dir, file), img); err != nil {
+			t.Log(err)
+		}
+	}
+	return dir
+}
+
+func writePNG(path string, img image.Image) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(f, img); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
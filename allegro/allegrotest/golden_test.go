@@ -0,0 +1,42 @@
+package allegrotest
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestCompare(t *testing.T) {
+	expected := image.NewNRGBA(image.Rect(0, 0, 4, 4))
+	actual := image.NewNRGBA(image.Rect(10, 10, 14, 14))
+	for i := range expected.Pix {
+		expected.Pix[i] = 100
+		actual.Pix[i] = 100
+	}
+	actual.SetNRGBA(10, 10, color.NRGBA{102, 100, 100, 100})
+	actual.SetNRGBA(11, 10, color.NRGBA{100, 110, 100, 100})
+
+	if _, n := compare(actual, expected, 0); n != 2 {
+		t.Errorf("with no tolerance, %d pixels differ, want 2", n)
+	}
+	diff, n := compare(actual, expected, 5)
+	if n != 1 {
+		t.Errorf("with tolerance 5, %d pixels differ, want 1", n)
+	}
+	if c := diff.NRGBAAt(1, 0); c.R != 0xFF || c.G != 0 {
+		t.Errorf("diff pixel is %v, want it highlighted", c)
+	}
+
+	if diff, _ := compare(actual, image.NewNRGBA(image.Rect(0, 0, 4, 5)), 0); diff != nil {
+		t.Error("images of different sizes compared successfully")
+	}
+}
+
+func TestCompareIgnoresTransparentColor(t *testing.T) {
+	a := image.NewNRGBA(image.Rect(0, 0, 1, 1))
+	b := image.NewNRGBA(image.Rect(0, 0, 1, 1))
+	b.SetNRGBA(0, 0, color.NRGBA{0xFF, 0xFF, 0xFF, 0})
+	if _, n := compare(a, b, 0); n != 0 {
+		t.Errorf("%d pixels differ, want 0", n)
+	}
+}
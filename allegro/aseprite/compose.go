@@ -0,0 +1,261 @@
+package aseprite
+
+import (
+	"image"
+	"math"
+	"sort"
+)
+
+// BlendMode is the mode used to blend a layer onto the layers below it.
+type BlendMode int
+
+const (
+	BlendNormal BlendMode = iota
+	BlendMultiply
+	BlendScreen
+	BlendOverlay
+	BlendDarken
+	BlendLighten
+	BlendColorDodge
+	BlendColorBurn
+	BlendHardLight
+	BlendSoftLight
+	BlendDifference
+	BlendExclusion
+	BlendHue
+	BlendSaturation
+	BlendColor
+	BlendLuminosity
+	BlendAddition
+	BlendSubtract
+	BlendDivide
+)
+
+// Visible reports whether layer i and every group containing it are visible.
+// Reference layers are never considered visible.
+func (f *File) Visible(i int) bool {
+	for ; i >= 0 && i < len(f.Layers); i = f.Layers[i].Parent {
+		l := &f.Layers[i]
+		if l.Flags&LayerVisible == 0 || l.Flags&LayerReference != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// opacity returns the layer's opacity multiplied by that of its groups.
+func (f *File) opacity(i int) float64 {
+	o := 1.0
+	for ; i >= 0 && i < len(f.Layers); i = f.Layers[i].Parent {
+		o *= float64(f.Layers[i].Opacity) / 255
+	}
+	return o
+}
+
+// Composite flattens the visible layers of a frame into a single image the
+// size of the sprite, the way Aseprite displays it.
+func (f *File) Composite(frame int) *image.NRGBA {
+	canvas := image.NewNRGBA(image.Rect(0, 0, f.Width, f.Height))
+	if frame < 0 || frame >= len(f.Frames) {
+		return canvas
+	}
+
+	var cels []*Cel
+	for _, c := range f.Frames[frame].Cels {
+		if f.Visible(c.Layer) && f.Layers[c.Layer].Type == LayerNormal {
+			cels = append(cels, c)
+		}
+	}
+	// Z-index moves a cel up or down the layer stack within its frame; ties
+	// go to the cel with the lower z-index.
+	sort.SliceStable(cels, func(i, j int) bool {
+		a, b := cels[i].Layer+cels[i].ZIndex, cels[j].Layer+cels[j].ZIndex
+		if a != b {
+			return a < b
+		}
+		return cels[i].ZIndex < cels[j].ZIndex
+	})
+
+	for _, c := range cels {
+		layer := &f.Layers[c.Layer]
+		opacity := float64(c.Opacity) / 255 * f.opacity(c.Layer)
+		drawCel(canvas, c, layer.BlendMode, opacity)
+	}
+	return canvas
+}
+
+func drawCel(dst *image.NRGBA, c *Cel, mode BlendMode, opacity float64) {
+	r := c.Image.Bounds().Add(image.Pt(c.X, c.Y)).Intersect(dst.Bounds())
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			s := c.Image.PixOffset(x-c.X, y-c.Y)
+			d := dst.PixOffset(x, y)
+			blendPixel(dst.Pix[d:d+4], c.Image.Pix[s:s+4], mode, opacity)
+		}
+	}
+}
+
+// blendPixel blends the non-premultiplied src pixel onto dst. The blend mode
+// picks the source color, taking the backdrop into account, which is then
+// composited over dst as in normal mode.
+func blendPixel(dst, src []uint8, mode BlendMode, opacity float64) {
+	sa := float64(src[3]) / 255 * opacity
+	if sa <= 0 {
+		return
+	}
+	ba := float64(dst[3]) / 255
+	var s, b [3]float64
+	for i := range s {
+		s[i] = float64(src[i]) / 255
+		b[i] = float64(dst[i]) / 255
+	}
+	if mode != BlendNormal && ba > 0 {
+		m := blend(mode, b, s)
+		for i := range s {
+			s[i] = (1-ba)*s[i] + ba*clamp(m[i])
+		}
+	}
+
+	ra := sa + ba*(1-sa)
+	for i := range s {
+		v := (s[i]*sa + b[i]*ba*(1-sa)) / ra
+		dst[i] = uint8(math.Round(clamp(v) * 255))
+	}
+	dst[3] = uint8(math.Round(ra * 255))
+}
+
+func blend(mode BlendMode, b, s [3]float64) [3]float64 {
+	switch mode {
+	case BlendHue:
+		return setLum(setSat(s, sat(b)), lum(b))
+	case BlendSaturation:
+		return setLum(setSat(b, sat(s)), lum(b))
+	case BlendColor:
+		return setLum(s, lum(b))
+	case BlendLuminosity:
+		return setLum(b, lum(s))
+	}
+	var r [3]float64
+	for i := range r {
+		r[i] = blendChannel(mode, b[i], s[i])
+	}
+	return r
+}
+
+func blendChannel(mode BlendMode, b, s float64) float64 {
+	switch mode {
+	case BlendMultiply:
+		return b * s
+	case BlendScreen:
+		return b + s - b*s
+	case BlendOverlay:
+		return blendChannel(BlendHardLight, s, b)
+	case BlendDarken:
+		return math.Min(b, s)
+	case BlendLighten:
+		return math.Max(b, s)
+	case BlendColorDodge:
+		if b == 0 {
+			return 0
+		}
+		if s >= 1 {
+			return 1
+		}
+		return math.Min(1, b/(1-s))
+	case BlendColorBurn:
+		if b >= 1 {
+			return 1
+		}
+		if s <= 0 {
+			return 0
+		}
+		return 1 - math.Min(1, (1-b)/s)
+	case BlendHardLight:
+		if s <= 0.5 {
+			return b * 2 * s
+		}
+		return blendChannel(BlendScreen, b, 2*s-1)
+	case BlendSoftLight:
+		if s <= 0.5 {
+			return b - (1-2*s)*b*(1-b)
+		}
+		var d float64
+		if b <= 0.25 {
+			d = ((16*b-12)*b + 4) * b
+		} else {
+			d = math.Sqrt(b)
+		}
+		return b + (2*s-1)*(d-b)
+	case BlendDifference:
+		return math.Abs(b - s)
+	case BlendExclusion:
+		return b + s - 2*b*s
+	case BlendAddition:
+		return math.Min(1, b+s)
+	case BlendSubtract:
+		return math.Max(0, b-s)
+	case BlendDivide:
+		if b == 0 {
+			return 0
+		}
+		if b >= s {
+			return 1
+		}
+		return b / s
+	}
+	return s
+}
+
+// The non-separable modes follow the W3C compositing specification.
+
+func lum(c [3]float64) float64 {
+	return 0.3*c[0] + 0.59*c[1] + 0.11*c[2]
+}
+
+func setLum(c [3]float64, l float64) [3]float64 {
+	d := l - lum(c)
+	for i := range c {
+		c[i] += d
+	}
+	l = lum(c)
+	n := math.Min(c[0], math.Min(c[1], c[2]))
+	x := math.Max(c[0], math.Max(c[1], c[2]))
+	for i := range c {
+		if n < 0 {
+			c[i] = l + (c[i]-l)*l/(l-n)
+		}
+		if x > 1 {
+			c[i] = l + (c[i]-l)*(1-l)/(x-l)
+		}
+	}
+	return c
+}
+
+func sat(c [3]float64) float64 {
+	return math.Max(c[0], math.Max(c[1], c[2])) - math.Min(c[0], math.Min(c[1], c[2]))
+}
+
+func setSat(c [3]float64, s float64) [3]float64 {
+	max, mid, min := 0, 1, 2
+	if c[max] < c[mid] {
+		max, mid = mid, max
+	}
+	if c[mid] < c[min] {
+		mid, min = min, mid
+	}
+	if c[max] < c[mid] {
+		max, mid = mid, max
+	}
+	if c[max] > c[min] {
+		c[mid] = (c[mid] - c[min]) * s / (c[max] - c[min])
+		c[max] = s
+	} else {
+		c[mid], c[max] = 0, 0
+	}
+	c[min] = 0
+	return c
+}
+
+func clamp(v float64) float64 {
+	return math.Max(0, math.Min(1, v))
+}
@@ -0,0 +1,519 @@
+package aseprite
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"io/ioutil"
+)
+
+const (
+	headerMagic = 0xA5E0
+	frameMagic  = 0xF1FA
+
+	chunkOldPalette = 0x0004
+	chunkLayer      = 0x2004
+	chunkCel        = 0x2005
+	chunkTags       = 0x2018
+	chunkPalette    = 0x2019
+	chunkUserData   = 0x2020
+	chunkSlice      = 0x2022
+
+	flagLayerOpacity = 1
+)
+
+// ColorDepth is the number of bits per pixel used by a file's cels.
+type ColorDepth int
+
+const (
+	DepthRGBA      ColorDepth = 32
+	DepthGrayscale ColorDepth = 16
+	DepthIndexed   ColorDepth = 8
+)
+
+// LayerFlags describe a layer's state in the editor.
+type LayerFlags int
+
+const (
+	LayerVisible    LayerFlags = 1
+	LayerEditable   LayerFlags = 2
+	LayerLocked     LayerFlags = 4
+	LayerBackground LayerFlags = 8
+	LayerReference  LayerFlags = 64
+)
+
+// LayerType distinguishes image layers from groups and tilemaps.
+type LayerType int
+
+const (
+	LayerNormal  LayerType = 0
+	LayerGroup   LayerType = 1
+	LayerTilemap LayerType = 2
+)
+
+// Layer is a single layer, in bottom-to-top order.
+type Layer struct {
+	Name       string
+	Flags      LayerFlags
+	Type       LayerType
+	ChildLevel int
+	BlendMode  BlendMode
+	Opacity    uint8
+
+	// Parent is the index of the enclosing group layer, or -1.
+	Parent int
+
+	// UserData is the text attached to the layer, if any.
+	UserData string
+}
+
+// Cel is a layer's image within one frame.
+type Cel struct {
+	Layer   int
+	X, Y    int
+	Opacity uint8
+	ZIndex  int
+	Image   *image.NRGBA
+
+	// UserData is the text attached to the cel, if any.
+	UserData string
+}
+
+// Frame is a single frame of the sprite.
+type Frame struct {
+	// Duration is how long the frame is shown, in seconds.
+	Duration float64
+	Cels     []*Cel
+}
+
+// Direction is the playback direction of a tag.
+type Direction int
+
+const (
+	Forward Direction = iota
+	Reverse
+	PingPong
+	PingPongReverse
+)
+
+// Tag is a named range of frames, usually an animation.
+type Tag struct {
+	Name      string
+	From, To  int
+	Direction Direction
+
+	// Repeat is the number of times the tag plays, or 0 for forever.
+	Repeat int
+
+	UserData string
+}
+
+// Slice is a named region of the sprite, optionally carrying nine-patch and
+// pivot information. Its properties may change from frame to frame.
+type Slice struct {
+	Name     string
+	Keys     []SliceKey
+	UserData string
+}
+
+// SliceKey holds a slice's properties from a given frame onwards.
+type SliceKey struct {
+	Frame  int
+	Bounds image.Rectangle
+
+	// Center is the stretchable center of a nine-patch, relative to Bounds.
+	// It is empty if the slice isn't a nine-patch.
+	Center image.Rectangle
+
+	// Pivot is relative to Bounds, and only meaningful if HasPivot is set.
+	Pivot    image.Point
+	HasPivot bool
+}
+
+// At returns the key in effect at the given frame.
+func (s *Slice) At(frame int) SliceKey {
+	var key SliceKey
+	for _, k := range s.Keys {
+		if k.Frame > frame {
+			break
+		}
+		key = k
+	}
+	return key
+}
+
+// File is a decoded Aseprite document.
+type File struct {
+	Width, Height int
+	Depth         ColorDepth
+
+	// TransparentIndex is the palette entry treated as transparent in
+	// indexed sprites.
+	TransparentIndex uint8
+
+	Palette color.Palette
+	Layers  []Layer
+	Frames  []Frame
+	Tags    []Tag
+	Slices  []Slice
+}
+
+type reader struct {
+	buf []byte
+	pos int
+	err error
+}
+
+func (r *reader) next(n int) []byte {
+	if r.err != nil {
+		return make([]byte, n)
+	}
+	if n < 0 || r.pos+n > len(r.buf) {
+		r.err = io.ErrUnexpectedEOF
+		return make([]byte, n)
+	}
+	b := r.buf[r.pos : r.pos+n]
+	r.pos += n
+	return b
+}
+
+func (r *reader) byte() uint8    { return r.next(1)[0] }
+func (r *reader) word() uint16   { return binary.LittleEndian.Uint16(r.next(2)) }
+func (r *reader) short() int16   { return int16(r.word()) }
+func (r *reader) dword() uint32  { return binary.LittleEndian.Uint32(r.next(4)) }
+func (r *reader) long() int32    { return int32(r.dword()) }
+func (r *reader) skip(n int)     { r.next(n) }
+func (r *reader) string() string { return string(r.next(int(r.word()))) }
+
+// Decode reads an .ase/.aseprite file.
+func Decode(in io.Reader) (*File, error) {
+	data, err := ioutil.ReadAll(in)
+	if err != nil {
+		return nil, err
+	}
+	r := &reader{buf: data}
+
+	r.dword() // file size
+	if r.word() != headerMagic {
+		return nil, errors.New("not an Aseprite file")
+	}
+	f := new(File)
+	numFrames := int(r.word())
+	f.Width, f.Height = int(r.word()), int(r.word())
+	f.Depth = ColorDepth(r.word())
+	flags := r.dword()
+	r.skip(2 + 4 + 4) // speed, reserved
+	f.TransparentIndex = r.byte()
+	r.skip(3)
+	numColors := int(r.word())
+	r.skip(84 + 1 + 1 + 2 + 2 + 2 + 2) // pixel ratio, grid, reserved
+	if r.err != nil {
+		return nil, r.err
+	}
+	switch f.Depth {
+	case DepthRGBA, DepthGrayscale, DepthIndexed:
+	default:
+		return nil, fmt.Errorf("unsupported color depth %d", f.Depth)
+	}
+	if numColors == 0 {
+		numColors = 256
+	}
+	f.Palette = make(color.Palette, numColors)
+	for i := range f.Palette {
+		f.Palette[i] = color.NRGBA{}
+	}
+
+	f.Frames = make([]Frame, numFrames)
+	for i := range f.Frames {
+		if err := f.decodeFrame(r, i, flags); err != nil {
+			return nil, fmt.Errorf("frame %d: %s", i, err)
+		}
+	}
+	return f, nil
+}
+
+func (f *File) decodeFrame(r *reader, index int, headerFlags uint32) error {
+	start := r.pos
+	size := int(r.dword())
+	if r.word() != frameMagic {
+		return errors.New("bad frame magic number")
+	}
+	chunks := int(r.word())
+	f.Frames[index].Duration = float64(r.word()) / 1000
+	r.skip(2)
+	if n := int(r.dword()); n != 0 {
+		chunks = n
+	}
+	if r.err != nil {
+		return r.err
+	}
+
+	// User data chunks attach to whatever came before them; tags are
+	// followed by one user data chunk per tag.
+	var (
+		userData  *string
+		tagCursor = -1
+	)
+	for c := 0; c < chunks; c++ {
+		chunkStart := r.pos
+		chunkSize := int(r.dword())
+		chunkType := r.word()
+		if r.err != nil {
+			return r.err
+		}
+		if chunkSize < 6 || chunkStart+chunkSize > len(r.buf) {
+			return fmt.Errorf("chunk %d has invalid size %d", c, chunkSize)
+		}
+		cr := &reader{buf: r.buf[r.pos : chunkStart+chunkSize]}
+		r.pos = chunkStart + chunkSize
+
+		switch chunkType {
+		case chunkOldPalette:
+			f.decodeOldPalette(cr)
+		case chunkPalette:
+			f.decodePalette(cr)
+		case chunkLayer:
+			f.decodeLayer(cr, headerFlags)
+			userData = &f.Layers[len(f.Layers)-1].UserData
+		case chunkCel:
+			cel, err := f.decodeCel(cr, index)
+			if err != nil {
+				return err
+			}
+			userData = nil
+			if cel != nil {
+				userData = &cel.UserData
+			}
+		case chunkTags:
+			tagCursor = len(f.Tags)
+			f.decodeTags(cr)
+			userData = nil
+		case chunkSlice:
+			f.decodeSlice(cr)
+			userData = &f.Slices[len(f.Slices)-1].UserData
+		case chunkUserData:
+			text := decodeUserData(cr)
+			if tagCursor >= 0 && tagCursor < len(f.Tags) {
+				f.Tags[tagCursor].UserData = text
+				tagCursor++
+			} else if userData != nil {
+				*userData = text
+			}
+			continue
+		}
+		if chunkType != chunkTags {
+			tagCursor = -1
+		}
+		if cr.err != nil {
+			return fmt.Errorf("chunk %#x: %s", chunkType, cr.err)
+		}
+	}
+	r.pos = start + size
+	return nil
+}
+
+func (f *File) decodeOldPalette(r *reader) {
+	packets := int(r.word())
+	index := 0
+	for p := 0; p < packets && r.err == nil; p++ {
+		index += int(r.byte())
+		count := int(r.byte())
+		if count == 0 {
+			count = 256
+		}
+		for i := 0; i < count; i++ {
+			rgb := r.next(3)
+			f.setPalette(index, color.NRGBA{rgb[0], rgb[1], rgb[2], 0xFF})
+			index++
+		}
+	}
+}
+
+func (f *File) decodePalette(r *reader) {
+	size := int(r.dword())
+	first, last := int(r.dword()), int(r.dword())
+	r.skip(8)
+	if size > len(f.Palette) {
+		grown := make(color.Palette, size)
+		copy(grown, f.Palette)
+		for i := len(f.Palette); i < size; i++ {
+			grown[i] = color.NRGBA{}
+		}
+		f.Palette = grown
+	}
+	for i := first; i <= last && r.err == nil; i++ {
+		flags := r.word()
+		c := r.next(4)
+		f.setPalette(i, color.NRGBA{c[0], c[1], c[2], c[3]})
+		if flags&1 != 0 {
+			r.string()
+		}
+	}
+}
+
+func (f *File) setPalette(i int, c color.NRGBA) {
+	if i >= 0 && i < len(f.Palette) {
+		f.Palette[i] = c
+	}
+}
+
+func (f *File) decodeLayer(r *reader, headerFlags uint32) {
+	l := Layer{
+		Flags:      LayerFlags(r.word()),
+		Type:       LayerType(r.word()),
+		ChildLevel: int(r.word()),
+		Parent:     -1,
+	}
+	r.skip(4) // default width and height
+	l.BlendMode = BlendMode(r.word())
+	l.Opacity = r.byte()
+	r.skip(3)
+	l.Name = r.string()
+	if headerFlags&flagLayerOpacity == 0 {
+		l.Opacity = 0xFF
+	}
+	for i := len(f.Layers) - 1; i >= 0; i-- {
+		if f.Layers[i].ChildLevel < l.ChildLevel {
+			if f.Layers[i].Type == LayerGroup {
+				l.Parent = i
+			}
+			break
+		}
+	}
+	f.Layers = append(f.Layers, l)
+}
+
+func (f *File) decodeCel(r *reader, frame int) (*Cel, error) {
+	cel := &Cel{
+		Layer:   int(r.word()),
+		X:       int(r.short()),
+		Y:       int(r.short()),
+		Opacity: r.byte(),
+	}
+	celType := r.word()
+	cel.ZIndex = int(r.short())
+	r.skip(5)
+	if r.err != nil {
+		return nil, r.err
+	}
+	if cel.Layer >= len(f.Layers) {
+		return nil, fmt.Errorf("cel refers to invalid layer %d", cel.Layer)
+	}
+
+	switch celType {
+	case 0, 2:
+		w, h := int(r.word()), int(r.word())
+		pixels := r.buf[r.pos:]
+		if celType == 2 {
+			zr, err := zlib.NewReader(bytes.NewReader(pixels))
+			if err != nil {
+				return nil, err
+			}
+			if pixels, err = ioutil.ReadAll(zr); err != nil {
+				return nil, err
+			}
+		}
+		img, err := f.decodePixels(pixels, w, h, cel.Layer)
+		if err != nil {
+			return nil, err
+		}
+		cel.Image = img
+	case 1:
+		linked := int(r.word())
+		if linked < 0 || linked >= frame {
+			return nil, fmt.Errorf("cel links to invalid frame %d", linked)
+		}
+		// A linked cel shares its position and opacity as well as its image.
+		for _, c := range f.Frames[linked].Cels {
+			if c.Layer == cel.Layer {
+				cel.X, cel.Y, cel.Opacity, cel.Image = c.X, c.Y, c.Opacity, c.Image
+				break
+			}
+		}
+	default:
+		// Compressed tilemaps are not supported; skip the cel.
+		return nil, nil
+	}
+	if cel.Image == nil {
+		return nil, nil
+	}
+	f.Frames[frame].Cels = append(f.Frames[frame].Cels, cel)
+	return cel, nil
+}
+
+func (f *File) decodePixels(data []byte, w, h, layer int) (*image.NRGBA, error) {
+	bpp := int(f.Depth) / 8
+	if len(data) < w*h*bpp {
+		return nil, io.ErrUnexpectedEOF
+	}
+	background := layer >= 0 && layer < len(f.Layers) && f.Layers[layer].Flags&LayerBackground != 0
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for i := 0; i < w*h; i++ {
+		p := img.Pix[i*4 : i*4+4]
+		switch f.Depth {
+		case DepthRGBA:
+			copy(p, data[i*4:i*4+4])
+		case DepthGrayscale:
+			v, a := data[i*2], data[i*2+1]
+			p[0], p[1], p[2], p[3] = v, v, v, a
+		case DepthIndexed:
+			index := data[i]
+			if index == f.TransparentIndex && !background {
+				continue
+			}
+			if int(index) < len(f.Palette) {
+				c := color.NRGBAModel.Convert(f.Palette[index]).(color.NRGBA)
+				p[0], p[1], p[2], p[3] = c.R, c.G, c.B, c.A
+			}
+		}
+	}
+	return img, nil
+}
+
+func (f *File) decodeTags(r *reader) {
+	n := int(r.word())
+	r.skip(8)
+	for i := 0; i < n && r.err == nil; i++ {
+		t := Tag{From: int(r.word()), To: int(r.word()), Direction: Direction(r.byte())}
+		t.Repeat = int(r.word())
+		r.skip(6 + 3 + 1)
+		t.Name = r.string()
+		f.Tags = append(f.Tags, t)
+	}
+}
+
+func (f *File) decodeSlice(r *reader) {
+	n := int(r.dword())
+	flags := r.dword()
+	r.skip(4)
+	s := Slice{Name: r.string()}
+	for i := 0; i < n && r.err == nil; i++ {
+		k := SliceKey{Frame: int(r.dword())}
+		x, y := int(r.long()), int(r.long())
+		w, h := int(r.dword()), int(r.dword())
+		k.Bounds = image.Rect(x, y, x+w, y+h)
+		if flags&1 != 0 {
+			cx, cy := int(r.long()), int(r.long())
+			cw, ch := int(r.dword()), int(r.dword())
+			k.Center = image.Rect(cx, cy, cx+cw, cy+ch)
+		}
+		if flags&2 != 0 {
+			k.Pivot = image.Pt(int(r.long()), int(r.long()))
+			k.HasPivot = true
+		}
+		s.Keys = append(s.Keys, k)
+	}
+	f.Slices = append(f.Slices, s)
+}
+
+func decodeUserData(r *reader) string {
+	if r.dword()&1 != 0 {
+		return r.string()
+	}
+	return ""
+}
@@ -0,0 +1,235 @@
+package aseprite
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"image"
+	"testing"
+)
+
+// writer builds little-endian Aseprite data for tests.
+type writer struct{ bytes.Buffer }
+
+func (w *writer) byte(v uint8)   { w.WriteByte(v) }
+func (w *writer) word(v uint16)  { binary.Write(w, binary.LittleEndian, v) }
+func (w *writer) dword(v uint32) { binary.Write(w, binary.LittleEndian, v) }
+func (w *writer) zero(n int)     { w.Write(make([]byte, n)) }
+func (w *writer) string(s string) {
+	w.word(uint16(len(s)))
+	w.WriteString(s)
+}
+
+func chunk(typ uint16, body func(w *writer)) []byte {
+	var c writer
+	body(&c)
+	var w writer
+	w.dword(uint32(c.Len() + 6))
+	w.word(typ)
+	w.Write(c.Bytes())
+	return w.Bytes()
+}
+
+func frame(duration uint16, chunks ...[]byte) []byte {
+	var body bytes.Buffer
+	for _, c := range chunks {
+		body.Write(c)
+	}
+	var w writer
+	w.dword(uint32(body.Len() + 16))
+	w.word(frameMagic)
+	w.word(uint16(len(chunks)))
+	w.word(duration)
+	w.zero(2)
+	w.dword(uint32(len(chunks)))
+	w.Write(body.Bytes())
+	return w.Bytes()
+}
+
+func file(width, height int, frames ...[]byte) []byte {
+	var body bytes.Buffer
+	for _, f := range frames {
+		body.Write(f)
+	}
+	var w writer
+	w.dword(uint32(body.Len() + 128))
+	w.word(headerMagic)
+	w.word(uint16(len(frames)))
+	w.word(uint16(width))
+	w.word(uint16(height))
+	w.word(32)
+	w.dword(flagLayerOpacity)
+	w.zero(2 + 4 + 4)
+	w.byte(0)
+	w.zero(3)
+	w.word(0)
+	w.zero(84 + 1 + 1 + 2 + 2 + 2 + 2)
+	w.Write(body.Bytes())
+	return w.Bytes()
+}
+
+func layer(name string, mode BlendMode, opacity uint8) []byte {
+	return chunk(chunkLayer, func(w *writer) {
+		w.word(uint16(LayerVisible | LayerEditable))
+		w.word(uint16(LayerNormal))
+		w.word(0)
+		w.zero(4)
+		w.word(uint16(mode))
+		w.byte(opacity)
+		w.zero(3)
+		w.string(name)
+	})
+}
+
+// cel returns a w by h cel filled with a single color, optionally compressed.
+func cel(layer, x, y, w, h int, rgba [4]uint8, compressed bool) []byte {
+	pixels := bytes.Repeat(rgba[:], w*h)
+	return chunk(chunkCel, func(c *writer) {
+		c.word(uint16(layer))
+		c.word(uint16(x))
+		c.word(uint16(y))
+		c.byte(0xFF)
+		if compressed {
+			c.word(2)
+		} else {
+			c.word(0)
+		}
+		c.word(0)
+		c.zero(5)
+		c.word(uint16(w))
+		c.word(uint16(h))
+		if compressed {
+			zw := zlib.NewWriter(c)
+			zw.Write(pixels)
+			zw.Close()
+		} else {
+			c.Write(pixels)
+		}
+	})
+}
+
+func TestDecode(t *testing.T) {
+	tags := chunk(chunkTags, func(w *writer) {
+		w.word(2)
+		w.zero(8)
+		for i, name := range []string{"idle", "hit"} {
+			w.word(uint16(i))
+			w.word(uint16(i))
+			w.byte(uint8(PingPong) * uint8(i))
+			w.word(uint16(i))
+			w.zero(6 + 3 + 1)
+			w.string(name)
+		}
+	})
+	userData := func(text string) []byte {
+		return chunk(chunkUserData, func(w *writer) {
+			w.dword(1)
+			w.string(text)
+		})
+	}
+	slice := chunk(chunkSlice, func(w *writer) {
+		w.dword(1)
+		w.dword(2)
+		w.dword(0)
+		w.string("hitbox")
+		w.dword(0)
+		w.dword(1)
+		w.dword(2)
+		w.dword(3)
+		w.dword(4)
+		w.dword(1)
+		w.dword(2)
+	})
+
+	data := file(4, 4,
+		frame(100,
+			layer("base", BlendNormal, 0xFF),
+			userData("base layer"),
+			layer("shade", BlendMultiply, 0xFF),
+			tags, userData("first"), userData("second"),
+			slice,
+			cel(0, 0, 0, 4, 4, [4]uint8{200, 100, 50, 0xFF}, false),
+			cel(1, 2, 2, 2, 2, [4]uint8{128, 255, 0, 0xFF}, true),
+		),
+		frame(50,
+			cel(0, 1, 1, 1, 1, [4]uint8{0, 0, 0xFF, 0xFF}, true),
+		),
+	)
+	f, err := Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if f.Width != 4 || f.Height != 4 || len(f.Frames) != 2 || len(f.Layers) != 2 {
+		t.Fatalf("got %dx%d with %d frames and %d layers", f.Width, f.Height, len(f.Frames), len(f.Layers))
+	}
+	if f.Frames[0].Duration != 0.1 || f.Frames[1].Duration != 0.05 {
+		t.Errorf("got durations %v and %v", f.Frames[0].Duration, f.Frames[1].Duration)
+	}
+	if f.Layers[0].UserData != "base layer" || f.Layers[1].BlendMode != BlendMultiply {
+		t.Errorf("got layers %+v", f.Layers)
+	}
+	if len(f.Tags) != 2 || f.Tags[1].Name != "hit" || f.Tags[1].Direction != PingPong || f.Tags[1].Repeat != 1 {
+		t.Errorf("got tags %+v", f.Tags)
+	}
+	if f.Tags[0].UserData != "first" || f.Tags[1].UserData != "second" {
+		t.Errorf("got tag user data %q and %q", f.Tags[0].UserData, f.Tags[1].UserData)
+	}
+	if len(f.Slices) != 1 {
+		t.Fatalf("got %d slices", len(f.Slices))
+	}
+	key := f.Slices[0].At(1)
+	if key.Bounds != image.Rect(1, 2, 4, 6) || !key.HasPivot || key.Pivot != image.Pt(1, 2) {
+		t.Errorf("got slice key %+v", key)
+	}
+
+	img := f.Composite(0)
+	check := func(x, y int, want [4]uint8) {
+		t.Helper()
+		p := img.Pix[img.PixOffset(x, y):]
+		if got := [4]uint8{p[0], p[1], p[2], p[3]}; got != want {
+			t.Errorf("pixel (%d, %d) = %v, want %v", x, y, got, want)
+		}
+	}
+	check(0, 0, [4]uint8{200, 100, 50, 0xFF})
+	check(3, 3, [4]uint8{100, 100, 0, 0xFF})
+
+	img = f.Composite(1)
+	check(0, 0, [4]uint8{})
+	check(1, 1, [4]uint8{0, 0, 0xFF, 0xFF})
+}
+
+func TestDecodeHiddenLayer(t *testing.T) {
+	hidden := layer("hidden", BlendNormal, 0xFF)
+	binary.LittleEndian.PutUint16(hidden[6:], uint16(LayerEditable))
+	data := file(1, 1,
+		frame(100,
+			layer("half", BlendNormal, 0x80),
+			hidden,
+			cel(0, 0, 0, 1, 1, [4]uint8{0xFF, 0, 0, 0xFF}, false),
+			cel(1, 0, 0, 1, 1, [4]uint8{0, 0xFF, 0, 0xFF}, false),
+		),
+	)
+	f, err := Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Visible(1) {
+		t.Error("hidden layer reported as visible")
+	}
+	if got := f.Composite(0).Pix; got[0] != 0xFF || got[1] != 0 || got[3] != 0x80 {
+		t.Errorf("got pixel %v", got)
+	}
+}
+
+func TestDecodeBadLayer(t *testing.T) {
+	data := file(1, 1,
+		frame(100,
+			layer("only", BlendNormal, 0xFF),
+			cel(1, 0, 0, 1, 1, [4]uint8{0xFF, 0, 0, 0xFF}, false),
+		),
+	)
+	if _, err := Decode(bytes.NewReader(data)); err == nil {
+		t.Error("cel on a missing layer was accepted")
+	}
+}
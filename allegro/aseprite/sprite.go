@@ -0,0 +1,142 @@
+// Package aseprite reads Aseprite's native .ase/.aseprite files without
+// needing a JSON export.
+//
+// Decode parses a file into its layers, cels, tags and slices, and Composite
+// flattens a frame the way the editor shows it, honouring layer visibility,
+// opacity and blend modes. Load goes one step further and packs every frame
+// into an atlas, turning tags into sprite.Animations.
+package aseprite
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/dradtke/go-allegro/allegro/atlas"
+	"github.com/dradtke/go-allegro/allegro/sprite"
+)
+
+// Sprite is an Aseprite file loaded into bitmaps, ready to be animated with
+// the sprite package. Call Destroy to free its bitmaps.
+type Sprite struct {
+	// File is the decoded document the sprite was built from.
+	File *File
+
+	// Frames holds the flattened image of each frame, in order.
+	Frames []sprite.Frame
+
+	// Animations has an animation for each tag, plus one named "" that
+	// loops through every frame.
+	Animations map[string]*sprite.Animation
+
+	atlas *atlas.Atlas
+}
+
+// Load loads an .ase/.aseprite file, flattening each frame's visible layers
+// and packing the results into an atlas.
+func Load(filename string) (*Sprite, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	s, err := Read(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load Aseprite file '%s': %s", filename, err)
+	}
+	return s, nil
+}
+
+// Read decodes an Aseprite file from r into a sprite; see Load.
+func Read(r io.Reader) (*Sprite, error) {
+	file, err := Decode(r)
+	if err != nil {
+		return nil, err
+	}
+	return New(file)
+}
+
+// New builds a sprite from an already decoded file.
+func New(file *File) (*Sprite, error) {
+	if len(file.Frames) == 0 {
+		return nil, errors.New("sprite has no frames")
+	}
+	b := atlas.NewBuilder(0, 0)
+	if file.Width > b.PageWidth {
+		b.PageWidth = file.Width
+	}
+	if file.Height > b.PageHeight {
+		b.PageHeight = file.Height
+	}
+	for i := range file.Frames {
+		if err := b.AddImage(fmt.Sprint(i), file.Composite(i)); err != nil {
+			return nil, err
+		}
+	}
+	a, err := b.Build()
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Sprite{File: file, Animations: make(map[string]*sprite.Animation), atlas: a}
+	for i, fr := range file.Frames {
+		bmp, _ := a.Bitmap(fmt.Sprint(i))
+		duration := fr.Duration
+		if duration <= 0 {
+			duration = sprite.DefaultFrameDuration
+		}
+		s.Frames = append(s.Frames, sprite.Frame{Bitmap: bmp, Duration: duration})
+	}
+	for _, tag := range file.Tags {
+		if tag.From < 0 || tag.To >= len(s.Frames) || tag.From > tag.To {
+			a.Destroy()
+			return nil, fmt.Errorf("tag '%s' has invalid frame range %d-%d", tag.Name, tag.From, tag.To)
+		}
+		s.Animations[tag.Name] = s.animation(tag)
+	}
+	s.Animations[""] = &sprite.Animation{Frames: s.Frames}
+	return s, nil
+}
+
+func (s *Sprite) animation(tag Tag) *sprite.Animation {
+	anim := &sprite.Animation{Name: tag.Name}
+	for i := tag.From; i <= tag.To; i++ {
+		anim.Frames = append(anim.Frames, s.Frames[i])
+	}
+	if tag.Direction == Reverse || tag.Direction == PingPongReverse {
+		for i, j := 0, len(anim.Frames)-1; i < j; i, j = i+1, j-1 {
+			anim.Frames[i], anim.Frames[j] = anim.Frames[j], anim.Frames[i]
+		}
+	}
+	if tag.Direction == PingPong || tag.Direction == PingPongReverse {
+		anim.Mode = sprite.PingPong
+	}
+	if tag.Repeat == 1 {
+		anim.Mode = sprite.Once
+	}
+	return anim
+}
+
+// Animation returns the animation for the named tag, or nil if there is none.
+func (s *Sprite) Animation(name string) *sprite.Animation {
+	return s.Animations[name]
+}
+
+// Slice returns the named slice, or nil if there is none.
+func (s *Sprite) Slice(name string) *Slice {
+	for i := range s.File.Slices {
+		if s.File.Slices[i].Name == name {
+			return &s.File.Slices[i]
+		}
+	}
+	return nil
+}
+
+// Destroy frees the sprite's bitmaps.
+func (s *Sprite) Destroy() {
+	if s.atlas != nil {
+		s.atlas.Destroy()
+		s.atlas = nil
+	}
+}
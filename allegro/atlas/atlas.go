@@ -0,0 +1,288 @@
+// Package atlas packs many small bitmaps into a few large ones at runtime.
+//
+// Drawing bitmaps that share a parent lets Allegro batch them together while
+// HoldBitmapDrawing is on; drawing hundreds of unrelated small bitmaps forces
+// a flush on every texture switch. An Atlas copies its inputs onto shared
+// pages and hands back sub-bitmaps of those pages, which can be drawn exactly
+// like the originals.
+package atlas
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"sort"
+
+	"github.com/dradtke/go-allegro/allegro"
+)
+
+// DefaultPageSize is the width and height of atlas pages when none is given.
+const DefaultPageSize = 1024
+
+// Builder collects images to be packed into an Atlas.
+type Builder struct {
+	// PageWidth and PageHeight are the size of each page bitmap. Every input
+	// (plus padding) must fit within a single page.
+	PageWidth, PageHeight int
+
+	// Padding is the number of empty pixels left around each input, which
+	// keeps linear filtering from bleeding neighbouring images together.
+	Padding int
+
+	sources []source
+	names   map[string]bool
+}
+
+type source struct {
+	name string
+	bmp  *allegro.Bitmap
+	img  image.Image
+	w, h int
+}
+
+// NewBuilder returns a builder producing pages of the given size.
+func NewBuilder(pageWidth, pageHeight int) *Builder {
+	if pageWidth <= 0 {
+		pageWidth = DefaultPageSize
+	}
+	if pageHeight <= 0 {
+		pageHeight = DefaultPageSize
+	}
+	return &Builder{
+		PageWidth:  pageWidth,
+		PageHeight: pageHeight,
+		names:      make(map[string]bool),
+	}
+}
+
+// AddBitmap queues an Allegro bitmap for packing under the given name. The
+// bitmap is only read during Build, and is not destroyed by it.
+func (b *Builder) AddBitmap(name string, bmp *allegro.Bitmap) error {
+	if bmp == nil {
+		return allegro.BitmapIsNull
+	}
+	return b.add(source{name: name, bmp: bmp, w: bmp.Width(), h: bmp.Height()})
+}
+
+// AddImage queues a Go image for packing under the given name.
+func (b *Builder) AddImage(name string, img image.Image) error {
+	if img == nil {
+		return errors.New("image is nil")
+	}
+	size := img.Bounds().Size()
+	return b.add(source{name: name, img: img, w: size.X, h: size.Y})
+}
+
+func (b *Builder) add(src source) error {
+	if b.names == nil {
+		b.names = make(map[string]bool)
+	}
+	if b.names[src.name] {
+		return fmt.Errorf("duplicate atlas entry '%s'", src.name)
+	}
+	if src.w+2*b.Padding > b.PageWidth || src.h+2*b.Padding > b.PageHeight {
+		return fmt.Errorf("atlas entry '%s' (%dx%d) does not fit in a %dx%d page", src.name, src.w, src.h, b.PageWidth, b.PageHeight)
+	}
+	b.names[src.name] = true
+	b.sources = append(b.sources, src)
+	return nil
+}
+
+// Len returns the number of queued entries.
+func (b *Builder) Len() int {
+	return len(b.sources)
+}
+
+// Build packs every queued entry into as few pages as possible, copies the
+// pixel data across, and returns the resulting atlas. Page bitmaps are
+// created with the current new bitmap flags and format.
+func (b *Builder) Build() (*Atlas, error) {
+	order := make([]int, len(b.sources))
+	for i := range order {
+		order[i] = i
+	}
+	// Tallest first packs noticeably tighter on a skyline.
+	sort.SliceStable(order, func(i, j int) bool {
+		si, sj := b.sources[order[i]], b.sources[order[j]]
+		if si.h != sj.h {
+			return si.h > sj.h
+		}
+		return si.w > sj.w
+	})
+
+	var (
+		packers []*skyline
+		regions = make([]Region, len(b.sources))
+	)
+	for _, i := range order {
+		src := b.sources[i]
+		w, h := src.w+2*b.Padding, src.h+2*b.Padding
+		placed := false
+		for page, p := range packers {
+			if x, y, ok := p.insert(w, h); ok {
+				regions[i] = Region{Name: src.name, Page: page, X: x + b.Padding, Y: y + b.Padding, W: src.w, H: src.h}
+				placed = true
+				break
+			}
+		}
+		if !placed {
+			p := newSkyline(b.PageWidth, b.PageHeight)
+			x, y, _ := p.insert(w, h)
+			regions[i] = Region{Name: src.name, Page: len(packers), X: x + b.Padding, Y: y + b.Padding, W: src.w, H: src.h}
+			packers = append(packers, p)
+		}
+	}
+
+	pages := make([]*allegro.Bitmap, len(packers))
+	for i := range pages {
+		page := allegro.CreateBitmap(b.PageWidth, b.PageHeight)
+		if page == nil {
+			for _, p := range pages[:i] {
+				p.Destroy()
+			}
+			return nil, fmt.Errorf("failed to create %dx%d atlas page", b.PageWidth, b.PageHeight)
+		}
+		pages[i] = page
+	}
+
+	for i, page := range pages {
+		if err := b.fill(page, i, regions); err != nil {
+			for _, p := range pages {
+				p.Destroy()
+			}
+			return nil, err
+		}
+	}
+
+	return newAtlas(pages, regions)
+}
+
+// fill copies every source assigned to the given page into place.
+func (b *Builder) fill(page *allegro.Bitmap, index int, regions []Region) error {
+	page.AsTarget(func() {
+		state := allegro.StoreState(allegro.STATE_BLENDER)
+		defer allegro.RestoreState(state)
+
+		// Copy source pixels verbatim rather than blending them onto the
+		// cleared page.
+		allegro.SetBlender(allegro.ADD, allegro.ONE, allegro.ZERO)
+		allegro.ClearToColor(allegro.MapRGBA(0, 0, 0, 0))
+		for i, src := range b.sources {
+			if src.bmp == nil || regions[i].Page != index {
+				continue
+			}
+			r := regions[i]
+			src.bmp.Draw(float32(r.X), float32(r.Y), allegro.FLIP_NONE)
+		}
+	})
+
+	for i, src := range b.sources {
+		if src.img == nil || regions[i].Page != index {
+			continue
+		}
+		r := regions[i]
+		// image.RGBA is premultiplied like Allegro's bitmaps, and its bytes
+		// are in ABGR_8888_LE's order, so rows can be copied straight in.
+		rgba, ok := src.img.(*image.RGBA)
+		if !ok || rgba.Rect.Min != (image.Point{}) {
+			rgba = image.NewRGBA(image.Rect(0, 0, r.W, r.H))
+			draw.Draw(rgba, rgba.Rect, src.img, src.img.Bounds().Min, draw.Src)
+		}
+		reg, err := page.LockRegion(r.X, r.Y, r.W, r.H, allegro.PIXEL_FORMAT_ABGR_8888_LE, allegro.LOCK_WRITEONLY)
+		if err != nil {
+			return err
+		}
+		for y := 0; y < r.H; y++ {
+			copy(reg.Row(y, r.W), rgba.Pix[y*rgba.Stride:])
+		}
+		page.Unlock()
+	}
+	return nil
+}
+
+// Region describes where a single entry lives within an atlas.
+type Region struct {
+	Name string `json:"name"`
+	Page int    `json:"page"`
+	X    int    `json:"x"`
+	Y    int    `json:"y"`
+	W    int    `json:"w"`
+	H    int    `json:"h"`
+}
+
+// Atlas is a set of page bitmaps along with named sub-bitmaps of them.
+type Atlas struct {
+	pages   []*allegro.Bitmap
+	regions []Region
+	subs    []*allegro.Bitmap
+	index   map[string]int
+}
+
+func newAtlas(pages []*allegro.Bitmap, regions []Region) (*Atlas, error) {
+	a := &Atlas{
+		pages:   pages,
+		regions: regions,
+		subs:    make([]*allegro.Bitmap, len(regions)),
+		index:   make(map[string]int, len(regions)),
+	}
+	for i, r := range regions {
+		if r.Page < 0 || r.Page >= len(pages) {
+			a.Destroy()
+			return nil, fmt.Errorf("atlas entry '%s' refers to missing page %d", r.Name, r.Page)
+		}
+		sub, err := pages[r.Page].CreateSubBitmap(r.X, r.Y, r.W, r.H)
+		if err != nil {
+			a.Destroy()
+			return nil, fmt.Errorf("atlas entry '%s': %s", r.Name, err)
+		}
+		a.subs[i] = sub
+		a.index[r.Name] = i
+	}
+	return a, nil
+}
+
+// Bitmap returns the sub-bitmap for the named entry.
+func (a *Atlas) Bitmap(name string) (*allegro.Bitmap, bool) {
+	i, ok := a.index[name]
+	if !ok {
+		return nil, false
+	}
+	return a.subs[i], true
+}
+
+// Region returns the placement of the named entry.
+func (a *Atlas) Region(name string) (Region, bool) {
+	i, ok := a.index[name]
+	if !ok {
+		return Region{}, false
+	}
+	return a.regions[i], true
+}
+
+// Names returns the names of every entry, in the order they were added.
+func (a *Atlas) Names() []string {
+	names := make([]string, len(a.regions))
+	for i, r := range a.regions {
+		names[i] = r.Name
+	}
+	return names
+}
+
+// Pages returns the atlas' page bitmaps.
+func (a *Atlas) Pages() []*allegro.Bitmap {
+	return a.pages
+}
+
+// Destroy frees every sub-bitmap and page owned by the atlas.
+func (a *Atlas) Destroy() {
+	for _, sub := range a.subs {
+		if sub != nil {
+			sub.Destroy()
+		}
+	}
+	for _, page := range a.pages {
+		page.Destroy()
+	}
+	a.subs, a.pages = nil, nil
+}
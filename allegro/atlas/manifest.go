@@ -0,0 +1,114 @@
+package atlas
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/dradtke/go-allegro/allegro"
+)
+
+// Manifest is the serializable description of an atlas: the image file for
+// each page and the placement of every entry.
+type Manifest struct {
+	Pages   []Page   `json:"pages"`
+	Regions []Region `json:"regions"`
+}
+
+// Page describes a single page image. File is relative to the manifest.
+type Page struct {
+	File   string `json:"file"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+}
+
+// ReadManifest decodes a JSON manifest.
+func ReadManifest(r io.Reader) (*Manifest, error) {
+	var m Manifest
+	if err := json.NewDecoder(r).Decode(&m); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// Write encodes the manifest as JSON.
+func (m *Manifest) Write(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "\t")
+	return enc.Encode(m)
+}
+
+// Manifest returns a manifest describing the atlas. Page file names are
+// derived from base, e.g. "sprites" yields "sprites_0.png", "sprites_1.png".
+func (a *Atlas) Manifest(base string) *Manifest {
+	m := &Manifest{
+		Pages:   make([]Page, len(a.pages)),
+		Regions: append([]Region(nil), a.regions...),
+	}
+	for i, page := range a.pages {
+		m.Pages[i] = Page{
+			File:   fmt.Sprintf("%s_%d.png", base, i),
+			Width:  page.Width(),
+			Height: page.Height(),
+		}
+	}
+	return m
+}
+
+// Save writes the atlas' pages as PNG files alongside a JSON manifest at the
+// given path. Saving PNGs requires the image addon.
+func (a *Atlas) Save(filename string) error {
+	dir := filepath.Dir(filename)
+	base := strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename))
+	m := a.Manifest(base)
+	for i, page := range a.pages {
+		if err := page.Save(filepath.Join(dir, m.Pages[i].File)); err != nil {
+			return err
+		}
+	}
+
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	if err := m.Write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Load reads an atlas previously written by Save. Page images are loaded with
+// the current new bitmap flags, so loading requires the image addon.
+func Load(filename string) (*Atlas, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	m, err := ReadManifest(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read atlas manifest '%s': %s", filename, err)
+	}
+	return m.Load(filepath.Dir(filename))
+}
+
+// Load loads the manifest's page images, relative to dir, and creates the
+// atlas' sub-bitmaps.
+func (m *Manifest) Load(dir string) (*Atlas, error) {
+	pages := make([]*allegro.Bitmap, len(m.Pages))
+	for i, p := range m.Pages {
+		bmp, err := allegro.LoadBitmap(filepath.Join(dir, p.File))
+		if err != nil {
+			for _, loaded := range pages[:i] {
+				loaded.Destroy()
+			}
+			return nil, err
+		}
+		pages[i] = bmp
+	}
+	return newAtlas(pages, append([]Region(nil), m.Regions...))
+}
@@ -0,0 +1,99 @@
+package atlas
+
+// skyline is a bottom-left skyline rectangle packer. It keeps track of the
+// top edge of everything packed so far as a list of horizontal segments, and
+// places each new rectangle on the segment that leaves it lowest.
+type skyline struct {
+	width, height int
+	nodes         []skyNode
+}
+
+type skyNode struct {
+	x, y, w int
+}
+
+func newSkyline(width, height int) *skyline {
+	return &skyline{
+		width:  width,
+		height: height,
+		nodes:  []skyNode{{0, 0, width}},
+	}
+}
+
+// fit returns the y position at which a rectangle of the given size would
+// rest if its left edge were placed at the start of node i, or false if it
+// can't be placed there.
+func (s *skyline) fit(i, w, h int) (int, bool) {
+	x := s.nodes[i].x
+	if x+w > s.width {
+		return 0, false
+	}
+	y, remaining := 0, w
+	for ; remaining > 0; i++ {
+		if i == len(s.nodes) {
+			return 0, false
+		}
+		if s.nodes[i].y > y {
+			y = s.nodes[i].y
+		}
+		if y+h > s.height {
+			return 0, false
+		}
+		remaining -= s.nodes[i].w
+	}
+	return y, true
+}
+
+// insert finds room for a rectangle of the given size, reserves it, and
+// returns its top-left corner.
+func (s *skyline) insert(w, h int) (x, y int, ok bool) {
+	best, bestY, bestW := -1, 0, 0
+	for i := range s.nodes {
+		fy, fits := s.fit(i, w, h)
+		if !fits {
+			continue
+		}
+		if best == -1 || fy < bestY || (fy == bestY && s.nodes[i].w < bestW) {
+			best, bestY, bestW = i, fy, s.nodes[i].w
+		}
+	}
+	if best == -1 {
+		return 0, 0, false
+	}
+
+	x = s.nodes[best].x
+	s.add(best, skyNode{x, bestY + h, w})
+	return x, bestY, true
+}
+
+// add inserts node at index i and trims any following nodes that it now
+// covers.
+func (s *skyline) add(i int, node skyNode) {
+	s.nodes = append(s.nodes, skyNode{})
+	copy(s.nodes[i+1:], s.nodes[i:])
+	s.nodes[i] = node
+
+	for j := i + 1; j < len(s.nodes); {
+		prev, cur := s.nodes[j-1], &s.nodes[j]
+		overlap := prev.x + prev.w - cur.x
+		if overlap <= 0 {
+			break
+		}
+		cur.x += overlap
+		cur.w -= overlap
+		if cur.w > 0 {
+			break
+		}
+		s.nodes = append(s.nodes[:j], s.nodes[j+1:]...)
+	}
+
+	// Merge neighbouring segments at the same height.
+	for j := 0; j < len(s.nodes)-1; {
+		if s.nodes[j].y == s.nodes[j+1].y {
+			s.nodes[j].w += s.nodes[j+1].w
+			s.nodes = append(s.nodes[:j+1], s.nodes[j+2:]...)
+			continue
+		}
+		j++
+	}
+}
@@ -0,0 +1,45 @@
+package atlas
+
+import (
+	"image"
+	"testing"
+)
+
+func TestSkylineNoOverlap(t *testing.T) {
+	s := newSkyline(256, 256)
+	var placed []image.Rectangle
+	sizes := [][2]int{{64, 32}, {32, 64}, {100, 10}, {17, 17}, {200, 50}, {8, 8}, {56, 90}, {30, 30}}
+	for i := 0; i < 4; i++ {
+		for _, size := range sizes {
+			x, y, ok := s.insert(size[0], size[1])
+			if !ok {
+				continue
+			}
+			r := image.Rect(x, y, x+size[0], y+size[1])
+			if !r.In(image.Rect(0, 0, 256, 256)) {
+				t.Fatalf("%v is outside of the page", r)
+			}
+			for _, other := range placed {
+				if r.Overlaps(other) {
+					t.Fatalf("%v overlaps %v", r, other)
+				}
+			}
+			placed = append(placed, r)
+		}
+	}
+	if len(placed) == 0 {
+		t.Fatal("nothing was placed")
+	}
+}
+
+func TestSkylineFull(t *testing.T) {
+	s := newSkyline(64, 64)
+	for i := 0; i < 16; i++ {
+		if _, _, ok := s.insert(16, 16); !ok {
+			t.Fatalf("tile %d should fit", i)
+		}
+	}
+	if _, _, ok := s.insert(1, 1); ok {
+		t.Fatal("a full page should not accept more tiles")
+	}
+}
@@ -0,0 +1,137 @@
+package allegro
+
+// #include <allegro5/allegro.h>
+/*
+typedef struct {
+	ALLEGRO_BITMAP *bitmap;
+	float sx, sy, sw, sh;
+	ALLEGRO_COLOR tint;
+	float cx, cy, dx, dy, xscale, yscale, angle;
+	int flags;
+} bitmap_draw;
+
+typedef struct {
+	float x, y;
+	ALLEGRO_COLOR color;
+} pixel_draw;
+
+static void draw_bitmaps(bitmap_draw *draws, int n) {
+	int i;
+	for (i = 0; i < n; i++) {
+		bitmap_draw *d = &draws[i];
+		if (d->sw == 0 && d->sh == 0) {
+			d->sw = al_get_bitmap_width(d->bitmap);
+			d->sh = al_get_bitmap_height(d->bitmap);
+		}
+		al_draw_tinted_scaled_rotated_bitmap_region(d->bitmap,
+			d->sx, d->sy, d->sw, d->sh, d->tint,
+			d->cx, d->cy, d->dx, d->dy,
+			d->xscale, d->yscale, d->angle, d->flags);
+	}
+}
+
+static void draw_pixels(pixel_draw *draws, int n) {
+	int i;
+	for (i = 0; i < n; i++) {
+		al_draw_pixel(draws[i].x, draws[i].y, draws[i].color);
+	}
+}
+*/
+import "C"
+import (
+	"unsafe"
+)
+
+// BitmapDraw describes a single bitmap draw for DrawBitmaps. Its fields mirror
+// the parameters of DrawTintedScaledRotatedRegion.
+type BitmapDraw struct {
+	Bitmap *Bitmap
+
+	// SX, SY, SW and SH select a region of Bitmap. If SW and SH are both
+	// zero, the whole bitmap is drawn.
+	SX, SY, SW, SH float32
+
+	// Tint is multiplied with the bitmap's colors. The zero value draws the
+	// bitmap untinted.
+	Tint Color
+
+	CX, CY, DX, DY float32
+
+	// ScaleX and ScaleY default to 1 when left at zero.
+	ScaleX, ScaleY float32
+
+	Angle float32
+	Flags DrawFlags
+}
+
+// PixelDraw describes a single pixel for DrawPixels.
+type PixelDraw struct {
+	X, Y  float32
+	Color Color
+}
+
+var (
+	zeroColor  Color
+	whiteColor = Color(C.ALLEGRO_COLOR{r: 1, g: 1, b: 1, a: 1})
+)
+
+// DrawBitmaps draws each bitmap in turn to the target bitmap, making a single
+// call into Allegro for the whole slice rather than one per bitmap. Draws
+// with a nil Bitmap are skipped.
+//
+// Combine this with HoldBitmapDrawing when the bitmaps share a parent.
+func DrawBitmaps(draws []BitmapDraw) {
+	if len(draws) == 0 {
+		return
+	}
+	draws_ := make([]C.bitmap_draw, 0, len(draws))
+	for i := range draws {
+		d := &draws[i]
+		if d.Bitmap == nil {
+			continue
+		}
+		tint, xscale, yscale := d.Tint, d.ScaleX, d.ScaleY
+		if tint == zeroColor {
+			tint = whiteColor
+		}
+		if xscale == 0 {
+			xscale = 1
+		}
+		if yscale == 0 {
+			yscale = 1
+		}
+		draws_ = append(draws_, C.bitmap_draw{
+			bitmap: (*C.ALLEGRO_BITMAP)(d.Bitmap),
+			sx:     C.float(d.SX),
+			sy:     C.float(d.SY),
+			sw:     C.float(d.SW),
+			sh:     C.float(d.SH),
+			tint:   C.ALLEGRO_COLOR(tint),
+			cx:     C.float(d.CX),
+			cy:     C.float(d.CY),
+			dx:     C.float(d.DX),
+			dy:     C.float(d.DY),
+			xscale: C.float(xscale),
+			yscale: C.float(yscale),
+			angle:  C.float(d.Angle),
+			flags:  C.int(d.Flags),
+		})
+	}
+	if len(draws_) == 0 {
+		return
+	}
+	C.draw_bitmaps((*C.bitmap_draw)(unsafe.Pointer(&draws_[0])), C.int(len(draws_)))
+}
+
+// DrawPixels draws each pixel in turn with DrawPixel, making a single call
+// into Allegro for the whole slice rather than one per pixel.
+func DrawPixels(pixels []PixelDraw) {
+	if len(pixels) == 0 {
+		return
+	}
+	pixels_ := make([]C.pixel_draw, len(pixels))
+	for i, p := range pixels {
+		pixels_[i] = C.pixel_draw{x: C.float(p.X), y: C.float(p.Y), color: C.ALLEGRO_COLOR(p.Color)}
+	}
+	C.draw_pixels((*C.pixel_draw)(unsafe.Pointer(&pixels_[0])), C.int(len(pixels_)))
+}
@@ -0,0 +1,76 @@
+package allegro_test
+
+import (
+	"testing"
+
+	"github.com/dradtke/go-allegro/allegro"
+	"github.com/dradtke/go-allegro/allegro/allegrotest"
+)
+
+const benchDraws = 1000
+
+// benchDrawing runs f on the Allegro thread with a memory bitmap as the
+// target and a small memory bitmap to draw.
+func benchDrawing(b *testing.B, f func(sprite *allegro.Bitmap)) {
+	allegrotest.Do(func() {
+		flags := allegro.NewBitmapFlags()
+		defer allegro.SetNewBitmapFlags(flags)
+		allegro.SetNewBitmapFlags(allegro.MEMORY_BITMAP)
+		target := allegro.CreateBitmap(256, 256)
+		defer target.Destroy()
+		sprite := allegro.CreateBitmap(16, 16)
+		defer sprite.Destroy()
+		target.AsTarget(func() {
+			b.ResetTimer()
+			f(sprite)
+			b.StopTimer()
+		})
+	})
+}
+
+func BenchmarkDrawTintedScaledRotatedRegion(b *testing.B) {
+	benchDrawing(b, func(sprite *allegro.Bitmap) {
+		tint := allegro.MapRGBAf(1, 1, 1, 1)
+		for n := 0; n < b.N; n++ {
+			for i := 0; i < benchDraws; i++ {
+				sprite.DrawTintedScaledRotatedRegion(0, 0, 16, 16, tint, 8, 8, float32(i%256), float32(i/4%256), 1, 1, 0, allegro.FLIP_NONE)
+			}
+		}
+	})
+}
+
+func BenchmarkDrawBitmaps(b *testing.B) {
+	benchDrawing(b, func(sprite *allegro.Bitmap) {
+		draws := make([]allegro.BitmapDraw, benchDraws)
+		for i := range draws {
+			draws[i] = allegro.BitmapDraw{Bitmap: sprite, CX: 8, CY: 8, DX: float32(i % 256), DY: float32(i / 4 % 256)}
+		}
+		for n := 0; n < b.N; n++ {
+			allegro.DrawBitmaps(draws)
+		}
+	})
+}
+
+func BenchmarkDrawPixel(b *testing.B) {
+	benchDrawing(b, func(*allegro.Bitmap) {
+		color := allegro.MapRGB(0xFF, 0, 0)
+		for n := 0; n < b.N; n++ {
+			for i := 0; i < benchDraws; i++ {
+				allegro.DrawPixel(float32(i%256), float32(i/256), color)
+			}
+		}
+	})
+}
+
+func BenchmarkDrawPixels(b *testing.B) {
+	benchDrawing(b, func(*allegro.Bitmap) {
+		color := allegro.MapRGB(0xFF, 0, 0)
+		pixels := make([]allegro.PixelDraw, benchDraws)
+		for i := range pixels {
+			pixels[i] = allegro.PixelDraw{X: float32(i % 256), Y: float32(i / 256), Color: color}
+		}
+		for n := 0; n < b.N; n++ {
+			allegro.DrawPixels(pixels)
+		}
+	})
+}
@@ -0,0 +1,268 @@
+// Package camera provides a 2D camera for scrolling, zooming and rotating a
+// view of a larger world.
+//
+// Each frame, call Update to advance following and shake, then draw the
+// world inside Draw:
+//
+//	cam := camera.New(320, 240)
+//	cam.Follow(player.X, player.Y)
+//	cam.Update(timer.Speed())
+//	cam.Draw(func() {
+//		// draw the world in world coordinates
+//	})
+//
+// Camera math is done in Go with transform.Matrix, so only Draw needs a
+// target bitmap.
+package camera
+
+import (
+	"math"
+	"math/rand"
+
+	"github.com/dradtke/go-allegro/allegro"
+	"github.com/dradtke/go-allegro/allegro/transform"
+)
+
+// Rect is an axis-aligned rectangle in world coordinates.
+type Rect struct {
+	X, Y, W, H float32
+}
+
+// Camera maps world coordinates onto a viewport.
+type Camera struct {
+	// X and Y are the world position shown at the center of the viewport.
+	X, Y float32
+
+	// Zoom is the magnification; the zero value is treated as 1.
+	Zoom float32
+
+	// Rotation is the camera's rotation in radians. Rotating the camera
+	// clockwise turns the world counter-clockwise on screen.
+	Rotation float32
+
+	// Width and Height are the size of the viewport, in the units of the
+	// transform in effect when Draw is called (usually pixels).
+	Width, Height float32
+
+	// Bounds, if set, limits the camera so that it never shows anything
+	// outside of it. Rotation is not taken into account. If the world is
+	// smaller than the view, it is centered instead.
+	Bounds *Rect
+
+	// DeadzoneWidth and DeadzoneHeight are the size, in world units, of a
+	// rectangle around the center of the view within which a followed
+	// target can move without moving the camera.
+	DeadzoneWidth, DeadzoneHeight float32
+
+	// FollowSpeed controls how quickly the camera catches up with its
+	// target: after t seconds it has covered 1-e^(-FollowSpeed*t) of the
+	// distance, independent of frame rate. Zero snaps straight to the
+	// target.
+	FollowSpeed float64
+
+	following        bool
+	targetX, targetY float32
+
+	shakeMagnitude       float32
+	shakeTime, shakeLeft float64
+	shakeX, shakeY       float32
+
+	base    transform.Matrix
+	hasBase bool
+}
+
+// New returns a camera with a viewport of the given size, centered on the
+// world origin.
+func New(width, height float32) *Camera {
+	return &Camera{Width: width, Height: height, Zoom: 1}
+}
+
+// Follow sets the world position that the camera should track. Call it
+// every frame with the target's latest position.
+func (c *Camera) Follow(x, y float32) {
+	c.following = true
+	c.targetX, c.targetY = x, y
+}
+
+// StopFollowing stops tracking the target, leaving the camera where it is.
+func (c *Camera) StopFollowing() {
+	c.following = false
+}
+
+// Shake starts a screen shake that moves the view by up to magnitude units
+// in each direction, fading out over duration seconds. A stronger shake
+// replaces a weaker one that is still running.
+func (c *Camera) Shake(magnitude float32, duration float64) {
+	if c.shakeLeft > 0 && c.currentShake() > magnitude {
+		return
+	}
+	c.shakeMagnitude = magnitude
+	c.shakeTime, c.shakeLeft = duration, duration
+}
+
+func (c *Camera) currentShake() float32 {
+	if c.shakeLeft <= 0 || c.shakeTime <= 0 {
+		return 0
+	}
+	return c.shakeMagnitude * float32(c.shakeLeft/c.shakeTime)
+}
+
+// Update advances following and shaking by dt seconds, then applies Bounds.
+func (c *Camera) Update(dt float64) {
+	if c.following {
+		x, y := c.deadzone(c.targetX, c.targetY)
+		if c.FollowSpeed <= 0 {
+			c.X, c.Y = x, y
+		} else {
+			k := float32(1 - math.Exp(-c.FollowSpeed*dt))
+			c.X += (x - c.X) * k
+			c.Y += (y - c.Y) * k
+		}
+	}
+
+	c.shakeX, c.shakeY = 0, 0
+	if c.shakeLeft > 0 {
+		c.shakeLeft -= dt
+		if m := c.currentShake(); m > 0 {
+			c.shakeX = m * (2*rand.Float32() - 1)
+			c.shakeY = m * (2*rand.Float32() - 1)
+		}
+	}
+	c.Clamp()
+}
+
+// deadzone returns where the camera needs to be for x, y to sit inside the
+// deadzone.
+func (c *Camera) deadzone(x, y float32) (float32, float32) {
+	cx, cy := c.X, c.Y
+	hw, hh := c.DeadzoneWidth/2, c.DeadzoneHeight/2
+	if x < cx-hw {
+		cx = x + hw
+	} else if x > cx+hw {
+		cx = x - hw
+	}
+	if y < cy-hh {
+		cy = y + hh
+	} else if y > cy+hh {
+		cy = y - hh
+	}
+	return cx, cy
+}
+
+// Clamp moves the camera back inside Bounds, if set. Update calls it
+// automatically.
+func (c *Camera) Clamp() {
+	if c.Bounds == nil {
+		return
+	}
+	b := c.Bounds
+	hw, hh := c.Width/2/c.zoom(), c.Height/2/c.zoom()
+	c.X = clamp(c.X, b.X+hw, b.X+b.W-hw)
+	c.Y = clamp(c.Y, b.Y+hh, b.Y+b.H-hh)
+}
+
+func clamp(v, min, max float32) float32 {
+	if min > max {
+		return (min + max) / 2
+	}
+	if v < min {
+		return min
+	}
+	if v > max {
+		return max
+	}
+	return v
+}
+
+func (c *Camera) zoom() float32 {
+	if c.Zoom == 0 {
+		return 1
+	}
+	return c.Zoom
+}
+
+// Matrix returns the camera's world-to-viewport transformation, including
+// any shake.
+func (c *Camera) Matrix() transform.Matrix {
+	m := transform.Identity()
+	m.Translate(-c.X, -c.Y)
+	m.Rotate(-c.Rotation)
+	m.Scale(c.zoom(), c.zoom())
+	m.Translate(c.Width/2+c.shakeX, c.Height/2+c.shakeY)
+	return m
+}
+
+// screen returns the full world-to-screen transformation, composing the
+// camera with the transform that was in effect when Draw was last called.
+func (c *Camera) screen() transform.Matrix {
+	m := c.Matrix()
+	if c.hasBase {
+		m.Compose(&c.base)
+	} else if t := allegro.CurrentTransform(); t != nil {
+		base := t.Matrix()
+		m.Compose(&base)
+	}
+	return m
+}
+
+// Draw calls f with the camera's transformation composed onto the target
+// bitmap's current transformation, restoring the original afterwards.
+func (c *Camera) Draw(f func()) {
+	t := allegro.CurrentTransform()
+	if t == nil {
+		return
+	}
+	base := t.Matrix()
+	c.base, c.hasBase = base, true
+	m := c.Matrix()
+	m.Compose(&base)
+	allegro.UseTransform(allegro.TransformFromMatrix(&m))
+	defer allegro.UseTransform(allegro.TransformFromMatrix(&base))
+	f()
+}
+
+// WorldToScreen converts a world position to screen coordinates, such as
+// those reported by mouse events. The transformation that was in effect when
+// Draw was last called is taken into account, or the target bitmap's
+// current transformation if Draw hasn't been called yet.
+func (c *Camera) WorldToScreen(x, y float32) (float32, float32) {
+	m := c.screen()
+	return m.Coordinates(x, y)
+}
+
+// ScreenToWorld converts screen coordinates, such as those reported by mouse
+// events, to a world position; see WorldToScreen.
+func (c *Camera) ScreenToWorld(x, y float32) (float32, float32) {
+	m := c.screen()
+	m.Invert()
+	return m.Coordinates(x, y)
+}
+
+// View returns the smallest rectangle containing everything in the world
+// that the camera can see, which is useful for culling.
+func (c *Camera) View() Rect {
+	m := c.Matrix()
+	m.Invert()
+	minX, minY := float32(math.Inf(1)), float32(math.Inf(1))
+	maxX, maxY := float32(math.Inf(-1)), float32(math.Inf(-1))
+	for _, p := range [...][2]float32{{0, 0}, {c.Width, 0}, {0, c.Height}, {c.Width, c.Height}} {
+		x, y := m.Coordinates(p[0], p[1])
+		minX, maxX = min(minX, x), max(maxX, x)
+		minY, maxY = min(minY, y), max(maxY, y)
+	}
+	return Rect{X: minX, Y: minY, W: maxX - minX, H: maxY - minY}
+}
+
+func min(a, b float32) float32 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func max(a, b float32) float32 {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,56 @@
+package camera
+
+import (
+	"math"
+	"testing"
+
+	"github.com/dradtke/go-allegro/allegro/transform"
+)
+
+func near(a, b float32) bool {
+	return math.Abs(float64(a-b)) < 1e-3
+}
+
+func TestScreenToWorld(t *testing.T) {
+	c := New(320, 240)
+	c.X, c.Y, c.Zoom, c.Rotation = 100, 50, 2, 0.7
+	// Pretend the camera was drawn under a 3x letterbox scale.
+	c.base, c.hasBase = transform.Build(10, 0, 3, 3, 0), true
+
+	sx, sy := c.WorldToScreen(100, 50)
+	if !near(sx, 10+160*3) || !near(sy, 120*3) {
+		t.Errorf("camera center is at (%v, %v) on screen", sx, sy)
+	}
+	x, y := c.ScreenToWorld(c.WorldToScreen(130, -20))
+	if !near(x, 130) || !near(y, -20) {
+		t.Errorf("round trip gave (%v, %v)", x, y)
+	}
+}
+
+func TestFollowDeadzone(t *testing.T) {
+	c := New(320, 240)
+	c.DeadzoneWidth, c.DeadzoneHeight = 40, 20
+	c.Follow(15, -5)
+	c.Update(1)
+	if c.X != 0 || c.Y != 0 {
+		t.Errorf("camera moved to (%v, %v) for a target inside the deadzone", c.X, c.Y)
+	}
+	c.Follow(50, 30)
+	c.Update(1)
+	if c.X != 30 || c.Y != 20 {
+		t.Errorf("camera moved to (%v, %v), want (30, 20)", c.X, c.Y)
+	}
+}
+
+func TestBounds(t *testing.T) {
+	c := New(100, 100)
+	c.Bounds = &Rect{X: 0, Y: 0, W: 400, H: 80}
+	c.Follow(-50, 500)
+	c.Update(1)
+	if c.X != 50 || c.Y != 40 {
+		t.Errorf("camera clamped to (%v, %v), want (50, 40)", c.X, c.Y)
+	}
+	if v := c.View(); v != (Rect{X: 0, Y: -10, W: 100, H: 100}) {
+		t.Errorf("got view %+v", v)
+	}
+}
@@ -0,0 +1,110 @@
+// Package capture saves what has been drawn to a bitmap, usually the
+// display's backbuffer, as screenshots, numbered image sequences or animated
+// GIFs.
+//
+// Capture a frame once it has been fully drawn, just before calling
+// FlipDisplay; Allegro does not define what the backbuffer holds after a
+// flip.
+package capture
+
+import (
+	"fmt"
+	"image"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/dradtke/go-allegro/allegro"
+)
+
+// Grab copies the contents of a bitmap into a new image.
+func Grab(bmp *allegro.Bitmap) (*image.RGBA, error) {
+	if bmp == nil {
+		return nil, allegro.BitmapIsNull
+	}
+	w, h := bmp.Width(), bmp.Height()
+	reg, err := bmp.Lock(allegro.PIXEL_FORMAT_ABGR_8888_LE, allegro.LOCK_READONLY)
+	if err != nil {
+		return nil, err
+	}
+	defer bmp.Unlock()
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		copy(img.Pix[y*img.Stride:], reg.Row(y, w))
+	}
+	return img, nil
+}
+
+// Screenshot saves a bitmap to a timestamped PNG file in dir, such as
+// "screenshot-20060102-150405.000.png", and returns the file's name. Saving
+// requires the image addon.
+func Screenshot(bmp *allegro.Bitmap, dir string) (string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	name := filepath.Join(dir, "screenshot-"+time.Now().Format("20060102-150405.000")+".png")
+	if err := bmp.Save(name); err != nil {
+		return "", err
+	}
+	return name, nil
+}
+
+// Sequence saves a run of frames as numbered image files. Saving requires
+// the image addon.
+type Sequence struct {
+	// Pattern is a fmt format for the file names, given the frame number,
+	// such as "frames/%05d.png". The extension picks the file type.
+	Pattern string
+
+	// Skip is the number of frames to let pass before saving the first.
+	Skip int
+
+	// Count is the number of frames to save; zero or less saves frames
+	// until Stop is called.
+	Count int
+
+	frame, saved int
+	stopped      bool
+}
+
+// NewSequence returns a sequence that saves count frames using pattern.
+func NewSequence(pattern string, count int) *Sequence {
+	return &Sequence{Pattern: pattern, Count: count}
+}
+
+// Capture should be called once per frame. It saves the bitmap if the frame
+// falls within the sequence's range, numbering files from zero.
+func (s *Sequence) Capture(bmp *allegro.Bitmap) error {
+	if s.Done() {
+		return nil
+	}
+	s.frame++
+	if s.frame <= s.Skip {
+		return nil
+	}
+	name := fmt.Sprintf(s.Pattern, s.saved)
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		return err
+	}
+	if err := bmp.Save(name); err != nil {
+		return err
+	}
+	s.saved++
+	return nil
+}
+
+// Stop ends the sequence early.
+func (s *Sequence) Stop() {
+	s.stopped = true
+}
+
+// Saved returns the number of files saved so far.
+func (s *Sequence) Saved() int {
+	return s.saved
+}
+
+// Done reports whether the sequence has saved all of its frames or been
+// stopped.
+func (s *Sequence) Done() bool {
+	return s.stopped || (s.Count > 0 && s.saved >= s.Count)
+}
@@ -0,0 +1,151 @@
+package capture
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"io"
+	"os"
+
+	"github.com/dradtke/go-allegro/allegro"
+)
+
+// GIF records frames into an animated GIF. Grabbing a frame happens on the
+// calling thread, but palette quantization and encoding run on a background
+// goroutine so that recording doesn't stall the game.
+type GIF struct {
+	frames chan *image.RGBA
+	done   chan error
+	err    error
+	closed bool
+}
+
+// GIFOptions controls how a GIF is encoded.
+type GIFOptions struct {
+	// Delay is the time each frame is shown, in seconds. GIF delays are
+	// stored in hundredths of a second.
+	Delay float64
+
+	// Colors is the palette size, up to 256. Zero means 256.
+	Colors int
+
+	// Dither enables Floyd-Steinberg dithering, which is slower but avoids
+	// banding in gradients. Leave it off for pixel art.
+	Dither bool
+
+	// LoopCount is passed to image/gif: 0 loops forever, -1 plays once.
+	LoopCount int
+}
+
+// RecordGIF starts recording a GIF that is written to w when Close is called.
+func RecordGIF(w io.Writer, opts GIFOptions) *GIF {
+	g := newGIF()
+	go func() {
+		g.done <- g.encode(w, opts)
+	}()
+	return g
+}
+
+func newGIF() *GIF {
+	return &GIF{
+		frames: make(chan *image.RGBA, 16),
+		done:   make(chan error, 1),
+	}
+}
+
+// RecordGIFFile starts recording a GIF to a file, which is created
+// immediately and closed by Close.
+func RecordGIFFile(filename string, opts GIFOptions) (*GIF, error) {
+	f, err := os.Create(filename)
+	if err != nil {
+		return nil, err
+	}
+	g := newGIF()
+	go func() {
+		err := g.encode(f, opts)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		g.done <- err
+	}()
+	return g, nil
+}
+
+// Capture grabs the bitmap's contents as the next frame.
+func (g *GIF) Capture(bmp *allegro.Bitmap) error {
+	if g.closed {
+		return errors.New("GIF recording is closed")
+	}
+	img, err := Grab(bmp)
+	if err != nil {
+		return err
+	}
+	g.frames <- img
+	return nil
+}
+
+// Close finishes recording, waits for the GIF to be encoded and written, and
+// returns any error from doing so.
+func (g *GIF) Close() error {
+	if g.closed {
+		return g.err
+	}
+	g.closed = true
+	close(g.frames)
+	g.err = <-g.done
+	return g.err
+}
+
+func (g *GIF) encode(w io.Writer, opts GIFOptions) error {
+	colors := opts.Colors
+	if colors <= 0 || colors > 256 {
+		colors = 256
+	}
+	delay := int(opts.Delay*100 + 0.5)
+	anim := &gif.GIF{LoopCount: opts.LoopCount}
+	for img := range g.frames {
+		anim.Image = append(anim.Image, quantize(img, colors, opts.Dither))
+		anim.Delay = append(anim.Delay, delay)
+	}
+	if len(anim.Image) == 0 {
+		return errors.New("no frames were captured")
+	}
+	return gif.EncodeAll(w, anim)
+}
+
+// quantize converts img to a paletted image with a palette chosen for it.
+// GIF frames are opaque, so img's alpha is set to 0xFF first, whatever the
+// backbuffer held.
+func quantize(img *image.RGBA, colors int, dither bool) *image.Paletted {
+	b := img.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			img.Pix[img.PixOffset(x, y)+3] = 0xFF
+		}
+	}
+	palette := medianCut(img, colors)
+	dst := image.NewPaletted(b, palette)
+	if dither {
+		draw.FloydSteinberg.Draw(dst, b, img, b.Min)
+		return dst
+	}
+
+	// Map each pixel to its nearest palette entry, caching the result as
+	// frames tend to use far fewer colors than they have pixels.
+	cache := make(map[[3]uint8]uint8)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			p := img.Pix[img.PixOffset(x, y):]
+			key := [3]uint8{p[0], p[1], p[2]}
+			index, ok := cache[key]
+			if !ok {
+				index = uint8(palette.Index(color.RGBA{p[0], p[1], p[2], 0xFF}))
+				cache[key] = index
+			}
+			dst.Pix[dst.PixOffset(x, y)] = index
+		}
+	}
+	return dst
+}
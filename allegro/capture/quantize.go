@@ -0,0 +1,132 @@
+package capture
+
+import (
+	"image"
+	"image/color"
+	"sort"
+)
+
+type colorCount struct {
+	c     [3]uint8
+	count int
+}
+
+// box is a set of colors that will be represented by one palette entry.
+type box []colorCount
+
+// medianCut picks a palette of at most n colors for img by repeatedly
+// splitting the box of colors with the widest spread at its median. Images
+// with n colors or fewer keep their exact colors. Alpha is ignored.
+func medianCut(img *image.RGBA, n int) color.Palette {
+	counts := make(map[[3]uint8]int)
+	b := img.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			p := img.Pix[img.PixOffset(x, y):]
+			counts[[3]uint8{p[0], p[1], p[2]}]++
+		}
+	}
+	all := make(box, 0, len(counts))
+	for c, count := range counts {
+		all = append(all, colorCount{c, count})
+	}
+	// Map iteration is random; sort so the palette is deterministic.
+	sort.Slice(all, func(i, j int) bool {
+		a, b := all[i].c, all[j].c
+		if a[0] != b[0] {
+			return a[0] < b[0]
+		}
+		if a[1] != b[1] {
+			return a[1] < b[1]
+		}
+		return a[2] < b[2]
+	})
+
+	boxes := []box{all}
+	for len(boxes) < n {
+		best, bestScore := -1, 0
+		for i, bx := range boxes {
+			if len(bx) < 2 {
+				continue
+			}
+			_, spread := bx.widest()
+			if score := spread * bx.total(); score > bestScore {
+				best, bestScore = i, score
+			}
+		}
+		if best < 0 {
+			break
+		}
+		lo, hi := boxes[best].split()
+		boxes[best] = lo
+		boxes = append(boxes, hi)
+	}
+
+	palette := make(color.Palette, 0, len(boxes))
+	for _, bx := range boxes {
+		if len(bx) > 0 {
+			palette = append(palette, bx.average())
+		}
+	}
+	if len(palette) == 0 {
+		palette = append(palette, color.RGBA{0, 0, 0, 0xFF})
+	}
+	return palette
+}
+
+func (bx box) total() int {
+	n := 0
+	for _, c := range bx {
+		n += c.count
+	}
+	return n
+}
+
+// widest returns the channel with the largest range of values, and that
+// range.
+func (bx box) widest() (channel, spread int) {
+	for ch := 0; ch < 3; ch++ {
+		min, max := 255, 0
+		for _, c := range bx {
+			v := int(c.c[ch])
+			if v < min {
+				min = v
+			}
+			if v > max {
+				max = v
+			}
+		}
+		if max-min > spread {
+			channel, spread = ch, max-min
+		}
+	}
+	return channel, spread
+}
+
+// split divides the box at the weighted median of its widest channel.
+func (bx box) split() (box, box) {
+	ch, _ := bx.widest()
+	sort.Slice(bx, func(i, j int) bool { return bx[i].c[ch] < bx[j].c[ch] })
+	half, seen := bx.total()/2, 0
+	for i, c := range bx {
+		seen += c.count
+		if seen >= half {
+			if i == len(bx)-1 {
+				i--
+			}
+			return bx[:i+1], bx[i+1:]
+		}
+	}
+	return bx[:len(bx)/2], bx[len(bx)/2:]
+}
+
+func (bx box) average() color.Color {
+	var r, g, b, n int
+	for _, c := range bx {
+		r += int(c.c[0]) * c.count
+		g += int(c.c[1]) * c.count
+		b += int(c.c[2]) * c.count
+		n += c.count
+	}
+	return color.RGBA{uint8((r + n/2) / n), uint8((g + n/2) / n), uint8((b + n/2) / n), 0xFF}
+}
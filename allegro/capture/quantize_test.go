@@ -0,0 +1,79 @@
+package capture
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/gif"
+	"testing"
+)
+
+func TestQuantizeKeepsExactColors(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 16, 16))
+	for y := 0; y < 16; y++ {
+		for x := 0; x < 16; x++ {
+			img.Set(x, y, color.RGBA{uint8(x * 16), uint8(y * 16), uint8(x + y), 0xFF})
+		}
+	}
+	dst := quantize(img, 256, false)
+	for y := 0; y < 16; y++ {
+		for x := 0; x < 16; x++ {
+			want := img.RGBAAt(x, y)
+			r, g, b, _ := dst.At(x, y).RGBA()
+			if got := (color.RGBA{uint8(r >> 8), uint8(g >> 8), uint8(b >> 8), 0xFF}); got != want {
+				t.Fatalf("pixel (%d, %d) = %v, want %v", x, y, got, want)
+			}
+		}
+	}
+}
+
+func TestQuantizeIgnoresAlpha(t *testing.T) {
+	frame := func() *image.RGBA {
+		img := image.NewRGBA(image.Rect(0, 0, 16, 16))
+		for y := 0; y < 16; y++ {
+			for x := 0; x < 16; x++ {
+				img.Set(x, y, color.RGBA{uint8(x * 8), uint8(y * 8), 0x40, 0x80})
+			}
+		}
+		return img
+	}
+	plain, dithered := quantize(frame(), 256, false), quantize(frame(), 256, true)
+	if !bytes.Equal(plain.Pix, dithered.Pix) {
+		t.Error("dithering changed a frame whose colors are all in its palette")
+	}
+}
+
+func TestMedianCutLimitsColors(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 64, 64))
+	for y := 0; y < 64; y++ {
+		for x := 0; x < 64; x++ {
+			img.Set(x, y, color.RGBA{uint8(x * 4), uint8(y * 4), 128, 0xFF})
+		}
+	}
+	if n := len(medianCut(img, 16)); n != 16 {
+		t.Errorf("palette has %d colors, want 16", n)
+	}
+}
+
+func TestEncodeGIF(t *testing.T) {
+	var buf bytes.Buffer
+	g := newGIF()
+	go func() {
+		g.done <- g.encode(&buf, GIFOptions{Delay: 0.1})
+	}()
+	for i := 0; i < 3; i++ {
+		img := image.NewRGBA(image.Rect(0, 0, 4, 4))
+		img.Set(i, i, color.RGBA{0xFF, 0, 0, 0xFF})
+		g.frames <- img
+	}
+	if err := g.Close(); err != nil {
+		t.Fatal(err)
+	}
+	anim, err := gif.DecodeAll(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(anim.Image) != 3 || anim.Delay[0] != 10 {
+		t.Errorf("got %d frames with delay %d, want 3 frames with delay 10", len(anim.Image), anim.Delay[0])
+	}
+}
@@ -0,0 +1,247 @@
+// Package collision provides pixel-perfect collision masks.
+//
+// A Mask records which pixels of a bitmap are solid, packed one bit per
+// pixel, so that testing two sprites for overlap compares 64 pixels at a time
+// instead of reading them from the bitmaps:
+//
+//	mask, err := collision.FromBitmap(sprite, 0)
+//	...
+//	if mask.Overlaps(other, int(ox-px), int(oy-py)) {
+//		// the sprites touch
+//	}
+package collision
+
+import (
+	"image"
+	"math/bits"
+
+	"github.com/dradtke/go-allegro/allegro"
+)
+
+// Mask is a collision mask: a grid of bits, one per pixel, where set bits are
+// solid.
+type Mask struct {
+	width, height int
+	stride        int // words per row
+	bits          []uint64
+}
+
+// New creates an empty mask.
+func New(width, height int) *Mask {
+	if width < 0 {
+		width = 0
+	}
+	if height < 0 {
+		height = 0
+	}
+	stride := (width + 63) / 64
+	return &Mask{width: width, height: height, stride: stride, bits: make([]uint64, stride*height)}
+}
+
+// FromBitmap creates a mask from a bitmap's alpha channel, treating pixels
+// with an alpha greater than threshold as solid. Sub-bitmaps work too, and
+// produce a mask of just their own area.
+//
+// The bitmap is locked while it is read, so it must not already be locked.
+// Masks are meant to be built once, such as when a sprite is loaded, rather
+// than every frame.
+func FromBitmap(bmp *allegro.Bitmap, threshold uint8) (*Mask, error) {
+	if bmp == nil {
+		return nil, allegro.BitmapIsNull
+	}
+	return FromBitmapRegion(bmp, 0, 0, bmp.Width(), bmp.Height(), threshold)
+}
+
+// FromBitmapRegion creates a mask from part of a bitmap's alpha channel,
+// such as one frame of a sprite sheet, treating pixels with an alpha greater
+// than threshold as solid. Only the region is locked.
+func FromBitmapRegion(bmp *allegro.Bitmap, x, y, width, height int, threshold uint8) (*Mask, error) {
+	reg, err := bmp.LockRegion(x, y, width, height, allegro.PIXEL_FORMAT_ABGR_8888_LE, allegro.LOCK_READONLY)
+	if err != nil {
+		return nil, err
+	}
+	defer bmp.Unlock()
+	m := New(width, height)
+	for j := 0; j < height; j++ {
+		row := reg.Row(j, width)
+		for i := 0; i < width; i++ {
+			if row[i*4+3] > threshold {
+				m.set(i, j)
+			}
+		}
+	}
+	return m, nil
+}
+
+// FromImage creates a mask from an image's alpha channel, treating pixels
+// with an alpha greater than threshold as solid.
+func FromImage(img image.Image, threshold uint8) *Mask {
+	b := img.Bounds()
+	m := New(b.Dx(), b.Dy())
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if _, _, _, a := img.At(x, y).RGBA(); uint8(a>>8) > threshold {
+				m.set(x-b.Min.X, y-b.Min.Y)
+			}
+		}
+	}
+	return m
+}
+
+// Width returns the width of the mask.
+func (m *Mask) Width() int {
+	return m.width
+}
+
+// Height returns the height of the mask.
+func (m *Mask) Height() int {
+	return m.height
+}
+
+// Get reports whether the pixel at x, y is solid. Pixels outside of the mask
+// are not.
+func (m *Mask) Get(x, y int) bool {
+	if x < 0 || y < 0 || x >= m.width || y >= m.height {
+		return false
+	}
+	return m.bits[y*m.stride+x>>6]&(1<<uint(x&63)) != 0
+}
+
+// Set marks the pixel at x, y as solid or not. Pixels outside of the mask are
+// ignored.
+func (m *Mask) Set(x, y int, solid bool) {
+	if x < 0 || y < 0 || x >= m.width || y >= m.height {
+		return
+	}
+	if solid {
+		m.set(x, y)
+	} else {
+		m.bits[y*m.stride+x>>6] &^= 1 << uint(x&63)
+	}
+}
+
+func (m *Mask) set(x, y int) {
+	m.bits[y*m.stride+x>>6] |= 1 << uint(x&63)
+}
+
+// Count returns the number of solid pixels.
+func (m *Mask) Count() int {
+	n := 0
+	for _, w := range m.bits {
+		n += bits.OnesCount64(w)
+	}
+	return n
+}
+
+// Sub returns a new mask of part of m, such as one frame of a sprite sheet.
+// Parts of the region outside of m are empty.
+func (m *Mask) Sub(x, y, width, height int) *Mask {
+	sub := New(width, height)
+	for j := 0; j < sub.height; j++ {
+		for i := 0; i < sub.width; i++ {
+			if m.Get(x+i, y+j) {
+				sub.set(i, j)
+			}
+		}
+	}
+	return sub
+}
+
+// Flip returns a new mask flipped to match a bitmap drawn with the given
+// flags, which may include allegro.FLIP_HORIZONTAL and allegro.FLIP_VERTICAL.
+func (m *Mask) Flip(flags allegro.DrawFlags) *Mask {
+	flipped := New(m.width, m.height)
+	for y := 0; y < m.height; y++ {
+		fy := y
+		if flags&allegro.FLIP_VERTICAL != 0 {
+			fy = m.height - 1 - y
+		}
+		for x := 0; x < m.width; x++ {
+			fx := x
+			if flags&allegro.FLIP_HORIZONTAL != 0 {
+				fx = m.width - 1 - x
+			}
+			if m.Get(x, y) {
+				flipped.set(fx, fy)
+			}
+		}
+	}
+	return flipped
+}
+
+// BoundsOverlap reports whether the bounding boxes of m and other overlap
+// when other's top-left corner is placed at dx, dy relative to m's. It is a
+// cheap test to run before Overlaps, which runs it too.
+func (m *Mask) BoundsOverlap(other *Mask, dx, dy int) bool {
+	return dx < m.width && dy < m.height && dx+other.width > 0 && dy+other.height > 0
+}
+
+// Overlaps reports whether any solid pixels of m and other overlap when
+// other's top-left corner is placed at dx, dy relative to m's.
+func (m *Mask) Overlaps(other *Mask, dx, dy int) bool {
+	found := false
+	m.overlap(other, dx, dy, func(w uint64) bool {
+		found = true
+		return false
+	})
+	return found
+}
+
+// OverlapCount returns the number of solid pixels that m and other have in
+// common when other's top-left corner is placed at dx, dy relative to m's.
+// It can be used to estimate how deeply two objects intersect.
+func (m *Mask) OverlapCount(other *Mask, dx, dy int) int {
+	n := 0
+	m.overlap(other, dx, dy, func(w uint64) bool {
+		n += bits.OnesCount64(w)
+		return true
+	})
+	return n
+}
+
+// overlap calls f with each non-zero word of overlapping bits, stopping if f
+// returns false.
+func (m *Mask) overlap(other *Mask, dx, dy int, f func(uint64) bool) {
+	if !m.BoundsOverlap(other, dx, dy) {
+		return
+	}
+	x0, y0 := max(0, dx), max(0, dy)
+	x1, y1 := min(m.width, dx+other.width), min(m.height, dy+other.height)
+	for y := y0; y < y1; y++ {
+		for x := x0; x < x1; x += 64 {
+			w := m.word(x, y) & other.word(x-dx, y-dy)
+			if n := x1 - x; n < 64 {
+				w &= 1<<uint(n) - 1
+			}
+			if w != 0 && !f(w) {
+				return
+			}
+		}
+	}
+}
+
+// word returns the 64 bits of row y starting at column x, which must be
+// within the mask. Bits past the end of the row are zero.
+func (m *Mask) word(x, y int) uint64 {
+	row := m.bits[y*m.stride : (y+1)*m.stride]
+	i, s := x>>6, uint(x&63)
+	w := row[i] >> s
+	if s != 0 && i+1 < len(row) {
+		w |= row[i+1] << (64 - s)
+	}
+	return w
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
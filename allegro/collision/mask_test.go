@@ -0,0 +1,75 @@
+package collision
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/dradtke/go-allegro/allegro"
+)
+
+// naiveOverlap counts overlapping pixels one at a time.
+func naiveOverlap(a, b *Mask, dx, dy int) int {
+	n := 0
+	for y := 0; y < a.Height(); y++ {
+		for x := 0; x < a.Width(); x++ {
+			if a.Get(x, y) && b.Get(x-dx, y-dy) {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+func randomMask(r *rand.Rand, w, h int) *Mask {
+	m := New(w, h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			m.Set(x, y, r.Intn(8) == 0)
+		}
+	}
+	return m
+}
+
+func TestOverlap(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	a, b := randomMask(r, 150, 20), randomMask(r, 70, 30)
+	for dy := -31; dy <= 21; dy += 3 {
+		for dx := -72; dx <= 152; dx++ {
+			want := naiveOverlap(a, b, dx, dy)
+			if got := a.OverlapCount(b, dx, dy); got != want {
+				t.Fatalf("OverlapCount at %d, %d = %d, want %d", dx, dy, got, want)
+			}
+			if got := a.Overlaps(b, dx, dy); got != (want > 0) {
+				t.Fatalf("Overlaps at %d, %d = %v, want %v", dx, dy, got, want > 0)
+			}
+		}
+	}
+}
+
+func TestFlip(t *testing.T) {
+	m := New(3, 2)
+	m.Set(0, 0, true)
+	m.Set(2, 1, true)
+
+	h := m.Flip(allegro.FLIP_HORIZONTAL)
+	if !h.Get(2, 0) || !h.Get(0, 1) || h.Count() != 2 {
+		t.Error("horizontal flip is wrong")
+	}
+	v := m.Flip(allegro.FLIP_VERTICAL)
+	if !v.Get(0, 1) || !v.Get(2, 0) || v.Count() != 2 {
+		t.Error("vertical flip is wrong")
+	}
+	hv := m.Flip(allegro.FLIP_HORIZONTAL | allegro.FLIP_VERTICAL)
+	if !hv.Get(2, 1) || !hv.Get(0, 0) || hv.Count() != 2 {
+		t.Error("combined flip is wrong")
+	}
+}
+
+func TestSub(t *testing.T) {
+	m := New(100, 4)
+	m.Set(70, 2, true)
+	sub := m.Sub(64, 0, 16, 4)
+	if !sub.Get(6, 2) || sub.Count() != 1 {
+		t.Error("sub-mask is wrong")
+	}
+}
@@ -20,6 +20,7 @@ const (
 	OPENGL                                 = C.ALLEGRO_OPENGL
 	OPENGL_3_0                             = C.ALLEGRO_OPENGL_3_0
 	OPENGL_FORWARD_COMPATIBLE              = C.ALLEGRO_OPENGL_FORWARD_COMPATIBLE
+	PROGRAMMABLE_PIPELINE                  = C.ALLEGRO_PROGRAMMABLE_PIPELINE
 	FRAMELESS                              = C.ALLEGRO_FRAMELESS
 	NOFRAME                                = C.ALLEGRO_NOFRAME
 	GENERATE_EXPOSE_EVENTS                 = C.ALLEGRO_GENERATE_EXPOSE_EVENTS
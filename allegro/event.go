@@ -1,6 +1,14 @@
 package allegro
 
 // #include <allegro5/allegro.h>
+/*
+static ALLEGRO_EVENT_SOURCE *create_user_event_source(void) {
+	ALLEGRO_EVENT_SOURCE *source = al_calloc(1, sizeof(ALLEGRO_EVENT_SOURCE));
+	if (source)
+		al_init_user_event_source(source);
+	return source;
+}
+*/
 import "C"
 import (
 	"errors"
@@ -63,6 +71,23 @@ func (source *EventSource) DestroyUserEventSource() {
 	C.al_destroy_user_event_source((*C.ALLEGRO_EVENT_SOURCE)(source))
 }
 
+// Allocates and initialises an event source for emitting user events. Unlike
+// one declared in Go, it can be registered with event queues and emitted from
+// any goroutine. It must be freed with FreeUserEventSource.
+func CreateUserEventSource() (*EventSource, error) {
+	source := C.create_user_event_source()
+	if source == nil {
+		return nil, errors.New("failed to create user event source")
+	}
+	return (*EventSource)(source), nil
+}
+
+// Destroys an event source made by CreateUserEventSource and frees its memory.
+func (source *EventSource) FreeUserEventSource() {
+	source.DestroyUserEventSource()
+	free(unsafe.Pointer(source))
+}
+
 // Assign the abstract user data to the event source. Allegro does not use the
 // data internally for anything; it is simply meant as a convenient way to
 // associate your own data or objects with events.
@@ -8,6 +8,7 @@ import (
 	"image"
 	"image/color"
 	"image/draw"
+	"unsafe"
 )
 
 const rgbaMAX = 0xFFFF
@@ -35,6 +36,7 @@ type BitmapFlags int
 
 const (
 	ALPHA_TEST             BitmapFlags = C.ALLEGRO_ALPHA_TEST
+	CONVERT_BITMAP                     = C.ALLEGRO_CONVERT_BITMAP
 	FORCE_LOCKING                      = C.ALLEGRO_FORCE_LOCKING
 	KEEP_BITMAP_FORMAT                 = C.ALLEGRO_KEEP_BITMAP_FORMAT
 	KEEP_INDEX                         = C.ALLEGRO_KEEP_INDEX
@@ -98,17 +100,90 @@ const (
 type BlendingValue int
 
 const (
-	ZERO          BlendingValue = C.ALLEGRO_ZERO
-	ONE                         = C.ALLEGRO_ONE
-	ALPHA                       = C.ALLEGRO_ALPHA
-	INVERSE_ALPHA               = C.ALLEGRO_INVERSE_ALPHA
-	// These need at least Allegro 5.0.10
-	/*
-		SRC_COLOR           = C.ALLEGRO_SRC_COLOR
-		DEST_COLOR          = C.ALLEGRO_DEST_COLOR
-		INVERSE_SRC_COLOR   = C.ALLEGRO_INVERSE_SRC_COLOR
-		INVERSE_DEST_COLOR  = C.ALLEGRO_INVERSE_DEST_COLOR
-	*/
+	ZERO                BlendingValue = C.ALLEGRO_ZERO
+	ONE                               = C.ALLEGRO_ONE
+	ALPHA                             = C.ALLEGRO_ALPHA
+	INVERSE_ALPHA                     = C.ALLEGRO_INVERSE_ALPHA
+	SRC_COLOR                         = C.ALLEGRO_SRC_COLOR
+	DEST_COLOR                        = C.ALLEGRO_DEST_COLOR
+	INVERSE_SRC_COLOR                 = C.ALLEGRO_INVERSE_SRC_COLOR
+	INVERSE_DEST_COLOR                = C.ALLEGRO_INVERSE_DEST_COLOR
+	CONST_COLOR                       = C.ALLEGRO_CONST_COLOR
+	INVERSE_CONST_COLOR               = C.ALLEGRO_INVERSE_CONST_COLOR
+)
+
+// BlendMode is a complete blender setting, as passed to
+// SetSeparateBlender.
+type BlendMode struct {
+	Op                 BlendingOperation
+	Src, Dst           BlendingValue
+	AlphaOp            BlendingOperation
+	AlphaSrc, AlphaDst BlendingValue
+}
+
+// Named blend modes. Apart from BlendAlpha, they expect source colors with
+// premultiplied alpha, which is how Allegro loads and creates bitmaps by
+// default.
+var (
+	// BlendAlpha is conventional alpha blending for colors that are not
+	// premultiplied.
+	BlendAlpha = BlendMode{ADD, ALPHA, INVERSE_ALPHA, ADD, ONE, INVERSE_ALPHA}
+
+	// BlendPremultiplied is alpha blending for premultiplied colors, and is
+	// Allegro's default.
+	BlendPremultiplied = BlendMode{ADD, ONE, INVERSE_ALPHA, ADD, ONE, INVERSE_ALPHA}
+
+	// BlendAdditive adds the source to the destination, brightening it.
+	BlendAdditive = BlendMode{ADD, ONE, ONE, ADD, ZERO, ONE}
+
+	// BlendMultiply multiplies the destination by the source, darkening it.
+	BlendMultiply = BlendMode{ADD, DEST_COLOR, INVERSE_ALPHA, ADD, ZERO, ONE}
+
+	// BlendScreen inverts, multiplies and inverts again, brightening the
+	// destination without oversaturating it.
+	BlendScreen = BlendMode{ADD, ONE, INVERSE_SRC_COLOR, ADD, ZERO, ONE}
+
+	// BlendErase removes the destination wherever the source is opaque,
+	// leaving it transparent.
+	BlendErase = BlendMode{ADD, ZERO, INVERSE_ALPHA, ADD, ZERO, INVERSE_ALPHA}
+)
+
+type RenderState int
+
+const (
+	// ALLEGRO_ALPHA_TEST is named RENDER_ALPHA_TEST here, as ALPHA_TEST is
+	// already a bitmap flag.
+	RENDER_ALPHA_TEST RenderState = C.ALLEGRO_ALPHA_TEST
+	WRITE_MASK                    = C.ALLEGRO_WRITE_MASK
+	DEPTH_TEST                    = C.ALLEGRO_DEPTH_TEST
+	DEPTH_FUNCTION                = C.ALLEGRO_DEPTH_FUNCTION
+	ALPHA_FUNCTION                = C.ALLEGRO_ALPHA_FUNCTION
+	ALPHA_TEST_VALUE              = C.ALLEGRO_ALPHA_TEST_VALUE
+)
+
+type RenderFunction int
+
+const (
+	RENDER_NEVER         RenderFunction = C.ALLEGRO_RENDER_NEVER
+	RENDER_ALWAYS                       = C.ALLEGRO_RENDER_ALWAYS
+	RENDER_LESS                         = C.ALLEGRO_RENDER_LESS
+	RENDER_EQUAL                        = C.ALLEGRO_RENDER_EQUAL
+	RENDER_LESS_EQUAL                   = C.ALLEGRO_RENDER_LESS_EQUAL
+	RENDER_GREATER                      = C.ALLEGRO_RENDER_GREATER
+	RENDER_NOT_EQUAL                    = C.ALLEGRO_RENDER_NOT_EQUAL
+	RENDER_GREATER_EQUAL                = C.ALLEGRO_RENDER_GREATER_EQUAL
+)
+
+type WriteMask int
+
+const (
+	MASK_RED   WriteMask = C.ALLEGRO_MASK_RED
+	MASK_GREEN           = C.ALLEGRO_MASK_GREEN
+	MASK_BLUE            = C.ALLEGRO_MASK_BLUE
+	MASK_ALPHA           = C.ALLEGRO_MASK_ALPHA
+	MASK_DEPTH           = C.ALLEGRO_MASK_DEPTH
+	MASK_RGB             = C.ALLEGRO_MASK_RGB
+	MASK_RGBA            = C.ALLEGRO_MASK_RGBA
 )
 
 // Static Methods {{{
@@ -167,6 +242,76 @@ func ClearToColor(c Color) {
 	C.al_clear_to_color(C.ALLEGRO_COLOR(c))
 }
 
+// Clear the depth buffer (confined by the clipping rectangle) to the given
+// value. A depth buffer is only available if it was requested with
+// al_set_new_display_option and the requirement could be met by the
+// al_create_display call creating the current display. Operations involving
+// the depth buffer are also affected by al_set_render_state.
+//
+// For example, if ALLEGRO_DEPTH_FUNCTION is set to ALLEGRO_RENDER_LESS then
+// depth buffer value of 1 represents infinite distance, and thus is a good
+// value to use when clearing the depth buffer.
+//
+// See https://liballeg.org/a5docs/5.2.6/graphics.html#al_clear_depth_buffer
+func ClearDepthBuffer(z float32) {
+	C.al_clear_depth_buffer(C.float(z))
+}
+
+// Set one of several render attributes; see ALLEGRO_RENDER_STATE for
+// details.
+//
+// This function does nothing if the target bitmap is a memory bitmap.
+//
+// See https://liballeg.org/a5docs/5.2.6/graphics.html#al_set_render_state
+func SetRenderState(state RenderState, value int) {
+	C.al_set_render_state(C.ALLEGRO_RENDER_STATE(state), C.int(value))
+}
+
+// SetDepthTest enables or disables depth testing for the target bitmap. The
+// display needs a depth buffer, requested by setting DEPTH_SIZE with
+// SetNewDisplayOption before creating it; bitmaps need one requested with
+// SetNewBitmapDepth.
+func SetDepthTest(enabled bool) {
+	SetRenderState(DEPTH_TEST, boolInt(enabled))
+}
+
+// SetDepthFunction sets the comparison used for depth testing. The default
+// is RENDER_LESS.
+func SetDepthFunction(f RenderFunction) {
+	SetRenderState(DEPTH_FUNCTION, int(f))
+}
+
+// SetWriteMask sets which of the color channels and the depth buffer are
+// written to when drawing. The default is MASK_RGBA | MASK_DEPTH.
+func SetWriteMask(mask WriteMask) {
+	SetRenderState(WRITE_MASK, int(mask))
+}
+
+// SetAlphaTest enables or disables alpha testing, which discards pixels
+// whose alpha fails the comparison set by SetAlphaFunction.
+func SetAlphaTest(enabled bool) {
+	SetRenderState(RENDER_ALPHA_TEST, boolInt(enabled))
+}
+
+// SetAlphaFunction sets the comparison used for alpha testing. The default
+// is RENDER_ALWAYS.
+func SetAlphaFunction(f RenderFunction) {
+	SetRenderState(ALPHA_FUNCTION, int(f))
+}
+
+// SetAlphaTestValue sets the value, from 0 to 255, that pixel alpha is
+// compared against when alpha testing.
+func SetAlphaTestValue(value int) {
+	SetRenderState(ALPHA_TEST_VALUE, value)
+}
+
+func boolInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
 // Loads an image file into a new ALLEGRO_BITMAP. The file type is determined
 // by the extension, except if the file has no extension in which case
 // al_identify_bitmap is used instead.
@@ -340,6 +485,28 @@ func SeparateBlender() (op BlendingOperation, src, dst BlendingValue, alpha_op B
 	return BlendingOperation(cop), BlendingValue(csrc), BlendingValue(cdst), BlendingOperation(calpha_op), BlendingValue(calpha_src), BlendingValue(calpha_dst)
 }
 
+// SetBlendMode sets the blender for the current thread to one of the named
+// blend modes, or any other BlendMode.
+func SetBlendMode(mode BlendMode) {
+	SetSeparateBlender(mode.Op, mode.Src, mode.Dst, mode.AlphaOp, mode.AlphaSrc, mode.AlphaDst)
+}
+
+// CurrentBlendMode returns the active blender for the current thread.
+func CurrentBlendMode() BlendMode {
+	var mode BlendMode
+	mode.Op, mode.Src, mode.Dst, mode.AlphaOp, mode.AlphaSrc, mode.AlphaDst = SeparateBlender()
+	return mode
+}
+
+// WithBlendMode sets the blender for the current thread, calls f, and then
+// restores the previous blender.
+func WithBlendMode(mode BlendMode, f func()) {
+	WithState(STATE_BLENDER, func() {
+		SetBlendMode(mode)
+		f()
+	})
+}
+
 // Sets the color to use for blending when using the ALLEGRO_CONST_COLOR or
 // ALLEGRO_INVERSE_CONST_COLOR blend functions. See al_set_blender for more
 // information.
@@ -1104,6 +1271,16 @@ func (reg *LockedRegion) PixelSize() int {
 	return int((*C.struct_ALLEGRO_LOCKED_REGION)(reg).pixel_size)
 }
 
+// Row returns the pixel data of row y of the locked region, which is width
+// pixels wide. The slice refers directly to the locked memory, so it must not
+// be used after the bitmap is unlocked.
+func (reg *LockedRegion) Row(y, width int) []byte {
+	r := (*C.struct_ALLEGRO_LOCKED_REGION)(reg)
+	n := width * int(r.pixel_size)
+	row := unsafe.Pointer(uintptr(r.data) + uintptr(y*int(r.pitch)))
+	return (*[1 << 30]byte)(row)[:n:n]
+}
+
 // Return the number of bytes that a pixel of the given format occupies. For
 // blocked pixel formats (e.g. compressed formats), this returns 0.
 //
@@ -137,6 +137,19 @@ func SetSeparateBitmapBlender(op BlendingOperation, src, dst BlendingValue, alph
 	)
 }
 
+// SetBitmapBlendMode sets the blender for the target bitmap to one of the
+// named blend modes, or any other BlendMode.
+func SetBitmapBlendMode(mode BlendMode) {
+	SetSeparateBitmapBlender(mode.Op, mode.Src, mode.Dst, mode.AlphaOp, mode.AlphaSrc, mode.AlphaDst)
+}
+
+// CurrentBitmapBlendMode returns the blender used by the target bitmap.
+func CurrentBitmapBlendMode() BlendMode {
+	var mode BlendMode
+	mode.Op, mode.Src, mode.Dst, mode.AlphaOp, mode.AlphaSrc, mode.AlphaDst = SeparateBitmapBlender()
+	return mode
+}
+
 // Resets the blender for this bitmap to the default. After resetting the
 // bitmap blender, the values set for
 // al_set_bitmap_blender/al_set_separate_bitmap_blender will be used instead.
@@ -0,0 +1,88 @@
+package ldtk
+
+import (
+	"github.com/dradtke/go-allegro/allegro"
+	"github.com/dradtke/go-allegro/allegro/primitives"
+)
+
+// Draw draws the level's visible layers with its top-left corner at x, y.
+// Entities aren't drawn, and the background is left to the caller.
+func (l *Level) Draw(x, y float32) {
+	allegro.HoldBitmapDrawing(true)
+	for _, layer := range l.Layers {
+		if layer.Visible {
+			layer.Draw(x, y)
+		}
+	}
+	allegro.HoldBitmapDrawing(false)
+}
+
+// AddTo adds the tiles of the level's visible layers to a sprite batch, with
+// the level's top-left corner at x, y, on consecutive sprite layers starting
+// at layer.
+func (l *Level) AddTo(batch *primitives.SpriteBatch, x, y float32, layer int) {
+	for i, lr := range l.Layers {
+		if lr.Visible {
+			lr.AddTo(batch, x, y, layer+i)
+		}
+	}
+}
+
+// Draw draws the layer's tiles for a level whose top-left corner is at x, y,
+// regardless of whether the layer is visible.
+func (l *Layer) Draw(x, y float32) {
+	draws := make([]allegro.BitmapDraw, 0, len(l.Tiles))
+	l.tiles(x, y, func(bmp *allegro.Bitmap, t *Tile, size, dx, dy float32, tint allegro.Color, flags allegro.DrawFlags) {
+		draws = append(draws, allegro.BitmapDraw{
+			Bitmap: bmp,
+			SX:     float32(t.SrcX), SY: float32(t.SrcY), SW: size, SH: size,
+			Tint: tint,
+			DX:   dx, DY: dy,
+			Flags: flags,
+		})
+	})
+	allegro.DrawBitmaps(draws)
+}
+
+// AddTo adds the layer's tiles to a sprite batch on the given sprite layer,
+// for a level whose top-left corner is at x, y.
+func (l *Layer) AddTo(batch *primitives.SpriteBatch, x, y float32, layer int) {
+	l.tiles(x, y, func(bmp *allegro.Bitmap, t *Tile, size, dx, dy float32, tint allegro.Color, flags allegro.DrawFlags) {
+		batch.Add(primitives.Sprite{
+			Bitmap: bmp,
+			SX:     float32(t.SrcX), SY: float32(t.SrcY), SW: size, SH: size,
+			Tint: tint,
+			DX:   dx, DY: dy,
+			Flags: flags,
+			Layer: layer,
+		})
+	})
+}
+
+// tiles calls f with how to draw each of the layer's tiles.
+func (l *Layer) tiles(x, y float32, f func(bmp *allegro.Bitmap, t *Tile, size, dx, dy float32, tint allegro.Color, flags allegro.DrawFlags)) {
+	ts := l.Tileset
+	if ts == nil || ts.Bitmap == nil || len(l.Tiles) == 0 {
+		return
+	}
+	size := float32(ts.GridSize)
+	x += float32(l.OffsetX)
+	y += float32(l.OffsetY)
+	alpha := float32(-1)
+	var tint allegro.Color
+	for i := range l.Tiles {
+		t := &l.Tiles[i]
+		if a := l.Opacity * t.Alpha; a != alpha {
+			alpha = a
+			tint = allegro.MapRGBAf(a, a, a, a)
+		}
+		var flags allegro.DrawFlags
+		if t.FlipX {
+			flags |= allegro.FLIP_HORIZONTAL
+		}
+		if t.FlipY {
+			flags |= allegro.FLIP_VERTICAL
+		}
+		f(ts.Bitmap, t, size, x+float32(t.X), y+float32(t.Y), tint, flags)
+	}
+}
@@ -0,0 +1,122 @@
+package ldtk
+
+import (
+	"encoding/json"
+	"image/color"
+)
+
+// Field is a custom field of a level or entity.
+type Field struct {
+	Identifier string
+
+	// Type is LDtk's name for the field's type, such as "Int", "String",
+	// "LocalEnum.Item" or "Array<Point>".
+	Type string
+
+	// Value is the field's value as JSON, which is null if it isn't set.
+	Value json.RawMessage
+}
+
+// Fields is a list of custom fields.
+type Fields []Field
+
+// Point is the value of a point field, in grid cells.
+type Point struct {
+	X int `json:"cx"`
+	Y int `json:"cy"`
+}
+
+// EntityRef is the value of an entity reference field. The entity can be
+// found with Project.Entity.
+type EntityRef struct {
+	EntityIID string `json:"entityIid"`
+	LayerIID  string `json:"layerIid"`
+	LevelIID  string `json:"levelIid"`
+	WorldIID  string `json:"worldIid"`
+}
+
+// Get returns the field with an identifier.
+func (f Fields) Get(identifier string) (Field, bool) {
+	for _, field := range f {
+		if field.Identifier == identifier {
+			return field, true
+		}
+	}
+	return Field{}, false
+}
+
+// Decode decodes the value of a field into v, as by json.Unmarshal. It
+// reports whether the field exists, is set, and could be decoded. Array
+// fields decode into slices, and enum fields into strings.
+func (f Fields) Decode(identifier string, v interface{}) bool {
+	field, ok := f.Get(identifier)
+	if !ok || len(field.Value) == 0 || string(field.Value) == "null" {
+		return false
+	}
+	return json.Unmarshal(field.Value, v) == nil
+}
+
+// Int returns the value of an integer field, or def if it is missing or
+// unset.
+func (f Fields) Int(identifier string, def int) int {
+	var v float64
+	if f.Decode(identifier, &v) {
+		return int(v)
+	}
+	return def
+}
+
+// Float returns the value of a number field, or def if it is missing or
+// unset.
+func (f Fields) Float(identifier string, def float64) float64 {
+	var v float64
+	if f.Decode(identifier, &v) {
+		return v
+	}
+	return def
+}
+
+// String returns the value of a string, enum or file path field, or def if
+// it is missing or unset.
+func (f Fields) String(identifier, def string) string {
+	var v string
+	if f.Decode(identifier, &v) {
+		return v
+	}
+	return def
+}
+
+// Bool returns the value of a boolean field, or def if it is missing.
+func (f Fields) Bool(identifier string, def bool) bool {
+	var v bool
+	if f.Decode(identifier, &v) {
+		return v
+	}
+	return def
+}
+
+// Color returns the value of a color field, or def if it is missing or
+// unset.
+func (f Fields) Color(identifier string, def color.NRGBA) color.NRGBA {
+	var s string
+	if f.Decode(identifier, &s) {
+		if c, err := parseColor(s); err == nil {
+			return c
+		}
+	}
+	return def
+}
+
+// Point returns the value of a point field.
+func (f Fields) Point(identifier string) (Point, bool) {
+	var p Point
+	ok := f.Decode(identifier, &p)
+	return p, ok
+}
+
+// EntityRef returns the value of an entity reference field.
+func (f Fields) EntityRef(identifier string) (EntityRef, bool) {
+	var r EntityRef
+	ok := f.Decode(identifier, &r)
+	return r, ok
+}
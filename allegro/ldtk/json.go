@@ -0,0 +1,421 @@
+package ldtk
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image/color"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+type jsonProject struct {
+	JSONVersion     string      `json:"jsonVersion"`
+	IID             string      `json:"iid"`
+	BgColor         string      `json:"bgColor"`
+	DefaultGridSize int         `json:"defaultGridSize"`
+	WorldLayout     string      `json:"worldLayout"`
+	WorldGridWidth  int         `json:"worldGridWidth"`
+	WorldGridHeight int         `json:"worldGridHeight"`
+	Defs            jsonDefs    `json:"defs"`
+	Levels          []jsonLevel `json:"levels"`
+	Worlds          []jsonWorld `json:"worlds"`
+}
+
+type jsonDefs struct {
+	Layers   []jsonLayerDef   `json:"layers"`
+	Entities []jsonEntityDef  `json:"entities"`
+	Tilesets []jsonTilesetDef `json:"tilesets"`
+}
+
+type jsonLayerDef struct {
+	UID           int    `json:"uid"`
+	Identifier    string `json:"identifier"`
+	Type          string `json:"__type"`
+	GridSize      int    `json:"gridSize"`
+	IntGridValues []struct {
+		Value      int    `json:"value"`
+		Identifier string `json:"identifier"`
+		Color      string `json:"color"`
+	} `json:"intGridValues"`
+}
+
+type jsonEntityDef struct {
+	UID        int      `json:"uid"`
+	Identifier string   `json:"identifier"`
+	Width      int      `json:"width"`
+	Height     int      `json:"height"`
+	PivotX     float32  `json:"pivotX"`
+	PivotY     float32  `json:"pivotY"`
+	Color      string   `json:"color"`
+	Tags       []string `json:"tags"`
+}
+
+type jsonTilesetDef struct {
+	UID          int      `json:"uid"`
+	Identifier   string   `json:"identifier"`
+	RelPath      string   `json:"relPath"`
+	PxWid        int      `json:"pxWid"`
+	PxHei        int      `json:"pxHei"`
+	TileGridSize int      `json:"tileGridSize"`
+	Spacing      int      `json:"spacing"`
+	Padding      int      `json:"padding"`
+	Tags         []string `json:"tags"`
+	CustomData   []struct {
+		TileID int    `json:"tileId"`
+		Data   string `json:"data"`
+	} `json:"customData"`
+	EnumTags []struct {
+		EnumValueID string `json:"enumValueId"`
+		TileIDs     []int  `json:"tileIds"`
+	} `json:"enumTags"`
+}
+
+type jsonWorld struct {
+	Identifier      string      `json:"identifier"`
+	IID             string      `json:"iid"`
+	WorldLayout     string      `json:"worldLayout"`
+	WorldGridWidth  int         `json:"worldGridWidth"`
+	WorldGridHeight int         `json:"worldGridHeight"`
+	Levels          []jsonLevel `json:"levels"`
+}
+
+type jsonLevel struct {
+	Identifier      string      `json:"identifier"`
+	IID             string      `json:"iid"`
+	UID             int         `json:"uid"`
+	WorldX          int         `json:"worldX"`
+	WorldY          int         `json:"worldY"`
+	WorldDepth      int         `json:"worldDepth"`
+	PxWid           int         `json:"pxWid"`
+	PxHei           int         `json:"pxHei"`
+	BgColor         string      `json:"__bgColor"`
+	BgRelPath       string      `json:"bgRelPath"`
+	ExternalRelPath string      `json:"externalRelPath"`
+	FieldInstances  []jsonField `json:"fieldInstances"`
+	LayerInstances  []jsonLayer `json:"layerInstances"`
+	Neighbours      []struct {
+		LevelIID string `json:"levelIid"`
+		Dir      string `json:"dir"`
+	} `json:"__neighbours"`
+}
+
+type jsonLayer struct {
+	Identifier      string       `json:"__identifier"`
+	Type            string       `json:"__type"`
+	CWid            int          `json:"__cWid"`
+	CHei            int          `json:"__cHei"`
+	GridSize        int          `json:"__gridSize"`
+	Opacity         float32      `json:"__opacity"`
+	PxTotalOffsetX  int          `json:"__pxTotalOffsetX"`
+	PxTotalOffsetY  int          `json:"__pxTotalOffsetY"`
+	TilesetDefUID   *int         `json:"__tilesetDefUid"`
+	IID             string       `json:"iid"`
+	LayerDefUID     int          `json:"layerDefUid"`
+	Visible         bool         `json:"visible"`
+	IntGridCSV      []int        `json:"intGridCsv"`
+	AutoLayerTiles  []jsonTile   `json:"autoLayerTiles"`
+	GridTiles       []jsonTile   `json:"gridTiles"`
+	EntityInstances []jsonEntity `json:"entityInstances"`
+}
+
+type jsonTile struct {
+	Px  [2]int   `json:"px"`
+	Src [2]int   `json:"src"`
+	F   int      `json:"f"`
+	T   int      `json:"t"`
+	A   *float32 `json:"a"`
+}
+
+type jsonEntity struct {
+	Identifier     string        `json:"__identifier"`
+	Grid           [2]int        `json:"__grid"`
+	Pivot          [2]float32    `json:"__pivot"`
+	Tags           []string      `json:"__tags"`
+	Tile           *jsonTileRect `json:"__tile"`
+	SmartColor     string        `json:"__smartColor"`
+	WorldX         *int          `json:"__worldX"`
+	WorldY         *int          `json:"__worldY"`
+	IID            string        `json:"iid"`
+	Width          int           `json:"width"`
+	Height         int           `json:"height"`
+	DefUID         int           `json:"defUid"`
+	Px             [2]int        `json:"px"`
+	FieldInstances []jsonField   `json:"fieldInstances"`
+}
+
+type jsonTileRect struct {
+	TilesetUID int `json:"tilesetUid"`
+	X          int `json:"x"`
+	Y          int `json:"y"`
+	W          int `json:"w"`
+	H          int `json:"h"`
+}
+
+type jsonField struct {
+	Identifier string          `json:"__identifier"`
+	Type       string          `json:"__type"`
+	Value      json.RawMessage `json:"__value"`
+}
+
+// decoder converts parsed JSON to a project, keeping the first error.
+type decoder struct {
+	p   *Project
+	err error
+}
+
+func (d *decoder) fail(err error) {
+	if d.err == nil {
+		d.err = err
+	}
+}
+
+func (d *decoder) color(s string) color.NRGBA {
+	c, err := parseColor(s)
+	if err != nil {
+		d.fail(err)
+	}
+	return c
+}
+
+// path resolves a path relative to the project file.
+func (d *decoder) path(path string) string {
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(d.p.dir, filepath.FromSlash(path))
+}
+
+func parseProject(data []byte, dir string) (*Project, error) {
+	var j jsonProject
+	if err := json.Unmarshal(data, &j); err != nil {
+		return nil, err
+	}
+	p := &Project{
+		JSONVersion:     j.JSONVersion,
+		DefaultGridSize: j.DefaultGridSize,
+		dir:             dir,
+	}
+	d := &decoder{p: p}
+	p.BgColor = d.color(j.BgColor)
+	for _, t := range j.Defs.Tilesets {
+		ts := &Tileset{
+			UID:        t.UID,
+			Identifier: t.Identifier,
+			Path:       d.path(t.RelPath),
+			Width:      t.PxWid,
+			Height:     t.PxHei,
+			GridSize:   t.TileGridSize,
+			Spacing:    t.Spacing,
+			Padding:    t.Padding,
+			Tags:       t.Tags,
+			CustomData: make(map[int]string),
+			EnumTags:   make(map[string][]int),
+		}
+		for _, c := range t.CustomData {
+			ts.CustomData[c.TileID] = c.Data
+		}
+		for _, e := range t.EnumTags {
+			ts.EnumTags[e.EnumValueID] = e.TileIDs
+		}
+		p.Tilesets = append(p.Tilesets, ts)
+	}
+	for _, l := range j.Defs.Layers {
+		def := &LayerDef{UID: l.UID, Identifier: l.Identifier, Type: d.layerType(l.Type), GridSize: l.GridSize}
+		for _, v := range l.IntGridValues {
+			def.IntGridValues = append(def.IntGridValues, IntGridValue{Value: v.Value, Identifier: v.Identifier, Color: d.color(v.Color)})
+		}
+		p.Layers = append(p.Layers, def)
+	}
+	for _, e := range j.Defs.Entities {
+		p.Entities = append(p.Entities, &EntityDef{
+			UID:        e.UID,
+			Identifier: e.Identifier,
+			Width:      e.Width,
+			Height:     e.Height,
+			PivotX:     e.PivotX,
+			PivotY:     e.PivotY,
+			Color:      d.color(e.Color),
+			Tags:       e.Tags,
+		})
+	}
+
+	worlds := j.Worlds
+	if len(worlds) == 0 {
+		worlds = []jsonWorld{{
+			Identifier:      "World",
+			IID:             j.IID,
+			WorldLayout:     j.WorldLayout,
+			WorldGridWidth:  j.WorldGridWidth,
+			WorldGridHeight: j.WorldGridHeight,
+			Levels:          j.Levels,
+		}}
+	}
+	for i := range worlds {
+		jw := &worlds[i]
+		w := &World{
+			Identifier: jw.Identifier,
+			IID:        jw.IID,
+			Layout:     jw.WorldLayout,
+			GridWidth:  jw.WorldGridWidth,
+			GridHeight: jw.WorldGridHeight,
+		}
+		for k := range jw.Levels {
+			w.Levels = append(w.Levels, d.level(&jw.Levels[k]))
+		}
+		p.Worlds = append(p.Worlds, w)
+	}
+	if d.err != nil {
+		return nil, d.err
+	}
+	return p, nil
+}
+
+func (d *decoder) layerType(s string) LayerType {
+	switch s {
+	case "IntGrid":
+		return IntGridLayer
+	case "Entities":
+		return EntityLayer
+	case "Tiles":
+		return TileLayer
+	case "AutoLayer":
+		return AutoLayer
+	}
+	d.fail(fmt.Errorf("unknown layer type %q", s))
+	return 0
+}
+
+func (d *decoder) level(j *jsonLevel) *Level {
+	l := &Level{
+		Identifier: j.Identifier,
+		IID:        j.IID,
+		UID:        j.UID,
+		WorldX:     j.WorldX,
+		WorldY:     j.WorldY,
+		WorldDepth: j.WorldDepth,
+		Width:      j.PxWid,
+		Height:     j.PxHei,
+		BgColor:    d.color(j.BgColor),
+		BgImage:    d.path(j.BgRelPath),
+		Fields:     fields(j.FieldInstances),
+		External:   d.path(j.ExternalRelPath),
+	}
+	for _, n := range j.Neighbours {
+		l.Neighbours = append(l.Neighbours, Neighbour{LevelIID: n.LevelIID, Dir: n.Dir})
+	}
+	if j.LayerInstances == nil {
+		return l
+	}
+	// LDtk lists layers from top to bottom.
+	l.Layers = make([]*Layer, 0, len(j.LayerInstances))
+	for i := len(j.LayerInstances) - 1; i >= 0; i-- {
+		l.Layers = append(l.Layers, d.layer(l, &j.LayerInstances[i]))
+	}
+	return l
+}
+
+func (d *decoder) layer(level *Level, j *jsonLayer) *Layer {
+	l := &Layer{
+		Identifier: j.Identifier,
+		IID:        j.IID,
+		Type:       d.layerType(j.Type),
+		Width:      j.CWid,
+		Height:     j.CHei,
+		GridSize:   j.GridSize,
+		Visible:    j.Visible,
+		Opacity:    j.Opacity,
+		OffsetX:    j.PxTotalOffsetX,
+		OffsetY:    j.PxTotalOffsetY,
+		IntGrid:    j.IntGridCSV,
+	}
+	for _, def := range d.p.Layers {
+		if def.UID == j.LayerDefUID {
+			l.Def = def
+		}
+	}
+	if j.TilesetDefUID != nil {
+		l.Tileset = d.p.Tileset(*j.TilesetDefUID)
+	}
+	if l.IntGrid != nil && len(l.IntGrid) != l.Width*l.Height {
+		d.fail(fmt.Errorf("layer %q has %d IntGrid values, want %d", l.Identifier, len(l.IntGrid), l.Width*l.Height))
+		l.IntGrid = nil
+	}
+	tiles := j.GridTiles
+	if l.Type != TileLayer {
+		tiles = j.AutoLayerTiles
+	}
+	for _, t := range tiles {
+		tile := Tile{
+			X: t.Px[0], Y: t.Px[1],
+			SrcX: t.Src[0], SrcY: t.Src[1],
+			ID:    t.T,
+			FlipX: t.F&1 != 0,
+			FlipY: t.F&2 != 0,
+			Alpha: 1,
+		}
+		if t.A != nil {
+			tile.Alpha = *t.A
+		}
+		l.Tiles = append(l.Tiles, tile)
+	}
+	for i := range j.EntityInstances {
+		l.Entities = append(l.Entities, d.entity(level, l, &j.EntityInstances[i]))
+	}
+	return l
+}
+
+func (d *decoder) entity(level *Level, layer *Layer, j *jsonEntity) *Entity {
+	e := &Entity{
+		Identifier: j.Identifier,
+		IID:        j.IID,
+		Tags:       j.Tags,
+		X:          j.Px[0],
+		Y:          j.Px[1],
+		GridX:      j.Grid[0],
+		GridY:      j.Grid[1],
+		WorldX:     level.WorldX + layer.OffsetX + j.Px[0],
+		WorldY:     level.WorldY + layer.OffsetY + j.Px[1],
+		Width:      j.Width,
+		Height:     j.Height,
+		PivotX:     j.Pivot[0],
+		PivotY:     j.Pivot[1],
+		SmartColor: d.color(j.SmartColor),
+		Fields:     fields(j.FieldInstances),
+	}
+	if j.WorldX != nil && j.WorldY != nil {
+		e.WorldX, e.WorldY = *j.WorldX, *j.WorldY
+	}
+	for _, def := range d.p.Entities {
+		if def.UID == j.DefUID {
+			e.Def = def
+		}
+	}
+	if t := j.Tile; t != nil {
+		e.Tile = &TileRect{Tileset: d.p.Tileset(t.TilesetUID), X: t.X, Y: t.Y, W: t.W, H: t.H}
+	}
+	return e
+}
+
+func fields(js []jsonField) Fields {
+	var f Fields
+	for _, j := range js {
+		f = append(f, Field{Identifier: j.Identifier, Type: j.Type, Value: j.Value})
+	}
+	return f
+}
+
+// parseColor parses a color in LDtk's #RRGGBB form. An empty string is
+// transparent.
+func parseColor(s string) (color.NRGBA, error) {
+	if s == "" {
+		return color.NRGBA{}, nil
+	}
+	if len(s) == 7 && strings.HasPrefix(s, "#") {
+		if v, err := strconv.ParseUint(s[1:], 16, 32); err == nil {
+			return color.NRGBA{uint8(v >> 16), uint8(v >> 8), uint8(v), 0xFF}, nil
+		}
+	}
+	return color.NRGBA{}, errors.New("invalid color " + s)
+}
@@ -0,0 +1,392 @@
+// Package ldtk loads projects made with the LDtk level editor and draws their
+// levels.
+//
+//	p, err := ldtk.Load("world.ldtk")
+//	if err != nil {
+//		...
+//	}
+//	if err := p.LoadImages(); err != nil {
+//		...
+//	}
+//	defer p.Destroy()
+//	level := p.Level("Level_0")
+//	for _, e := range level.Entities("Enemy") {
+//		spawnEnemy(e.X, e.Y, e.Fields.Int("health", 10))
+//	}
+//	...
+//	level.Draw(float32(level.WorldX), float32(level.WorldY))
+//
+// Projects saved with "separate level files" leave each level's layers in its
+// own file, which is read by Project.LoadLevel.
+package ldtk
+
+import (
+	"encoding/json"
+	"errors"
+	"image/color"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/dradtke/go-allegro/allegro"
+)
+
+// Project is an LDtk project.
+type Project struct {
+	JSONVersion     string
+	BgColor         color.NRGBA
+	DefaultGridSize int
+
+	// Worlds holds the project's worlds. Projects made before LDtk had
+	// multiple worlds have a single one.
+	Worlds []*World
+
+	Tilesets []*Tileset
+	Layers   []*LayerDef
+	Entities []*EntityDef
+
+	dir     string
+	bitmaps []*allegro.Bitmap
+}
+
+// World is a set of levels laid out together.
+type World struct {
+	Identifier string
+	IID        string
+
+	// Layout is "Free", "GridVania", "LinearHorizontal" or
+	// "LinearVertical". GridWidth and GridHeight are the size of a
+	// GridVania cell in pixels.
+	Layout                string
+	GridWidth, GridHeight int
+
+	Levels []*Level
+}
+
+// Level is a level of a world.
+type Level struct {
+	Identifier string
+	IID        string
+	UID        int
+
+	// WorldX and WorldY are the level's position in its world, and Width
+	// and Height its size, in pixels.
+	WorldX, WorldY, WorldDepth int
+	Width, Height              int
+
+	BgColor color.NRGBA
+
+	// BgImage is the path of the level's background image, if any.
+	BgImage string
+
+	Fields     Fields
+	Neighbours []Neighbour
+
+	// Layers are the level's layers, from bottom to top. It is nil for a
+	// level in a separate file that hasn't been loaded.
+	Layers []*Layer
+
+	// External is the path of the level's separate file, if it has one.
+	External string
+}
+
+// Neighbour is a level next to another.
+type Neighbour struct {
+	LevelIID string
+
+	// Dir is "n", "s", "e" or "w", "ne", "nw", "se" or "sw" for corners,
+	// "<" or ">" for a level below or above in depth, or "o" for an
+	// overlapping level.
+	Dir string
+}
+
+// Layer returns the level's layer with an identifier, or nil.
+func (l *Level) Layer(identifier string) *Layer {
+	for _, layer := range l.Layers {
+		if layer.Identifier == identifier {
+			return layer
+		}
+	}
+	return nil
+}
+
+// Entities returns the level's entities with an identifier, from all of its
+// entity layers, or all of its entities if the identifier is empty.
+func (l *Level) Entities(identifier string) []*Entity {
+	var entities []*Entity
+	for _, layer := range l.Layers {
+		for _, e := range layer.Entities {
+			if identifier == "" || e.Identifier == identifier {
+				entities = append(entities, e)
+			}
+		}
+	}
+	return entities
+}
+
+// LayerType is the kind of a layer.
+type LayerType int
+
+const (
+	IntGridLayer LayerType = iota
+	EntityLayer
+	TileLayer
+	AutoLayer
+)
+
+// Layer is a layer of a level.
+type Layer struct {
+	Identifier string
+	IID        string
+	Type       LayerType
+	Def        *LayerDef
+
+	// Width and Height are the size of the layer in cells of GridSize
+	// pixels.
+	Width, Height int
+	GridSize      int
+
+	Visible bool
+	Opacity float32
+
+	// OffsetX and OffsetY are the layer's total offset within its level,
+	// in pixels.
+	OffsetX, OffsetY int
+
+	// Tileset is the tileset that the layer's tiles come from, if any.
+	Tileset *Tileset
+
+	// IntGrid holds the values of an IntGrid layer, row by row, where zero
+	// is an empty cell.
+	IntGrid []int
+
+	// Tiles holds the tiles of a tile layer, or those placed by the rules of
+	// an auto-layer or IntGrid layer, in drawing order.
+	Tiles []Tile
+
+	Entities []*Entity
+}
+
+// IntAt returns the IntGrid value at column x, row y, or zero if that is
+// outside of the layer.
+func (l *Layer) IntAt(x, y int) int {
+	if x < 0 || y < 0 || x >= l.Width || y >= l.Height || l.IntGrid == nil {
+		return 0
+	}
+	return l.IntGrid[y*l.Width+x]
+}
+
+// Tile is a tile placed in a layer.
+type Tile struct {
+	// X and Y are the tile's position in the layer, and SrcX and SrcY its
+	// position in the tileset, in pixels.
+	X, Y       int
+	SrcX, SrcY int
+
+	// ID is the tile's ID within its tileset.
+	ID int
+
+	FlipX, FlipY bool
+
+	// Alpha is the tile's opacity, from 0 to 1.
+	Alpha float32
+}
+
+// Entity is an instance of an entity in a level.
+type Entity struct {
+	Identifier string
+	IID        string
+	Def        *EntityDef
+	Tags       []string
+
+	// X and Y are the position of the entity's pivot in its level, and
+	// GridX and GridY the cell that it is in. WorldX and WorldY are its
+	// position in the world.
+	X, Y           int
+	GridX, GridY   int
+	WorldX, WorldY int
+
+	// Width and Height are the entity's size in pixels, and PivotX and
+	// PivotY are where in that box its position is, from 0 to 1.
+	Width, Height  int
+	PivotX, PivotY float32
+
+	// Tile is the tile that represents the entity, if any.
+	Tile *TileRect
+
+	SmartColor color.NRGBA
+	Fields     Fields
+}
+
+// TileRect is a region of a tileset.
+type TileRect struct {
+	Tileset    *Tileset
+	X, Y, W, H int
+}
+
+// Tileset is an image that tiles are cut from.
+type Tileset struct {
+	UID        int
+	Identifier string
+
+	// Path is the path of the tileset's image, relative to the current
+	// directory. It is empty for LDtk's built-in tilesets, which aren't
+	// loaded.
+	Path          string
+	Width, Height int
+
+	GridSize         int
+	Spacing, Padding int
+
+	Tags []string
+
+	// CustomData holds the custom data of tiles, by tile ID, and EnumTags
+	// the tile IDs tagged with each value of the tileset's enum.
+	CustomData map[int]string
+	EnumTags   map[string][]int
+
+	// Bitmap is the loaded image, set by Project.LoadImages.
+	Bitmap *allegro.Bitmap
+}
+
+// LayerDef is the definition of a layer.
+type LayerDef struct {
+	UID        int
+	Identifier string
+	Type       LayerType
+	GridSize   int
+
+	// IntGridValues describes the values of an IntGrid layer.
+	IntGridValues []IntGridValue
+}
+
+// IntGridValue is one of the values of an IntGrid layer.
+type IntGridValue struct {
+	Value      int
+	Identifier string
+	Color      color.NRGBA
+}
+
+// EntityDef is the definition of an entity.
+type EntityDef struct {
+	UID            int
+	Identifier     string
+	Width, Height  int
+	PivotX, PivotY float32
+	Color          color.NRGBA
+	Tags           []string
+}
+
+// Load loads a project from an .ldtk file.
+func Load(filename string) (*Project, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	p, err := parseProject(data, filepath.Dir(filename))
+	if err != nil {
+		return nil, errors.New(filename + ": " + err.Error())
+	}
+	return p, nil
+}
+
+// Read reads a project. Files that it refers to, such as tilesets and
+// separate levels, are relative to dir.
+func Read(r io.Reader, dir string) (*Project, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return parseProject(data, dir)
+}
+
+// LoadLevel reads the layers and fields of a level that is saved in its own
+// file. It does nothing if they are already loaded.
+func (p *Project) LoadLevel(l *Level) error {
+	if l.Layers != nil || l.External == "" {
+		return nil
+	}
+	data, err := ioutil.ReadFile(l.External)
+	if err != nil {
+		return err
+	}
+	var j jsonLevel
+	if err := json.Unmarshal(data, &j); err != nil {
+		return errors.New(l.External + ": " + err.Error())
+	}
+	d := &decoder{p: p}
+	loaded := d.level(&j)
+	if d.err != nil {
+		return errors.New(l.External + ": " + d.err.Error())
+	}
+	loaded.External = l.External
+	*l = *loaded
+	return nil
+}
+
+// Level returns the first level with an identifier in any world, or nil.
+func (p *Project) Level(identifier string) *Level {
+	for _, w := range p.Worlds {
+		for _, l := range w.Levels {
+			if l.Identifier == identifier {
+				return l
+			}
+		}
+	}
+	return nil
+}
+
+// Entity returns the entity with an IID, such as the target of an entity
+// reference field, among the loaded levels, or nil.
+func (p *Project) Entity(iid string) *Entity {
+	for _, w := range p.Worlds {
+		for _, l := range w.Levels {
+			for _, layer := range l.Layers {
+				for _, e := range layer.Entities {
+					if e.IID == iid {
+						return e
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// Tileset returns the tileset with a UID, or nil.
+func (p *Project) Tileset(uid int) *Tileset {
+	for _, ts := range p.Tilesets {
+		if ts.UID == uid {
+			return ts
+		}
+	}
+	return nil
+}
+
+// LoadImages loads the bitmaps of the project's tilesets. Tilesets that are
+// already loaded are skipped.
+func (p *Project) LoadImages() error {
+	for _, ts := range p.Tilesets {
+		if ts.Bitmap != nil || ts.Path == "" {
+			continue
+		}
+		bmp, err := allegro.LoadBitmap(ts.Path)
+		if err != nil {
+			return err
+		}
+		ts.Bitmap = bmp
+		p.bitmaps = append(p.bitmaps, bmp)
+	}
+	return nil
+}
+
+// Destroy frees the bitmaps loaded by LoadImages.
+func (p *Project) Destroy() {
+	for _, bmp := range p.bitmaps {
+		bmp.Destroy()
+	}
+	p.bitmaps = nil
+	for _, ts := range p.Tilesets {
+		ts.Bitmap = nil
+	}
+}
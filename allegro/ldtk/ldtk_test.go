@@ -0,0 +1,162 @@
+package ldtk
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const project = `{
+	"jsonVersion": "1.5.3", "iid": "p1", "bgColor": "#40465B", "defaultGridSize": 16,
+	"worldLayout": "GridVania", "worldGridWidth": 256, "worldGridHeight": 256,
+	"defs": {
+		"layers": [
+			{"uid": 1, "identifier": "Collisions", "__type": "IntGrid", "gridSize": 16,
+			 "intGridValues": [{"value": 1, "identifier": "wall", "color": "#FF0000"}]},
+			{"uid": 2, "identifier": "Entities", "__type": "Entities", "gridSize": 16}
+		],
+		"entities": [{"uid": 10, "identifier": "Player", "width": 16, "height": 24, "pivotX": 0.5, "pivotY": 1, "color": "#00FF00", "tags": ["actor"]}],
+		"tilesets": [
+			{"uid": 20, "identifier": "Cavern", "relPath": "tiles/cavern.png", "pxWid": 64, "pxHei": 32, "tileGridSize": 16,
+			 "customData": [{"tileId": 3, "data": "lava"}], "enumTags": [{"enumValueId": "Hurts", "tileIds": [3, 4]}]},
+			{"uid": 21, "identifier": "Internal_Icons", "relPath": null, "pxWid": 1024, "pxHei": 1024, "tileGridSize": 16}
+		]
+	},
+	"levels": [
+		{"identifier": "Level_0", "iid": "l0", "uid": 0, "worldX": 256, "worldY": 0, "pxWid": 48, "pxHei": 32,
+		 "__bgColor": "#101010", "externalRelPath": null,
+		 "__neighbours": [{"levelIid": "l1", "dir": "e"}],
+		 "fieldInstances": [{"__identifier": "music", "__type": "String", "__value": "cave.ogg"}],
+		 "layerInstances": [
+			{"__identifier": "Entities", "__type": "Entities", "__cWid": 3, "__cHei": 2, "__gridSize": 16,
+			 "__opacity": 1, "__pxTotalOffsetX": 0, "__pxTotalOffsetY": 0, "__tilesetDefUid": null,
+			 "iid": "e", "layerDefUid": 2, "visible": true,
+			 "entityInstances": [
+				{"__identifier": "Player", "__grid": [1, 1], "__pivot": [0.5, 1], "__tags": ["actor"],
+				 "__tile": {"tilesetUid": 20, "x": 16, "y": 0, "w": 16, "h": 16},
+				 "__smartColor": "#00FF00", "__worldX": 280, "__worldY": 32,
+				 "iid": "player", "width": 16, "height": 24, "defUid": 10, "px": [24, 32],
+				 "fieldInstances": [
+					{"__identifier": "health", "__type": "Int", "__value": 12},
+					{"__identifier": "speed", "__type": "Float", "__value": 1.5},
+					{"__identifier": "friendly", "__type": "Bool", "__value": true},
+					{"__identifier": "tint", "__type": "Color", "__value": "#0000FF"},
+					{"__identifier": "home", "__type": "Point", "__value": {"cx": 2, "cy": 0}},
+					{"__identifier": "target", "__type": "EntityRef", "__value": {"entityIid": "player", "layerIid": "e", "levelIid": "l0", "worldIid": "p1"}},
+					{"__identifier": "items", "__type": "Array<LocalEnum.Item>", "__value": ["Key", "Sword"]},
+					{"__identifier": "unset", "__type": "Int", "__value": null}
+				 ]}
+			 ]},
+			{"__identifier": "Collisions", "__type": "IntGrid", "__cWid": 3, "__cHei": 2, "__gridSize": 16,
+			 "__opacity": 0.5, "__pxTotalOffsetX": 4, "__pxTotalOffsetY": 0, "__tilesetDefUid": 20,
+			 "iid": "c", "layerDefUid": 1, "visible": true,
+			 "intGridCsv": [0, 0, 0, 1, 1, 0],
+			 "autoLayerTiles": [{"px": [0, 16], "src": [32, 0], "f": 1, "t": 2}, {"px": [16, 16], "src": [48, 0], "f": 2, "t": 3, "a": 0.5}],
+			 "gridTiles": []}
+		 ]},
+		{"identifier": "Level_1", "iid": "l1", "uid": 1, "worldX": 512, "worldY": 0, "pxWid": 16, "pxHei": 16,
+		 "externalRelPath": "world/Level_1.ldtkl", "layerInstances": null, "fieldInstances": []}
+	],
+	"worlds": []
+}`
+
+const external = `{
+	"identifier": "Level_1", "iid": "l1", "uid": 1, "worldX": 512, "worldY": 0, "pxWid": 16, "pxHei": 16,
+	"__bgColor": "#202020", "fieldInstances": [],
+	"layerInstances": [
+		{"__identifier": "Entities", "__type": "Entities", "__cWid": 1, "__cHei": 1, "__gridSize": 16,
+		 "__opacity": 1, "__pxTotalOffsetX": 0, "__pxTotalOffsetY": 0, "iid": "e1", "layerDefUid": 2, "visible": true,
+		 "entityInstances": [{"__identifier": "Player", "__grid": [0, 0], "__pivot": [0.5, 1],
+		   "iid": "p2", "width": 16, "height": 24, "defUid": 10, "px": [8, 16], "fieldInstances": []}]}
+	]
+}`
+
+func TestProject(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ldtk")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := os.Mkdir(filepath.Join(dir, "world"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, data := range map[string]string{"world.ldtk": project, "world/Level_1.ldtkl": external} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	p, err := Load(filepath.Join(dir, "world.ldtk"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(p.Worlds) != 1 || p.Worlds[0].Layout != "GridVania" || len(p.Worlds[0].Levels) != 2 {
+		t.Fatalf("worlds are %+v", p.Worlds)
+	}
+	cavern := p.Tileset(20)
+	if cavern.Path != filepath.Join(dir, "tiles", "cavern.png") || cavern.CustomData[3] != "lava" || len(cavern.EnumTags["Hurts"]) != 2 {
+		t.Errorf("tileset is %+v", cavern)
+	}
+	if icons := p.Tileset(21); icons.Path != "" {
+		t.Errorf("internal tileset has path %q", icons.Path)
+	}
+
+	level := p.Level("Level_0")
+	if level.Fields.String("music", "") != "cave.ogg" || level.BgColor.R != 0x10 || level.Neighbours[0].Dir != "e" {
+		t.Errorf("level is %+v", level)
+	}
+	if len(level.Layers) != 2 || level.Layers[0].Identifier != "Collisions" {
+		t.Fatalf("layers are not bottom to top")
+	}
+	walls := level.Layer("Collisions")
+	if walls.Type != IntGridLayer || walls.Def.IntGridValues[0].Identifier != "wall" || walls.Tileset != cavern {
+		t.Errorf("IntGrid layer is %+v", walls)
+	}
+	if walls.IntAt(0, 1) != 1 || walls.IntAt(2, 1) != 0 || walls.IntAt(3, 0) != 0 {
+		t.Errorf("IntGrid values are %v", walls.IntGrid)
+	}
+	if len(walls.Tiles) != 2 || !walls.Tiles[0].FlipX || walls.Tiles[0].Alpha != 1 || !walls.Tiles[1].FlipY || walls.Tiles[1].Alpha != 0.5 {
+		t.Errorf("auto-layer tiles are %+v", walls.Tiles)
+	}
+
+	players := level.Entities("Player")
+	if len(players) != 1 || len(level.Entities("")) != 1 || len(level.Entities("Enemy")) != 0 {
+		t.Fatalf("got %d players", len(players))
+	}
+	e := players[0]
+	if e.X != 24 || e.Y != 32 || e.GridX != 1 || e.WorldX != 280 || e.PivotY != 1 || e.Def.Identifier != "Player" || e.Tile.Tileset != cavern {
+		t.Errorf("entity is %+v", e)
+	}
+	f := e.Fields
+	if f.Int("health", 0) != 12 || f.Float("speed", 0) != 1.5 || !f.Bool("friendly", false) || f.Color("tint", level.BgColor).B != 0xFF {
+		t.Errorf("fields are wrong")
+	}
+	if f.Int("unset", -1) != -1 || f.Int("missing", -1) != -1 {
+		t.Errorf("unset fields don't use their default")
+	}
+	if home, ok := f.Point("home"); !ok || home != (Point{2, 0}) {
+		t.Errorf("point field is %+v", home)
+	}
+	if ref, ok := f.EntityRef("target"); !ok || p.Entity(ref.EntityIID) != e {
+		t.Errorf("entity reference is %+v", ref)
+	}
+	var items []string
+	if !f.Decode("items", &items) || len(items) != 2 || items[1] != "Sword" {
+		t.Errorf("array field is %v", items)
+	}
+
+	ext := p.Level("Level_1")
+	if ext.Layers != nil {
+		t.Fatalf("external level was loaded early")
+	}
+	if err := p.LoadLevel(ext); err != nil {
+		t.Fatal(err)
+	}
+	if p.Level("Level_1") != ext || ext.BgColor.R != 0x20 || len(ext.Entities("Player")) != 1 {
+		t.Errorf("external level is %+v", ext)
+	}
+	if e := ext.Entities("Player")[0]; e.WorldX != 520 || e.WorldY != 16 {
+		t.Errorf("entity without world position is at %d, %d", e.WorldX, e.WorldY)
+	}
+}
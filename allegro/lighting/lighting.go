@@ -0,0 +1,338 @@
+// Package lighting renders 2D lights into a light map and darkens a scene
+// with it.
+//
+// A Map starts out filled with the ambient light. Each light adds its color
+// with a falloff towards its radius, minus the hard shadows cast by
+// occluders, and the result is multiplied over the scene:
+//
+//	lights, err := lighting.New(320, 180)
+//	...
+//	lights.Ambient = allegro.MapRGB(20, 20, 40)
+//	lights.Lights = append(lights.Lights, &lighting.Light{
+//		X: 100, Y: 80, Radius: 96,
+//		Color:       allegro.MapRGB(255, 200, 120),
+//		CastShadows: true,
+//	})
+//	lights.Occluders = append(lights.Occluders, wall)
+//	...
+//	// draw the scene
+//	lights.Render()
+//	lights.Composite()
+//
+// Rendering a light map requires the primitives addon.
+package lighting
+
+import (
+	"errors"
+	"math"
+
+	"github.com/dradtke/go-allegro/allegro"
+	"github.com/dradtke/go-allegro/allegro/primitives"
+)
+
+// Light is a point or cone light.
+type Light struct {
+	X, Y   float32
+	Radius float32
+
+	// Color is added to the light map at the light's center, fading to
+	// nothing at its radius.
+	Color allegro.Color
+
+	// Direction and Spread, both in radians, make a cone light, which
+	// shines within Spread/2 of Direction. A Spread of zero, or of 2π or
+	// more, is a point light.
+	Direction, Spread float32
+
+	// Sprite, if set, is drawn tinted by Color and scaled to the light's
+	// radius in place of the default linear falloff, for point lights with
+	// softer or more stylized edges.
+	Sprite *allegro.Bitmap
+
+	// CastShadows makes the light's occluders block it.
+	CastShadows bool
+}
+
+func (l *Light) isCone() bool {
+	return l.Spread > 0 && l.Spread < 2*math.Pi
+}
+
+// Map is a light map.
+type Map struct {
+	// Ambient is the light everywhere, before any lights are added. White
+	// leaves the scene unchanged.
+	Ambient allegro.Color
+
+	Lights []*Light
+
+	// Occluders are closed polygons that block lights with CastShadows
+	// set. They cast shadows behind them, but aren't shadowed themselves.
+	Occluders []primitives.Polyline
+
+	// Transform, if set, maps light and occluder coordinates onto the light
+	// map, such as a camera transform.
+	Transform *allegro.Transform
+
+	// Segments is the number of triangles used to draw a full circle of
+	// light.
+	Segments int
+
+	bitmap, scratch *allegro.Bitmap
+}
+
+// erase sets the destination to transparent black.
+var erase = allegro.BlendMode{
+	Op: allegro.ADD, Src: allegro.ZERO, Dst: allegro.ZERO,
+	AlphaOp: allegro.ADD, AlphaSrc: allegro.ZERO, AlphaDst: allegro.ZERO,
+}
+
+// New creates a light map. It is usually the size of the display, or of the
+// viewport buffer when rendering at a lower resolution. Lights look
+// smoother, at some cost, when it is created with MIN_LINEAR and MAG_LINEAR
+// set in the new bitmap flags.
+func New(width, height int) (*Map, error) {
+	m := &Map{Ambient: allegro.MapRGB(0, 0, 0), Segments: 48}
+	if err := m.Resize(width, height); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Resize recreates the light map at a new size.
+func (m *Map) Resize(width, height int) error {
+	if width <= 0 || height <= 0 {
+		return errors.New("invalid light map size")
+	}
+	m.Destroy()
+	m.bitmap = allegro.CreateBitmap(width, height)
+	m.scratch = allegro.CreateBitmap(width, height)
+	if m.bitmap == nil || m.scratch == nil {
+		m.Destroy()
+		return errors.New("failed to create light map")
+	}
+	return nil
+}
+
+// Bitmap returns the light map, as of the last call to Render.
+func (m *Map) Bitmap() *allegro.Bitmap {
+	return m.bitmap
+}
+
+// Destroy frees the light map.
+func (m *Map) Destroy() {
+	for _, bmp := range []*allegro.Bitmap{m.bitmap, m.scratch} {
+		if bmp != nil {
+			bmp.Destroy()
+		}
+	}
+	m.bitmap, m.scratch = nil, nil
+}
+
+// Render redraws the light map from the ambient light, lights and occluders.
+func (m *Map) Render() {
+	m.bitmap.AsTarget(func() {
+		allegro.ClearToColor(m.Ambient)
+	})
+	for _, l := range m.Lights {
+		if l.Radius <= 0 {
+			continue
+		}
+		if !l.CastShadows || len(m.Occluders) == 0 {
+			drawTo(m.bitmap, m.Transform, func() {
+				allegro.SetBlendMode(allegro.BlendAdditive)
+				m.drawLight(l)
+			})
+			continue
+		}
+
+		// Draw the light on its own, cut the shadows out of it, and then
+		// add what's left to the light map.
+		x, y, w, h := m.bounds(l)
+		if w <= 0 || h <= 0 {
+			continue
+		}
+		drawTo(m.scratch, m.Transform, func() {
+			allegro.SetClippingRectangle(x, y, w, h)
+			allegro.ClearToColor(allegro.MapRGBA(0, 0, 0, 0))
+			allegro.SetBlendMode(allegro.BlendAdditive)
+			m.drawLight(l)
+			allegro.SetBlendMode(erase)
+			black := allegro.MapRGB(0, 0, 0)
+			for _, occluder := range m.Occluders {
+				for _, shadow := range shadows(primitives.Point{X: l.X, Y: l.Y}, l.Radius, occluder) {
+					primitives.DrawFilledPolygon(shadow, black)
+				}
+			}
+			allegro.ResetClippingRectangle()
+		})
+		drawTo(m.bitmap, nil, func() {
+			allegro.SetBlendMode(allegro.BlendAdditive)
+			m.scratch.DrawRegion(float32(x), float32(y), float32(w), float32(h), float32(x), float32(y), allegro.FLIP_NONE)
+		})
+	}
+}
+
+// Composite multiplies the light map over the target bitmap, stretching it
+// to cover the whole target.
+func (m *Map) Composite() {
+	allegro.WithState(allegro.STATE_BLENDER|allegro.STATE_TRANSFORM, func() {
+		allegro.UseTransform(allegro.IdentityTransform())
+		allegro.SetBlender(allegro.ADD, allegro.DEST_COLOR, allegro.ZERO)
+		target := allegro.TargetBitmap()
+		m.bitmap.DrawScaled(0, 0, float32(m.bitmap.Width()), float32(m.bitmap.Height()),
+			0, 0, float32(target.Width()), float32(target.Height()), allegro.FLIP_NONE)
+	})
+}
+
+// drawTo calls f with target as the target bitmap and t, or the identity if t
+// is nil, as the transform, restoring the previous target, transform and
+// blender afterwards.
+func drawTo(target *allegro.Bitmap, t *allegro.Transform, f func()) {
+	allegro.WithState(allegro.STATE_TARGET_BITMAP|allegro.STATE_TRANSFORM|allegro.STATE_BLENDER, func() {
+		allegro.SetTargetBitmap(target)
+		if t == nil {
+			t = allegro.IdentityTransform()
+		}
+		allegro.UseTransform(t)
+		f()
+	})
+}
+
+// bounds returns the area of the light map that a light can reach.
+func (m *Map) bounds(l *Light) (x, y, w, h int) {
+	minX, minY := float32(math.Inf(1)), float32(math.Inf(1))
+	maxX, maxY := float32(math.Inf(-1)), float32(math.Inf(-1))
+	for _, c := range [4][2]float32{{-1, -1}, {1, -1}, {-1, 1}, {1, 1}} {
+		px, py := l.X+c[0]*l.Radius, l.Y+c[1]*l.Radius
+		if m.Transform != nil {
+			px, py = m.Transform.Coordinates(px, py)
+		}
+		minX, minY = min(minX, px), min(minY, py)
+		maxX, maxY = max(maxX, px), max(maxY, py)
+	}
+	x0 := int(math.Max(0, math.Floor(float64(minX))))
+	y0 := int(math.Max(0, math.Floor(float64(minY))))
+	x1 := int(math.Min(float64(m.bitmap.Width()), math.Ceil(float64(maxX))))
+	y1 := int(math.Min(float64(m.bitmap.Height()), math.Ceil(float64(maxY))))
+	return x0, y0, x1 - x0, y1 - y0
+}
+
+// drawLight draws a light's falloff onto the target.
+func (m *Map) drawLight(l *Light) {
+	if l.Sprite != nil && !l.isCone() {
+		w, h := float32(l.Sprite.Width()), float32(l.Sprite.Height())
+		l.Sprite.DrawTintedScaled(l.Color, 0, 0, w, h, l.X-l.Radius, l.Y-l.Radius, l.Radius*2, l.Radius*2, allegro.FLIP_NONE)
+		return
+	}
+	start, sweep := float32(0), float32(2*math.Pi)
+	if l.isCone() {
+		start, sweep = l.Direction-l.Spread/2, l.Spread
+	}
+	vertices := fan(l.X, l.Y, l.Radius, start, sweep, l.Color, m.Segments)
+	primitives.DrawPrim(vertices, nil, nil, 0, len(vertices), primitives.PRIM_TRIANGLE_FAN)
+}
+
+// fan returns a triangle fan covering part of a circle, with color at the
+// center fading to transparent black at the edge.
+func fan(x, y, r, start, sweep float32, color allegro.Color, segments int) []primitives.Vertex {
+	n := int(math.Ceil(float64(segments) * float64(sweep) / (2 * math.Pi)))
+	if n < 1 {
+		n = 1
+	}
+	edge := allegro.MapRGBA(0, 0, 0, 0)
+	vertices := make([]primitives.Vertex, 0, n+2)
+	vertices = append(vertices, primitives.Vertex{X: x, Y: y, Color: color})
+	for i := 0; i <= n; i++ {
+		a := float64(start + sweep*float32(i)/float32(n))
+		vertices = append(vertices, primitives.Vertex{
+			X:     x + r*float32(math.Cos(a)),
+			Y:     y + r*float32(math.Sin(a)),
+			Color: edge,
+		})
+	}
+	return vertices
+}
+
+// shadows returns the polygons shadowed from a light by an occluder, out to
+// at least radius from the light. A shadow is cast behind each edge that
+// faces away from the light, so the occluder itself is left lit.
+func shadows(light primitives.Point, radius float32, occluder primitives.Polyline) []primitives.Polyline {
+	if len(occluder) < 3 {
+		return nil
+	}
+	// Edges face away from the light when the light is on their inner
+	// side, which depends on the polygon's winding.
+	var area float32
+	for i, a := range occluder {
+		b := occluder[(i+1)%len(occluder)]
+		area += a.X*b.Y - b.X*a.Y
+	}
+
+	var result []primitives.Polyline
+	for i, a := range occluder {
+		b := occluder[(i+1)%len(occluder)]
+		side := (b.X-a.X)*(light.Y-a.Y) - (b.Y-a.Y)*(light.X-a.X)
+		if side*area <= 0 {
+			continue
+		}
+		da, db := dist(light, a), dist(light, b)
+		if da >= radius && db >= radius && segmentDist(light, a, b) >= radius {
+			continue
+		}
+		// Project the edge away from the light. The extra point along
+		// the bisector keeps the far side of the shadow outside of the
+		// light's radius even when the edge is wide and close.
+		far := 2 * radius
+		mid := primitives.Point{
+			X: (a.X-light.X)/da + (b.X-light.X)/db,
+			Y: (a.Y-light.Y)/da + (b.Y-light.Y)/db,
+		}
+		result = append(result, primitives.Polyline{
+			a,
+			project(light, a, max(far, da)),
+			project(light, primitives.Point{X: light.X + mid.X, Y: light.Y + mid.Y}, far*2),
+			project(light, b, max(far, db)),
+			b,
+		})
+	}
+	return result
+}
+
+// project returns the point at distance d from origin in the direction of p.
+func project(origin, p primitives.Point, d float32) primitives.Point {
+	l := dist(origin, p)
+	if l == 0 {
+		return p
+	}
+	return primitives.Point{X: origin.X + (p.X-origin.X)*d/l, Y: origin.Y + (p.Y-origin.Y)*d/l}
+}
+
+func dist(a, b primitives.Point) float32 {
+	return float32(math.Hypot(float64(b.X-a.X), float64(b.Y-a.Y)))
+}
+
+// segmentDist returns the distance from p to the segment from a to b.
+func segmentDist(p, a, b primitives.Point) float32 {
+	dx, dy := b.X-a.X, b.Y-a.Y
+	l := dx*dx + dy*dy
+	if l == 0 {
+		return dist(p, a)
+	}
+	t := ((p.X-a.X)*dx + (p.Y-a.Y)*dy) / l
+	t = max(0, min(1, t))
+	return dist(p, primitives.Point{X: a.X + t*dx, Y: a.Y + t*dy})
+}
+
+func min(a, b float32) float32 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func max(a, b float32) float32 {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,60 @@
+package lighting
+
+import (
+	"testing"
+
+	"github.com/dradtke/go-allegro/allegro/primitives"
+)
+
+// inside reports whether p is inside the polygon, by ray casting.
+func inside(p primitives.Point, poly primitives.Polyline) bool {
+	in := false
+	for i, a := range poly {
+		b := poly[(i+1)%len(poly)]
+		if (a.Y > p.Y) != (b.Y > p.Y) && p.X < a.X+(p.Y-a.Y)*(b.X-a.X)/(b.Y-a.Y) {
+			in = !in
+		}
+	}
+	return in
+}
+
+func shadowed(p primitives.Point, shadows []primitives.Polyline) bool {
+	for _, s := range shadows {
+		if inside(p, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestShadows(t *testing.T) {
+	light := primitives.Point{X: 0, Y: 0}
+	box := primitives.Polyline{{X: 10, Y: -5}, {X: 20, Y: -5}, {X: 20, Y: 5}, {X: 10, Y: 5}}
+	reversed := primitives.Polyline{box[3], box[2], box[1], box[0]}
+
+	for _, occluder := range []primitives.Polyline{box, reversed} {
+		s := shadows(light, 100, occluder)
+		for _, tc := range []struct {
+			p    primitives.Point
+			want bool
+		}{
+			{primitives.Point{X: 50, Y: 0}, true},
+			{primitives.Point{X: 95, Y: 40}, true},
+			{primitives.Point{X: 5, Y: 0}, false},
+			{primitives.Point{X: 15, Y: 0}, false},
+			{primitives.Point{X: 50, Y: 40}, false},
+			{primitives.Point{X: -50, Y: 0}, false},
+		} {
+			if got := shadowed(tc.p, s); got != tc.want {
+				t.Errorf("point %v shadowed = %v, want %v", tc.p, got, tc.want)
+			}
+		}
+	}
+}
+
+func TestShadowsOutOfRange(t *testing.T) {
+	box := primitives.Polyline{{X: 200, Y: -5}, {X: 210, Y: -5}, {X: 210, Y: 5}, {X: 200, Y: 5}}
+	if s := shadows(primitives.Point{}, 100, box); len(s) != 0 {
+		t.Errorf("got %d shadows from an occluder out of range", len(s))
+	}
+}
@@ -0,0 +1,81 @@
+package loader
+
+import (
+	"io/ioutil"
+
+	"github.com/dradtke/go-allegro/allegro"
+	"github.com/dradtke/go-allegro/allegro/audio"
+	"github.com/dradtke/go-allegro/allegro/font"
+)
+
+// Bitmap queues a bitmap to be loaded into *bmp. A worker decodes it into a
+// memory bitmap, which Finish converts using the new bitmap flags of the
+// thread that calls it, normally into a video bitmap.
+func (l *Loader) Bitmap(filename string, bmp **allegro.Bitmap) {
+	var b *allegro.Bitmap
+	l.Add(Job{
+		Load: func() (err error) {
+			allegro.SetNewBitmapFlags(allegro.MEMORY_BITMAP)
+			b, err = allegro.LoadBitmap(filename)
+			return err
+		},
+		Finish: func() error {
+			b.Convert()
+			*bmp = b
+			return nil
+		},
+		Cancel: func() {
+			b.Destroy()
+		},
+	})
+}
+
+// Font queues a font to be loaded into *f, as by font.LoadFont. Unlike the
+// other assets, a font is loaded entirely by Finish, on the display's thread,
+// because the TTF addon keeps the new bitmap flags of the thread that loads a
+// font for the glyph pages that it makes later. TTF glyphs are drawn when they
+// are first used, so this is usually quick, but a bitmap font's image is
+// decoded by Finish too; to decode it on a worker, load it with Bitmap and
+// use font.GrabFontFromBitmap instead.
+func (l *Loader) Font(filename string, size, flags int, f **font.Font) {
+	l.Add(Job{
+		Finish: func() (err error) {
+			*f, err = font.LoadFont(filename, size, flags)
+			return err
+		},
+	})
+}
+
+// Sample queues an audio sample to be loaded into *s, as by audio.LoadSample.
+// Samples don't need a display, so they are decoded entirely by a worker.
+func (l *Loader) Sample(filename string, s **audio.Sample) {
+	var sample *audio.Sample
+	l.Add(Job{
+		Load: func() (err error) {
+			sample, err = audio.LoadSample(filename)
+			return err
+		},
+		Finish: func() error {
+			*s = sample
+			return nil
+		},
+		Cancel: func() {
+			sample.Destroy()
+		},
+	})
+}
+
+// File queues a file to be read into *data.
+func (l *Loader) File(filename string, data *[]byte) {
+	var b []byte
+	l.Add(Job{
+		Load: func() (err error) {
+			b, err = ioutil.ReadFile(filename)
+			return err
+		},
+		Finish: func() error {
+			*data = b
+			return nil
+		},
+	})
+}
@@ -0,0 +1,255 @@
+// Package loader loads assets on background goroutines, so that a loading
+// screen can keep drawing while they are read.
+//
+// Files are read and decoded by workers into memory bitmaps and buffers,
+// which need a display to become video bitmaps. That last step is done by
+// Finish, which must be called on the thread that owns the display, usually
+// from the event loop. The loader's event source wakes the loop each time an
+// asset is ready to be finished.
+//
+//	ld := loader.New(0)
+//	defer ld.Close()
+//	src, err := ld.EventSource()
+//	if err != nil {
+//		...
+//	}
+//	queue.RegisterEventSource(src)
+//
+//	var player *allegro.Bitmap
+//	var music *audio.Sample
+//	var ui *font.Font
+//	ld.Bitmap("player.png", &player)
+//	ld.Sample("music.ogg", &music)
+//	ld.Font("ui.ttf", 16, 0, &ui)
+//
+//	for !ld.Done() {
+//		queue.WaitForEvent(&event)
+//		ld.Finish(5 * time.Millisecond)
+//		done, total := ld.Progress()
+//		drawLoadingScreen(float32(done) / float32(total))
+//	}
+//	if err := ld.Err(); err != nil {
+//		...
+//	}
+package loader
+
+import (
+	"errors"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/dradtke/go-allegro/allegro"
+)
+
+// Job is an asset to load. Load, if it isn't nil, is called by a worker, and
+// Finish, if it isn't nil, by Loader.Finish once Load has succeeded. Cancel, if it isn't
+// nil, is called instead of Finish for a job that has been loaded when the
+// loader is closed, to free what Load made.
+//
+// Each worker goroutine is locked to its own thread, so Load may change
+// Allegro's thread-local state, such as the new bitmap flags, without
+// affecting other threads.
+type Job struct {
+	Load   func() error
+	Finish func() error
+	Cancel func()
+}
+
+type result struct {
+	Job
+	err error
+}
+
+// Loader runs jobs on a pool of worker goroutines.
+type Loader struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	pending []Job
+	loaded  []result
+	ready   chan struct{}
+	source  *allegro.EventSource
+	closed  bool
+
+	done, total int
+	err         error
+
+	wg sync.WaitGroup
+}
+
+// New starts a loader with a number of workers, or one for each CPU if
+// workers isn't positive.
+func New(workers int) *Loader {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	l := &Loader{ready: make(chan struct{}, 1)}
+	l.cond = sync.NewCond(&l.mu)
+	l.wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go l.work()
+	}
+	return l
+}
+
+// EventSource returns a source that emits a user event whenever a job has
+// been loaded and is waiting to be finished. It is created on first use, and
+// jobs loaded before then don't emit events.
+func (l *Loader) EventSource() (*allegro.EventSource, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed {
+		return nil, errors.New("loader is closed")
+	}
+	if l.source == nil {
+		// A source declared in Go can't be handed to a queue, so it is
+		// allocated by Allegro instead.
+		source, err := allegro.CreateUserEventSource()
+		if err != nil {
+			return nil, err
+		}
+		l.source = source
+	}
+	return l.source, nil
+}
+
+// Add queues a job. It does nothing once the loader is closed.
+func (l *Loader) Add(job Job) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed {
+		return
+	}
+	l.pending = append(l.pending, job)
+	l.total++
+	l.cond.Signal()
+}
+
+// Finish finishes jobs that have been loaded, in the order that they were
+// loaded, and returns how many it finished. It stops early once budget has
+// passed, unless budget is zero, but always finishes at least one job if any
+// are waiting.
+//
+// Finish must be called on the thread whose display the assets are for.
+func (l *Loader) Finish(budget time.Duration) int {
+	start := time.Now()
+	n := 0
+	for budget <= 0 || n == 0 || time.Since(start) < budget {
+		l.mu.Lock()
+		if len(l.loaded) == 0 {
+			l.mu.Unlock()
+			break
+		}
+		r := l.loaded[0]
+		l.loaded = l.loaded[1:]
+		l.mu.Unlock()
+
+		if r.err == nil && r.Finish != nil {
+			r.err = r.Finish()
+		}
+
+		l.mu.Lock()
+		l.done++
+		if r.err != nil && l.err == nil {
+			l.err = r.err
+		}
+		l.mu.Unlock()
+		n++
+	}
+	return n
+}
+
+// Wait finishes jobs as they are loaded until all of them are done, and
+// returns the same as Err. Like Finish, it must be called on the display's
+// thread.
+func (l *Loader) Wait() error {
+	for {
+		l.Finish(0)
+		if l.Done() {
+			return l.Err()
+		}
+		<-l.ready
+	}
+}
+
+// Progress returns how many jobs have been finished, and how many have been
+// queued.
+func (l *Loader) Progress() (done, total int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.done, l.total
+}
+
+// Done returns whether every queued job has been finished.
+func (l *Loader) Done() bool {
+	done, total := l.Progress()
+	return done == total
+}
+
+// Err returns the first error from loading or finishing a job, if any.
+func (l *Loader) Err() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.err
+}
+
+// Close stops the workers once their current jobs are loaded, and frees the
+// event source. Jobs that haven't started are dropped, and those that have
+// been loaded but not finished are cancelled.
+func (l *Loader) Close() {
+	l.mu.Lock()
+	l.closed = true
+	l.pending = nil
+	l.cond.Broadcast()
+	l.mu.Unlock()
+	l.wg.Wait()
+
+	for _, r := range l.loaded {
+		if r.err == nil && r.Cancel != nil {
+			r.Cancel()
+		}
+	}
+	l.loaded = nil
+	if l.source != nil {
+		l.source.FreeUserEventSource()
+		l.source = nil
+	}
+}
+
+func (l *Loader) work() {
+	defer l.wg.Done()
+	// Never unlocked, so the thread exits along with the goroutine instead of
+	// going back to the runtime with whatever state the jobs left on it.
+	runtime.LockOSThread()
+	for {
+		l.mu.Lock()
+		for len(l.pending) == 0 && !l.closed {
+			l.cond.Wait()
+		}
+		if l.closed {
+			l.mu.Unlock()
+			return
+		}
+		job := l.pending[0]
+		l.pending = l.pending[1:]
+		l.mu.Unlock()
+
+		var err error
+		if job.Load != nil {
+			err = job.Load()
+		}
+
+		l.mu.Lock()
+		l.loaded = append(l.loaded, result{job, err})
+		source := l.source
+		l.mu.Unlock()
+		select {
+		case l.ready <- struct{}{}:
+		default:
+		}
+		if source != nil {
+			// This fails only if nothing is listening.
+			source.EmitUserEvent()
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package loader
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestLoader(t *testing.T) {
+	dir, err := ioutil.TempDir("", "loader")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := ioutil.WriteFile(filepath.Join(dir, "a.txt"), []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	l := New(2)
+	defer l.Close()
+	var data, missing []byte
+	l.File(filepath.Join(dir, "a.txt"), &data)
+	l.File(filepath.Join(dir, "b.txt"), &missing)
+	finished := 0
+	for i := 0; i < 10; i++ {
+		job := Job{Finish: func() error { finished++; return nil }}
+		if i%2 == 0 {
+			job.Load = func() error { return nil }
+		}
+		l.Add(job)
+	}
+	if _, total := l.Progress(); total != 12 {
+		t.Errorf("total is %d", total)
+	}
+	if err := l.Wait(); err == nil || !os.IsNotExist(err) {
+		t.Errorf("error is %v", err)
+	}
+	if done, total := l.Progress(); done != total || !l.Done() {
+		t.Errorf("progress is %d/%d", done, total)
+	}
+	if string(data) != "hello" || missing != nil || finished != 10 {
+		t.Errorf("data is %q, missing is %q, %d finished", data, missing, finished)
+	}
+
+	failed := errors.New("failed to finish")
+	l.Add(Job{
+		Load:   func() error { return nil },
+		Finish: func() error { return failed },
+	})
+	l.Wait()
+	if l.Err() == failed {
+		t.Errorf("first error was replaced")
+	}
+}
+
+func TestClose(t *testing.T) {
+	l := New(1)
+	started, block := make(chan struct{}), make(chan struct{})
+	cancelled := 0
+	l.Add(Job{
+		Load:   func() error { close(started); <-block; return nil },
+		Finish: func() error { t.Error("finished after close"); return nil },
+		Cancel: func() { cancelled++ },
+	})
+	l.Add(Job{
+		Load: func() error { t.Error("loaded after close"); return nil },
+	})
+	<-started
+	go func() {
+		// Let the first job's load end only once the loader is closing.
+		for closed := false; !closed; runtime.Gosched() {
+			l.mu.Lock()
+			closed = l.closed
+			l.mu.Unlock()
+		}
+		close(block)
+	}()
+	l.Close()
+	if cancelled != 1 {
+		t.Errorf("cancelled %d jobs", cancelled)
+	}
+	l.Add(Job{Load: func() error { return nil }})
+	if done, total := l.Progress(); done != 0 || total != 2 {
+		t.Errorf("progress is %d/%d", done, total)
+	}
+}
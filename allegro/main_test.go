@@ -0,0 +1,11 @@
+package allegro_test
+
+import (
+	"testing"
+
+	"github.com/dradtke/go-allegro/allegro/allegrotest"
+)
+
+func TestMain(m *testing.M) {
+	allegrotest.Main(m)
+}
@@ -0,0 +1,308 @@
+// Package ninepatch draws bitmaps split into nine pieces, so that frames
+// such as UI panels and buttons can be drawn at any size without stretching
+// their corners.
+//
+// The corners are drawn at their original size, the edges are stretched or
+// tiled along their length, and the center fills the rest. All nine pieces
+// are drawn with a single call to al_draw_prim, which avoids the seams that
+// separately scaled pieces leave at fractional positions:
+//
+//	panel, err := ninepatch.Load("panel.9.png")
+//	...
+//	panel.Draw(x, y, w, h)
+//	cx, cy, cw, ch := panel.Content(x, y, w, h)
+//	// draw the panel's contents within cx, cy, cw, ch
+//
+// Drawing requires the primitives addon.
+package ninepatch
+
+import (
+	"errors"
+	"image"
+
+	"github.com/dradtke/go-allegro/allegro"
+	"github.com/dradtke/go-allegro/allegro/primitives"
+)
+
+// Mode is how the edges or center of a nine-patch fill their space.
+type Mode int
+
+const (
+	// Stretch scales the piece to fill its space.
+	Stretch Mode = iota
+	// Tile repeats the piece at its original size, cutting off the last
+	// repetition.
+	Tile
+)
+
+// Insets are distances in from each side of a rectangle, in pixels.
+type Insets struct {
+	Left, Top, Right, Bottom int
+}
+
+// NinePatch is a bitmap split into nine pieces by its insets.
+type NinePatch struct {
+	// Insets split the bitmap into corners, edges and center.
+	Insets Insets
+
+	// Padding is the space around the content area, used by Content. It
+	// defaults to the insets.
+	Padding Insets
+
+	// Edges and Center control how the edges and center fill their space.
+	Edges, Center Mode
+
+	bitmap        *allegro.Bitmap
+	width, height int
+	owned         []*allegro.Bitmap
+	batch         *primitives.SpriteBatch
+}
+
+// New creates a nine-patch from a bitmap, which may be a sub-bitmap, and its
+// insets. The bitmap is not copied, so it must outlive the nine-patch.
+func New(bmp *allegro.Bitmap, insets Insets) (*NinePatch, error) {
+	if bmp == nil {
+		return nil, allegro.BitmapIsNull
+	}
+	p := &NinePatch{
+		Insets:  insets,
+		Padding: insets,
+		bitmap:  bmp,
+		width:   bmp.Width(),
+		height:  bmp.Height(),
+		batch:   primitives.NewSpriteBatch(),
+	}
+	if insets.Left < 0 || insets.Top < 0 || insets.Right < 0 || insets.Bottom < 0 ||
+		insets.Left+insets.Right > p.width || insets.Top+insets.Bottom > p.height {
+		return nil, errors.New("nine-patch insets don't fit the bitmap")
+	}
+	return p, nil
+}
+
+// FromNinePNG creates a nine-patch from a bitmap with Android-style guides:
+// a one pixel border in which black pixels along the top and left edges mark
+// the stretchable area, and black pixels along the bottom and right edges
+// mark the content area. If the content area isn't marked, it is the same as
+// the stretchable area.
+//
+// The nine-patch uses a sub-bitmap of bmp without the border, so bmp must
+// outlive it.
+func FromNinePNG(bmp *allegro.Bitmap) (*NinePatch, error) {
+	if bmp == nil {
+		return nil, allegro.BitmapIsNull
+	}
+	w, h := bmp.Width(), bmp.Height()
+	if w < 3 || h < 3 {
+		return nil, errors.New("nine-patch image is too small")
+	}
+	reg, err := bmp.Lock(allegro.PIXEL_FORMAT_ABGR_8888_LE, allegro.LOCK_READONLY)
+	if err != nil {
+		return nil, err
+	}
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		copy(img.Pix[y*img.Stride:], reg.Row(y, w))
+	}
+	bmp.Unlock()
+
+	insets, padding, err := guides(img)
+	if err != nil {
+		return nil, err
+	}
+	inner, err := bmp.CreateSubBitmap(1, 1, w-2, h-2)
+	if err != nil {
+		return nil, err
+	}
+	p, err := New(inner, insets)
+	if err != nil {
+		inner.Destroy()
+		return nil, err
+	}
+	p.Padding = padding
+	p.owned = []*allegro.Bitmap{inner}
+	return p, nil
+}
+
+// Load loads a .9.png file and creates a nine-patch from its guides, as
+// FromNinePNG does. Loading requires the image addon. The loaded bitmap is
+// freed by Destroy.
+func Load(filename string) (*NinePatch, error) {
+	bmp, err := allegro.LoadBitmap(filename)
+	if err != nil {
+		return nil, err
+	}
+	p, err := FromNinePNG(bmp)
+	if err != nil {
+		bmp.Destroy()
+		return nil, err
+	}
+	// Destroy sub-bitmaps before their parent.
+	p.owned = append(p.owned, bmp)
+	return p, nil
+}
+
+// Destroy frees any bitmaps that the nine-patch created.
+func (p *NinePatch) Destroy() {
+	for _, bmp := range p.owned {
+		bmp.Destroy()
+	}
+	p.owned = nil
+}
+
+// Bitmap returns the bitmap that the nine-patch draws.
+func (p *NinePatch) Bitmap() *allegro.Bitmap {
+	return p.bitmap
+}
+
+// MinSize returns the smallest size that the nine-patch can be drawn at
+// without shrinking its corners.
+func (p *NinePatch) MinSize() (w, h float32) {
+	return float32(p.Insets.Left + p.Insets.Right), float32(p.Insets.Top + p.Insets.Bottom)
+}
+
+// Content returns the content area of the nine-patch when it is drawn at x,
+// y with size w, h.
+func (p *NinePatch) Content(x, y, w, h float32) (cx, cy, cw, ch float32) {
+	pad := p.Padding
+	return x + float32(pad.Left), y + float32(pad.Top),
+		w - float32(pad.Left+pad.Right), h - float32(pad.Top+pad.Bottom)
+}
+
+// Draw draws the nine-patch to fill the rectangle at x, y with size w, h.
+func (p *NinePatch) Draw(x, y, w, h float32) {
+	p.DrawTinted(allegro.MapRGBAf(1, 1, 1, 1), x, y, w, h)
+}
+
+// DrawTinted draws the nine-patch like Draw, with its colors multiplied by
+// tint.
+func (p *NinePatch) DrawTinted(tint allegro.Color, x, y, w, h float32) {
+	p.AddTo(p.batch, tint, x, y, w, h, 0)
+	p.batch.Flush()
+}
+
+// AddTo adds the nine-patch's pieces to a sprite batch on the given layer,
+// so that many nine-patches sharing a bitmap can be drawn together.
+func (p *NinePatch) AddTo(batch *primitives.SpriteBatch, tint allegro.Color, x, y, w, h float32, layer int) {
+	for _, s := range p.pieces(x, y, w, h) {
+		s.Tint = tint
+		s.Layer = layer
+		batch.Add(s)
+	}
+}
+
+// pieces returns the sprites that draw the nine-patch.
+func (p *NinePatch) pieces(x, y, w, h float32) []primitives.Sprite {
+	if w <= 0 || h <= 0 {
+		return nil
+	}
+	in := p.Insets
+	// Source boundaries, and destination boundaries with the corners
+	// shrunk proportionally if they don't fit.
+	sx := [4]float32{0, float32(in.Left), float32(p.width - in.Right), float32(p.width)}
+	sy := [4]float32{0, float32(in.Top), float32(p.height - in.Bottom), float32(p.height)}
+	dx := bounds(x, w, float32(in.Left), float32(in.Right))
+	dy := bounds(y, h, float32(in.Top), float32(in.Bottom))
+
+	var sprites []primitives.Sprite
+	for row := 0; row < 3; row++ {
+		for col := 0; col < 3; col++ {
+			mode := p.Edges
+			switch {
+			case row == 1 && col == 1:
+				mode = p.Center
+			case row != 1 && col != 1:
+				// Corners are only ever scaled, when shrunk.
+				mode = Stretch
+			}
+			sprites = p.fill(sprites, mode,
+				sx[col], sy[row], sx[col+1]-sx[col], sy[row+1]-sy[row],
+				dx[col], dy[row], dx[col+1]-dx[col], dy[row+1]-dy[row])
+		}
+	}
+	return sprites
+}
+
+// fill appends the sprites that fill a destination rectangle with a source
+// region.
+func (p *NinePatch) fill(sprites []primitives.Sprite, mode Mode, sx, sy, sw, sh, dx, dy, dw, dh float32) []primitives.Sprite {
+	if sw <= 0 || sh <= 0 || dw <= 0 || dh <= 0 {
+		return sprites
+	}
+	if mode == Stretch {
+		return append(sprites, primitives.Sprite{
+			Bitmap: p.bitmap,
+			SX:     sx, SY: sy, SW: sw, SH: sh,
+			DX: dx, DY: dy,
+			ScaleX: dw / sw, ScaleY: dh / sh,
+		})
+	}
+	for ty := float32(0); ty < dh; ty += sh {
+		th := min(sh, dh-ty)
+		for tx := float32(0); tx < dw; tx += sw {
+			tw := min(sw, dw-tx)
+			sprites = append(sprites, primitives.Sprite{
+				Bitmap: p.bitmap,
+				SX:     sx, SY: sy, SW: tw, SH: th,
+				DX: dx + tx, DY: dy + ty,
+			})
+		}
+	}
+	return sprites
+}
+
+// bounds returns the destination boundaries of the three columns or rows
+// of a nine-patch drawn at pos with the given size.
+func bounds(pos, size, start, end float32) [4]float32 {
+	if start+end > size {
+		scale := size / (start + end)
+		start, end = start*scale, end*scale
+	}
+	return [4]float32{pos, pos + start, pos + size - end, pos + size}
+}
+
+// guides reads the insets and padding from a .9.png image's border.
+func guides(img *image.RGBA) (insets, padding Insets, err error) {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	black := func(x, y int) bool {
+		p := img.Pix[img.PixOffset(b.Min.X+x, b.Min.Y+y):]
+		return p[0] == 0 && p[1] == 0 && p[2] == 0 && p[3] == 0xFF
+	}
+	// span returns the first and last+1 guide positions along one edge,
+	// relative to the inner image.
+	span := func(n int, at func(i int) bool) (int, int, bool) {
+		first, last := -1, -1
+		for i := 1; i < n-1; i++ {
+			if at(i) {
+				if first < 0 {
+					first = i
+				}
+				last = i
+			}
+		}
+		return first - 1, last, first >= 0
+	}
+
+	left, right, okX := span(w, func(x int) bool { return black(x, 0) })
+	top, bottom, okY := span(h, func(y int) bool { return black(0, y) })
+	if !okX || !okY {
+		return Insets{}, Insets{}, errors.New("nine-patch image has no stretch guides")
+	}
+	insets = Insets{Left: left, Top: top, Right: w - 2 - right, Bottom: h - 2 - bottom}
+
+	padding = insets
+	if l, r, ok := span(w, func(x int) bool { return black(x, h-1) }); ok {
+		padding.Left, padding.Right = l, w-2-r
+	}
+	if t, b, ok := span(h, func(y int) bool { return black(w-1, y) }); ok {
+		padding.Top, padding.Bottom = t, h-2-b
+	}
+	return insets, padding, nil
+}
+
+func min(a, b float32) float32 {
+	if a < b {
+		return a
+	}
+	return b
+}
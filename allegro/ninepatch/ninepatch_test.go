@@ -0,0 +1,67 @@
+package ninepatch
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestGuides(t *testing.T) {
+	// A 10x8 image inside a one pixel border.
+	img := image.NewRGBA(image.Rect(0, 0, 12, 10))
+	black := color.RGBA{0, 0, 0, 0xFF}
+	for x := 4; x <= 7; x++ {
+		img.SetRGBA(x, 0, black)
+	}
+	for y := 3; y <= 5; y++ {
+		img.SetRGBA(0, y, black)
+	}
+	for x := 2; x <= 9; x++ {
+		img.SetRGBA(x, 9, black)
+	}
+
+	insets, padding, err := guides(img)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (Insets{Left: 3, Top: 2, Right: 3, Bottom: 3}); insets != want {
+		t.Errorf("insets = %+v, want %+v", insets, want)
+	}
+	if want := (Insets{Left: 1, Top: 2, Right: 1, Bottom: 3}); padding != want {
+		t.Errorf("padding = %+v, want %+v", padding, want)
+	}
+
+	if _, _, err := guides(image.NewRGBA(image.Rect(0, 0, 5, 5))); err == nil {
+		t.Error("image without guides was accepted")
+	}
+}
+
+func TestPieces(t *testing.T) {
+	p := &NinePatch{Insets: Insets{4, 4, 4, 4}, width: 12, height: 12}
+
+	sprites := p.pieces(10, 20, 30, 40)
+	if len(sprites) != 9 {
+		t.Fatalf("got %d stretched pieces, want 9", len(sprites))
+	}
+	center := sprites[4]
+	if center.DX != 14 || center.DY != 24 || center.SW*center.ScaleX != 22 || center.SH*center.ScaleY != 32 {
+		t.Errorf("center piece is %+v", center)
+	}
+	if br := sprites[8]; br.DX != 36 || br.DY != 56 || br.ScaleX != 1 || br.ScaleY != 1 {
+		t.Errorf("bottom-right corner is %+v", br)
+	}
+
+	// The 4x4 center tiled over 22x32 takes 6x8 tiles, the last column of
+	// which is cut to 2 pixels.
+	p.Center = Tile
+	sprites = p.pieces(10, 20, 30, 40)
+	if n := len(sprites) - 8; n != 48 {
+		t.Errorf("got %d center tiles, want 48", n)
+	}
+
+	// Corners shrink when the nine-patch is smaller than them.
+	sprites = p.pieces(0, 0, 4, 4)
+	if tl := sprites[0]; tl.ScaleX != 0.5 || tl.ScaleY != 0.5 {
+		t.Errorf("shrunk corner is %+v", tl)
+	}
+}
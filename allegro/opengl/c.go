@@ -0,0 +1,6 @@
+// +build opengl
+
+package opengl
+
+// #cgo !windows pkg-config: allegro-5
+import "C"
@@ -0,0 +1,8 @@
+// Package opengl provides support for Allegro's OpenGL integration, for
+// mixing custom OpenGL rendering with Allegro's own.
+//
+// It needs the OpenGL headers to build, so it is only compiled with the
+// "opengl" build tag:
+//
+//	go build -tags opengl
+package opengl
@@ -0,0 +1,149 @@
+// +build opengl
+
+package opengl
+
+// #include <allegro5/allegro.h>
+// #include <allegro5/allegro_opengl.h>
+// #include "../util.c"
+import "C"
+import (
+	"unsafe"
+
+	"github.com/dradtke/go-allegro/allegro"
+)
+
+type Variant int
+
+const (
+	DESKTOP_OPENGL Variant = C.ALLEGRO_DESKTOP_OPENGL
+	OPENGL_ES              = C.ALLEGRO_OPENGL_ES
+)
+
+// Returns the OpenGL texture id internally used by the given bitmap if it
+// uses one, else 0.
+//
+// See https://liballeg.org/a5docs/5.2.6/opengl.html#al_get_opengl_texture
+func Texture(bmp *allegro.Bitmap) uint32 {
+	return uint32(C.al_get_opengl_texture((*C.ALLEGRO_BITMAP)(unsafe.Pointer(bmp))))
+}
+
+// Retrieves the size of the texture used for the bitmap. This can be
+// different from the bitmap size if OpenGL only supports power-of-two sizes
+// or if it is a sub-bitmap.
+//
+// Returns true on success, false on failure. Zero width and height are
+// returned if the bitmap is not an OpenGL bitmap.
+//
+// See https://liballeg.org/a5docs/5.2.6/opengl.html#al_get_opengl_texture_size
+func TextureSize(bmp *allegro.Bitmap) (w, h int, ok bool) {
+	var cw, ch C.int
+	ok = bool(C.al_get_opengl_texture_size((*C.ALLEGRO_BITMAP)(unsafe.Pointer(bmp)), &cw, &ch))
+	return int(cw), int(ch), ok
+}
+
+// Returns the u/v coordinates for the top/left corner of the bitmap within
+// the used texture, in pixels.
+//
+// See https://liballeg.org/a5docs/5.2.6/opengl.html#al_get_opengl_texture_position
+func TexturePosition(bmp *allegro.Bitmap) (u, v int) {
+	var cu, cv C.int
+	C.al_get_opengl_texture_position((*C.ALLEGRO_BITMAP)(unsafe.Pointer(bmp)), &cu, &cv)
+	return int(cu), int(cv)
+}
+
+// Returns the OpenGL FBO id internally used by the given bitmap if it uses
+// one, otherwise returns zero. No attempt will be made to create an FBO if
+// the bitmap is not owned by the current display.
+//
+// The FBO returned by this function will only be freed when the bitmap is
+// destroyed, or if you call al_remove_opengl_fbo on the bitmap.
+//
+// Note: In Allegro 5.0.0 this function only returned an FBO if one had
+// previously been created by calling al_set_target_bitmap. It would not
+// attempt to create an FBO itself. This has since been changed.
+//
+// See https://liballeg.org/a5docs/5.2.6/opengl.html#al_get_opengl_fbo
+func FBO(bmp *allegro.Bitmap) uint32 {
+	return uint32(C.al_get_opengl_fbo((*C.ALLEGRO_BITMAP)(unsafe.Pointer(bmp))))
+}
+
+// Explicitly free an OpenGL FBO created for a bitmap, if it has one. Usually
+// you do not need to worry about freeing FBOs, unless you use
+// al_get_opengl_fbo.
+//
+// See https://liballeg.org/a5docs/5.2.6/opengl.html#al_remove_opengl_fbo
+func RemoveFBO(bmp *allegro.Bitmap) {
+	C.al_remove_opengl_fbo((*C.ALLEGRO_BITMAP)(unsafe.Pointer(bmp)))
+}
+
+// Returns the OpenGL program object associated with this shader, if the
+// platform is ALLEGRO_SHADER_GLSL. Otherwise, returns 0.
+//
+// See https://liballeg.org/a5docs/5.2.6/opengl.html#al_get_opengl_program_object
+func ProgramObject(shader *allegro.Shader) uint32 {
+	return uint32(C.al_get_opengl_program_object((*C.ALLEGRO_SHADER)(unsafe.Pointer(shader))))
+}
+
+// Returns the OpenGL or OpenGL ES version number of the client (the computer
+// the program is running on), for the current display. "1.0" is returned as
+// 0x01000000, "1.2.1" is returned as 0x01020100, and "1.2.2" as 0x01020200,
+// etc.
+//
+// A valid OpenGL context must exist for this function to work, which means
+// you may not call it before al_create_display.
+//
+// See https://liballeg.org/a5docs/5.2.6/opengl.html#al_get_opengl_version
+func Version() (major, minor, revision uint8) {
+	v := uint32(C.al_get_opengl_version())
+	major = uint8(v >> 24)
+	minor = uint8((v >> 16) & 255)
+	revision = uint8((v >> 8) & 255)
+	return
+}
+
+// Returns the variant or type of OpenGL used on the running platform. This
+// function can be called before creating a display or setting properties for
+// new displays. Possible values are: ALLEGRO_DESKTOP_OPENGL, ALLEGRO_OPENGL_ES.
+//
+// See https://liballeg.org/a5docs/5.2.6/opengl.html#al_get_opengl_variant
+func CurrentVariant() Variant {
+	return Variant(C.al_get_opengl_variant())
+}
+
+// This function is a helper to determine whether an OpenGL extension is
+// available on the given display or not.
+//
+// Returns true if the extension is available, false otherwise.
+//
+// See https://liballeg.org/a5docs/5.2.6/opengl.html#al_have_opengl_extension
+func HaveExtension(extension string) bool {
+	extension_ := C.CString(extension)
+	defer C.free_string(extension_)
+	return bool(C.al_have_opengl_extension(extension_))
+}
+
+// Helper to get the address of an OpenGL symbol.
+//
+// Returns nil if the symbol could not be found.
+//
+// See https://liballeg.org/a5docs/5.2.6/opengl.html#al_get_opengl_proc_address
+func ProcAddress(name string) unsafe.Pointer {
+	name_ := C.CString(name)
+	defer C.free_string(name_)
+	return C.al_get_opengl_proc_address(name_)
+}
+
+// Make the OpenGL context associated with the given display current for the
+// calling thread. If there is a current target bitmap which belongs to a
+// different OpenGL context, the target bitmap will be changed to NULL.
+//
+// Normally you do not need to use this function, as the context will be made
+// current when you call al_set_target_bitmap or al_set_target_backbuffer. You
+// might need it if you created an OpenGL "forward compatible" context. Then
+// al_get_backbuffer only returns NULL, so it would not work to pass that to
+// al_set_target_bitmap.
+//
+// See https://liballeg.org/a5docs/5.2.6/opengl.html#al_set_current_opengl_context
+func SetCurrentContext(d *allegro.Display) {
+	C.al_set_current_opengl_context((*C.ALLEGRO_DISPLAY)(unsafe.Pointer(d)))
+}
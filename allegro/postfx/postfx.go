@@ -0,0 +1,256 @@
+// Package postfx runs a rendered scene through a chain of full-screen
+// effects before it reaches the display.
+//
+// The scene is drawn into an offscreen bitmap, then each pass draws the
+// output of the previous one into one of two reusable targets, and the final
+// result is drawn onto the display's backbuffer:
+//
+//	chain, err := postfx.New(display, 0, 0)
+//	...
+//	chain.Passes = append(chain.Passes, &postfx.ShaderPass{Shader: crt})
+//	...
+//	case allegro.DisplayResizeEvent:
+//		if _, err := chain.HandleEvent(e); err != nil {
+//			...
+//		}
+//	...
+//	chain.Render(func() {
+//		// draw the scene
+//	})
+//	allegro.FlipDisplay()
+package postfx
+
+import (
+	"errors"
+	"image"
+
+	"github.com/dradtke/go-allegro/allegro"
+)
+
+// replace overwrites the destination, so that passes don't blend with the
+// previous contents of a reused target.
+var replace = allegro.BlendMode{
+	Op: allegro.ADD, Src: allegro.ONE, Dst: allegro.ZERO,
+	AlphaOp: allegro.ADD, AlphaSrc: allegro.ONE, AlphaDst: allegro.ZERO,
+}
+
+// Pass is a single effect in a chain.
+type Pass interface {
+	// Apply draws src, transformed by the effect, onto the target bitmap,
+	// which has the same size as src. Blending is set to replace the
+	// target's contents.
+	Apply(src *allegro.Bitmap) error
+}
+
+// PassFunc adapts an ordinary function into a Pass.
+type PassFunc func(src *allegro.Bitmap) error
+
+// Apply calls f(src).
+func (f PassFunc) Apply(src *allegro.Bitmap) error {
+	return f(src)
+}
+
+// ShaderPass draws its source through a shader.
+type ShaderPass struct {
+	Shader *allegro.Shader
+
+	// Uniforms, if set, is called with the shader in use so that it can
+	// set the shader's uniforms.
+	Uniforms func() error
+}
+
+// Apply draws src through the pass's shader.
+func (p *ShaderPass) Apply(src *allegro.Bitmap) error {
+	var err error
+	useErr := allegro.WithShader(p.Shader, func() {
+		if p.Uniforms != nil {
+			if err = p.Uniforms(); err != nil {
+				return
+			}
+		}
+		src.Draw(0, 0, allegro.FLIP_NONE)
+	})
+	if useErr != nil {
+		return useErr
+	}
+	return err
+}
+
+// CPUPass runs an effect on the CPU. The source is copied into an image, Func
+// fills in the destination image, and the result is copied back. This is
+// best suited to memory bitmaps; video bitmaps work, but are slow to lock.
+type CPUPass struct {
+	// Func computes dst from src. Both images are the size of the chain's
+	// targets, and dst is not cleared between frames.
+	Func func(dst, src *image.RGBA)
+
+	src, dst *image.RGBA
+}
+
+// Apply copies src into memory, runs Func, and writes the result to the
+// target bitmap.
+func (p *CPUPass) Apply(src *allegro.Bitmap) error {
+	target := allegro.TargetBitmap()
+	w, h := src.Width(), src.Height()
+	if p.src == nil || p.src.Rect.Dx() != w || p.src.Rect.Dy() != h {
+		p.src = image.NewRGBA(image.Rect(0, 0, w, h))
+		p.dst = image.NewRGBA(image.Rect(0, 0, w, h))
+	}
+
+	reg, err := src.Lock(allegro.PIXEL_FORMAT_ABGR_8888_LE, allegro.LOCK_READONLY)
+	if err != nil {
+		return err
+	}
+	for y := 0; y < h; y++ {
+		copy(p.src.Pix[y*p.src.Stride:], reg.Row(y, w))
+	}
+	src.Unlock()
+
+	p.Func(p.dst, p.src)
+
+	reg, err = target.Lock(allegro.PIXEL_FORMAT_ABGR_8888_LE, allegro.LOCK_WRITEONLY)
+	if err != nil {
+		return err
+	}
+	for y := 0; y < h; y++ {
+		copy(reg.Row(y, w), p.dst.Pix[y*p.dst.Stride:])
+	}
+	target.Unlock()
+	return nil
+}
+
+// Chain renders a scene through a list of passes.
+type Chain struct {
+	// Passes are applied in order. Passes can be added, removed or
+	// reordered between frames.
+	Passes []Pass
+
+	display       *allegro.Display
+	scene         *allegro.Bitmap
+	targets       [2]*allegro.Bitmap
+	width, height int
+	fixed         bool
+}
+
+// New creates a chain for a display. If width and height are zero, the
+// chain's bitmaps match the display's size and follow it as it is resized;
+// otherwise they keep the given size and the result is scaled to fit the
+// display, keeping its aspect ratio, with black bars to fill the rest.
+// Bitmaps are created with the current new bitmap flags.
+func New(display *allegro.Display, width, height int) (*Chain, error) {
+	c := &Chain{display: display, fixed: width > 0 && height > 0}
+	if !c.fixed {
+		width, height = display.Width(), display.Height()
+	}
+	if err := c.Resize(width, height); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Scene returns the bitmap that the scene is drawn into.
+func (c *Chain) Scene() *allegro.Bitmap {
+	return c.scene
+}
+
+// Resize recreates the chain's bitmaps at a new size.
+func (c *Chain) Resize(width, height int) error {
+	if width <= 0 || height <= 0 {
+		return errors.New("invalid post-processing size")
+	}
+	c.destroy()
+	c.scene = allegro.CreateBitmap(width, height)
+	c.targets[0] = allegro.CreateBitmap(width, height)
+	c.targets[1] = allegro.CreateBitmap(width, height)
+	if c.scene == nil || c.targets[0] == nil || c.targets[1] == nil {
+		c.destroy()
+		return errors.New("failed to create post-processing targets")
+	}
+	c.width, c.height = width, height
+	return nil
+}
+
+// HandleEvent acknowledges a resize event for the chain's display and, unless
+// the chain has a fixed size, recreates its bitmaps to match. It reports
+// whether the event was handled, and any error from recreating the bitmaps;
+// other events are ignored.
+func (c *Chain) HandleEvent(event interface{}) (bool, error) {
+	e, ok := event.(allegro.DisplayResizeEvent)
+	if !ok || e.Source() != c.display {
+		return false, nil
+	}
+	c.display.AcknowledgeResize()
+	if !c.fixed {
+		return true, c.Resize(c.display.Width(), c.display.Height())
+	}
+	return true, nil
+}
+
+// Render calls draw with the scene bitmap as the target, runs the result
+// through each pass, and draws the output onto the display's backbuffer.
+// Rendering stops at the first pass that returns an error, and nothing is
+// drawn to the backbuffer.
+func (c *Chain) Render(draw func()) error {
+	if c.scene == nil {
+		return allegro.BitmapIsNull
+	}
+	c.scene.AsTarget(draw)
+
+	src := c.scene
+	for i, p := range c.Passes {
+		dst := c.targets[i%2]
+		var err error
+		dst.AsTarget(func() {
+			allegro.WithBlendMode(replace, func() {
+				err = p.Apply(src)
+			})
+		})
+		if err != nil {
+			return err
+		}
+		src = dst
+	}
+
+	c.display.Backbuffer().AsTarget(func() {
+		// The result covers the display on its own terms, whatever
+		// transform was left on the backbuffer.
+		allegro.WithState(allegro.STATE_TRANSFORM, func() {
+			allegro.UseTransform(allegro.IdentityTransform())
+			dw, dh := float32(c.display.Width()), float32(c.display.Height())
+			x, y, w, h := fit(float32(c.width), float32(c.height), dw, dh)
+			if x > 0 || y > 0 {
+				allegro.ClearToColor(allegro.MapRGB(0, 0, 0))
+			}
+			allegro.WithBlendMode(replace, func() {
+				src.DrawScaled(0, 0, float32(c.width), float32(c.height), x, y, w, h, allegro.FLIP_NONE)
+			})
+		})
+	})
+	return nil
+}
+
+// fit returns the largest rectangle with the aspect ratio of a w x h image
+// that fits in a dw x dh area, centred so that any bars on either side are
+// the same size.
+func fit(w, h, dw, dh float32) (x, y, fw, fh float32) {
+	scale := dw / w
+	if s := dh / h; s < scale {
+		scale = s
+	}
+	fw, fh = w*scale, h*scale
+	return (dw - fw) / 2, (dh - fh) / 2, fw, fh
+}
+
+// Destroy frees the chain's bitmaps.
+func (c *Chain) Destroy() {
+	c.destroy()
+}
+
+func (c *Chain) destroy() {
+	for _, bmp := range []*allegro.Bitmap{c.scene, c.targets[0], c.targets[1]} {
+		if bmp != nil {
+			bmp.Destroy()
+		}
+	}
+	c.scene, c.targets = nil, [2]*allegro.Bitmap{}
+}
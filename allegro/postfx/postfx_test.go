@@ -0,0 +1,19 @@
+package postfx
+
+import "testing"
+
+func TestFit(t *testing.T) {
+	for _, c := range []struct {
+		w, h, dw, dh float32
+		x, y, fw, fh float32
+	}{
+		{320, 180, 640, 360, 0, 0, 640, 360},
+		{320, 180, 800, 600, 0, 75, 800, 450},
+		{320, 180, 1000, 360, 180, 0, 640, 360},
+	} {
+		x, y, fw, fh := fit(c.w, c.h, c.dw, c.dh)
+		if x != c.x || y != c.y || fw != c.fw || fh != c.fh {
+			t.Errorf("%vx%v in %vx%v gave (%v, %v, %v, %v)", c.w, c.h, c.dw, c.dh, x, y, fw, fh)
+		}
+	}
+}
@@ -0,0 +1,245 @@
+package primitives
+
+// #include <allegro5/allegro.h>
+// #include <allegro5/allegro_primitives.h>
+/*
+// Looks up everything the sprite batch needs to know about a bitmap in a
+// single call.
+static void bitmap_info(ALLEGRO_BITMAP *bmp, ALLEGRO_BITMAP **root, int *x, int *y, int *w, int *h) {
+	ALLEGRO_BITMAP *parent = al_get_parent_bitmap(bmp);
+	*root = parent ? parent : bmp;
+	*x = al_get_bitmap_x(bmp);
+	*y = al_get_bitmap_y(bmp);
+	*w = al_get_bitmap_width(bmp);
+	*h = al_get_bitmap_height(bmp);
+}
+*/
+import "C"
+import (
+	"math"
+	"sort"
+	"unsafe"
+
+	"github.com/dradtke/go-allegro/allegro"
+)
+
+// Sprite is a single draw command queued in a SpriteBatch. Its fields mirror
+// the parameters of Bitmap.DrawTintedScaledRotatedRegion.
+type Sprite struct {
+	// Bitmap is the bitmap to draw, which may be a sub-bitmap.
+	Bitmap *allegro.Bitmap
+
+	// SX, SY, SW and SH select a region of Bitmap. If SW and SH are both
+	// zero, the whole bitmap is drawn.
+	SX, SY, SW, SH float32
+
+	// Tint is multiplied with the bitmap's colors. The zero value draws the
+	// bitmap untinted.
+	Tint allegro.Color
+
+	// CX and CY are the pivot point within the region, and DX and DY the
+	// position it is drawn at.
+	CX, CY, DX, DY float32
+
+	// ScaleX and ScaleY scale the region around the pivot. Zero is treated
+	// as 1.
+	ScaleX, ScaleY float32
+
+	// Angle rotates the region clockwise around the pivot, in radians.
+	Angle float32
+
+	Flags allegro.DrawFlags
+
+	// Transform, if set, is applied after the sprite is positioned, in
+	// addition to the target bitmap's current transform.
+	Transform *allegro.Transform
+
+	// Layer orders sprites within a batch; lower layers are drawn first.
+	Layer int
+}
+
+type bitmapInfo struct {
+	root       *C.ALLEGRO_BITMAP
+	x, y, w, h float32
+}
+
+// SpriteBatch collects sprites and draws them with as few calls into Allegro
+// as possible. On Flush, sprites are sorted by layer and then by texture, and
+// every run of sprites sharing a parent bitmap is drawn with a single call to
+// al_draw_prim.
+//
+// Sorting by texture means sprites on the same layer are not necessarily
+// drawn in the order they were added; use distinct layers where overlap
+// order matters.
+type SpriteBatch struct {
+	sprites  []Sprite
+	vertices []C.ALLEGRO_VERTEX
+	info     map[*allegro.Bitmap]bitmapInfo
+}
+
+// NewSpriteBatch returns an empty sprite batch.
+func NewSpriteBatch() *SpriteBatch {
+	return &SpriteBatch{info: make(map[*allegro.Bitmap]bitmapInfo)}
+}
+
+// Add queues a sprite to be drawn on the next Flush.
+func (b *SpriteBatch) Add(s Sprite) {
+	if s.Bitmap == nil {
+		return
+	}
+	b.sprites = append(b.sprites, s)
+}
+
+// Len returns the number of queued sprites.
+func (b *SpriteBatch) Len() int {
+	return len(b.sprites)
+}
+
+// Reset discards all queued sprites without drawing them.
+func (b *SpriteBatch) Reset() {
+	b.sprites = b.sprites[:0]
+	for bmp := range b.info {
+		delete(b.info, bmp)
+	}
+}
+
+func (b *SpriteBatch) lookup(bmp *allegro.Bitmap) bitmapInfo {
+	if b.info == nil {
+		b.info = make(map[*allegro.Bitmap]bitmapInfo)
+	}
+	if info, ok := b.info[bmp]; ok {
+		return info
+	}
+	var (
+		root       *C.ALLEGRO_BITMAP
+		x, y, w, h C.int
+	)
+	C.bitmap_info((*C.ALLEGRO_BITMAP)(unsafe.Pointer(bmp)), &root, &x, &y, &w, &h)
+	info := bitmapInfo{root, float32(x), float32(y), float32(w), float32(h)}
+	b.info[bmp] = info
+	return info
+}
+
+// Flush draws every queued sprite to the target bitmap and empties the batch.
+// It returns the number of draw calls that were made.
+func (b *SpriteBatch) Flush() int {
+	if len(b.sprites) == 0 {
+		return 0
+	}
+	defer b.Reset()
+
+	for i := range b.sprites {
+		b.lookup(b.sprites[i].Bitmap)
+	}
+	sort.SliceStable(b.sprites, func(i, j int) bool {
+		si, sj := &b.sprites[i], &b.sprites[j]
+		if si.Layer != sj.Layer {
+			return si.Layer < sj.Layer
+		}
+		return uintptr(unsafe.Pointer(b.info[si.Bitmap].root)) < uintptr(unsafe.Pointer(b.info[sj.Bitmap].root))
+	})
+
+	if cap(b.vertices) < len(b.sprites)*6 {
+		b.vertices = make([]C.ALLEGRO_VERTEX, 0, len(b.sprites)*6)
+	}
+
+	calls := 0
+	for start := 0; start < len(b.sprites); {
+		texture := b.info[b.sprites[start].Bitmap].root
+		layer := b.sprites[start].Layer
+		b.vertices = b.vertices[:0]
+		end := start
+		for ; end < len(b.sprites); end++ {
+			s := &b.sprites[end]
+			info := b.info[s.Bitmap]
+			if info.root != texture || s.Layer != layer {
+				break
+			}
+			b.vertices = appendSprite(b.vertices, s, info)
+		}
+		drawRun(spriteRun{texture, b.vertices})
+		calls++
+		start = end
+	}
+	return calls
+}
+
+// spriteRun is a run of sprites' triangles that share a texture.
+type spriteRun struct {
+	texture  *C.ALLEGRO_BITMAP
+	vertices []C.ALLEGRO_VERTEX
+}
+
+// drawRun draws a run with a single call to al_draw_prim. It is a variable
+// so that tests can see what Flush emits.
+var drawRun = func(r spriteRun) {
+	C.al_draw_prim(
+		unsafe.Pointer(&r.vertices[0]),
+		nil,
+		r.texture,
+		0,
+		C.int(len(r.vertices)),
+		C.ALLEGRO_PRIM_TRIANGLE_LIST,
+	)
+}
+
+var zeroColor allegro.Color
+
+// appendSprite appends the two triangles making up a sprite.
+func appendSprite(v []C.ALLEGRO_VERTEX, s *Sprite, info bitmapInfo) []C.ALLEGRO_VERTEX {
+	sx, sy, sw, sh := s.SX, s.SY, s.SW, s.SH
+	if sw == 0 && sh == 0 {
+		sx, sy, sw, sh = 0, 0, info.w, info.h
+	}
+	scaleX, scaleY := s.ScaleX, s.ScaleY
+	if scaleX == 0 {
+		scaleX = 1
+	}
+	if scaleY == 0 {
+		scaleY = 1
+	}
+	tint := s.Tint
+	if tint == zeroColor {
+		tint = allegro.MapRGBAf(1, 1, 1, 1)
+	}
+	color := col(tint)
+
+	// Texture coordinates are in pixels of the root bitmap.
+	u0, v0 := info.x+sx, info.y+sy
+	u1, v1 := u0+sw, v0+sh
+	if s.Flags&allegro.FLIP_HORIZONTAL != 0 {
+		u0, u1 = u1, u0
+	}
+	if s.Flags&allegro.FLIP_VERTICAL != 0 {
+		v0, v1 = v1, v0
+	}
+
+	sin, cos := math.Sincos(float64(s.Angle))
+	var m *C.ALLEGRO_TRANSFORM
+	if s.Transform != nil {
+		m = (*C.ALLEGRO_TRANSFORM)(unsafe.Pointer(s.Transform))
+	}
+	corner := func(lx, ly, u, vv float32) C.ALLEGRO_VERTEX {
+		x := float64((lx - s.CX) * scaleX)
+		y := float64((ly - s.CY) * scaleY)
+		px := float32(x*cos-y*sin) + s.DX
+		py := float32(x*sin+y*cos) + s.DY
+		if m != nil {
+			px, py = px*float32(m.m[0][0])+py*float32(m.m[1][0])+float32(m.m[3][0]),
+				px*float32(m.m[0][1])+py*float32(m.m[1][1])+float32(m.m[3][1])
+		}
+		return C.ALLEGRO_VERTEX{
+			x:     C.float(px),
+			y:     C.float(py),
+			color: color,
+			u:     C.float(u),
+			v:     C.float(vv),
+		}
+	}
+
+	tl := corner(0, 0, u0, v0)
+	tr := corner(sw, 0, u1, v0)
+	br := corner(sw, sh, u1, v1)
+	bl := corner(0, sh, u0, v1)
+	return append(v, tl, tr, br, tl, br, bl)
+}
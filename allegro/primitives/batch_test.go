@@ -0,0 +1,103 @@
+package primitives
+
+import (
+	"runtime"
+	"testing"
+	"unsafe"
+
+	"github.com/dradtke/go-allegro/allegro"
+)
+
+func TestSpriteBatchFlush(t *testing.T) {
+	var runs []spriteRun
+	defer func(draw func(spriteRun)) { drawRun = draw }(drawRun)
+	drawRun = func(r spriteRun) {
+		// Flush reuses its vertex buffer, so keep a copy.
+		r.vertices = append(r.vertices[:0:0], r.vertices...)
+		runs = append(runs, r)
+	}
+
+	var (
+		page, other *allegro.Bitmap
+		calls, left int
+		err         error
+	)
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	allegro.Run(func() {
+		allegro.SetNewBitmapFlags(allegro.MEMORY_BITMAP)
+		page = allegro.CreateBitmap(64, 64)
+		defer page.Destroy()
+		other = allegro.CreateBitmap(8, 8)
+		defer other.Destroy()
+		var small, large *allegro.Bitmap
+		if small, err = page.CreateSubBitmap(16, 8, 8, 8); err != nil {
+			return
+		}
+		defer small.Destroy()
+		if large, err = page.CreateSubBitmap(32, 0, 16, 16); err != nil {
+			return
+		}
+		defer large.Destroy()
+
+		b := NewSpriteBatch()
+		b.Add(Sprite{Bitmap: other, Layer: 1})
+		b.Add(Sprite{Bitmap: small, DX: 10, DY: 20, Flags: allegro.FLIP_HORIZONTAL})
+		b.Add(Sprite{Bitmap: other})
+		b.Add(Sprite{Bitmap: large, SX: 4, SY: 4, SW: 8, SH: 8, Tint: allegro.MapRGBAf(0.5, 0.5, 0.5, 1)})
+		b.Add(Sprite{Bitmap: page, Layer: 1})
+		calls = b.Flush()
+		left = b.Len()
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Each layer has a run for each root bitmap, in whichever order the
+	// roots sort in.
+	if calls != 4 || len(runs) != 4 || left != 0 {
+		t.Fatalf("made %d calls, emitted %d runs, and left %d sprites", calls, len(runs), left)
+	}
+	texture := func(r spriteRun) unsafe.Pointer { return unsafe.Pointer(r.texture) }
+	for _, layer := range [][]spriteRun{runs[:2], runs[2:]} {
+		a, b := texture(layer[0]), texture(layer[1])
+		if !(a == unsafe.Pointer(page) && b == unsafe.Pointer(other) || a == unsafe.Pointer(other) && b == unsafe.Pointer(page)) {
+			t.Errorf("layer was drawn with textures %p and %p", a, b)
+		}
+	}
+	pageRun := runs[0]
+	if texture(pageRun) != unsafe.Pointer(page) {
+		pageRun = runs[1]
+	}
+	for i, r := range runs {
+		want := 6
+		if i < 2 && texture(r) == unsafe.Pointer(page) {
+			want = 12
+		}
+		if len(r.vertices) != want {
+			t.Errorf("run %d has %d vertices, not %d", i, len(r.vertices), want)
+		}
+	}
+	if len(pageRun.vertices) != 12 {
+		return
+	}
+
+	v := pageRun.vertices
+	check := func(i int, x, y, u, vv, c float32) {
+		got := v[i]
+		if float32(got.x) != x || float32(got.y) != y || float32(got.u) != u || float32(got.v) != vv || float32(got.color.r) != c || float32(got.color.a) != 1 {
+			t.Errorf("vertex %d is %+v", i, got)
+		}
+	}
+	// The small sub-bitmap is flipped, so its texture coordinates run from
+	// right to left, and its zero tint is drawn as white.
+	check(0, 10, 20, 24, 8, 1)
+	check(1, 18, 20, 16, 8, 1)
+	check(2, 18, 28, 16, 16, 1)
+	check(5, 10, 28, 24, 16, 1)
+	// The large sub-bitmap's region is offset by its position in the page.
+	check(6, 0, 0, 36, 4, 0.5)
+	check(7, 8, 0, 44, 4, 0.5)
+	check(8, 8, 8, 44, 12, 0.5)
+	check(11, 0, 8, 36, 12, 0.5)
+}
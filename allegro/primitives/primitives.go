@@ -13,6 +13,31 @@ extern void trangulage_polygon_callback(int x, int y, int z, void *userdata);
 // This is a workaround for a compilation type error.
 // See https://github.com/golang/go/issues/19835
 typedef void (*emit_triangle_callback_t)(int, int, int, void*);
+
+typedef struct {
+	float x1, y1, x2, y2;
+	ALLEGRO_COLOR color;
+	float thickness;
+} line_draw;
+
+typedef struct {
+	float x1, y1, x2, y2;
+	ALLEGRO_COLOR color;
+} rect_draw;
+
+static void draw_lines(line_draw *lines, int n) {
+	int i;
+	for (i = 0; i < n; i++) {
+		al_draw_line(lines[i].x1, lines[i].y1, lines[i].x2, lines[i].y2, lines[i].color, lines[i].thickness);
+	}
+}
+
+static void draw_filled_rectangles(rect_draw *rects, int n) {
+	int i;
+	for (i = 0; i < n; i++) {
+		al_draw_filled_rectangle(rects[i].x1, rects[i].y1, rects[i].x2, rects[i].y2, rects[i].color);
+	}
+}
 */
 import "C"
 import (
@@ -52,6 +77,19 @@ type Point struct {
 
 type Polyline []Point
 
+// Line is a single line segment for DrawLines.
+type Line struct {
+	P1, P2    Point
+	Color     allegro.Color
+	Thickness float32
+}
+
+// Rect is a single rectangle for DrawFilledRectangles.
+type Rect struct {
+	P1, P2 Point
+	Color  allegro.Color
+}
+
 func (p Polyline) vertices() []C.float {
 	v := make([]C.float, 0, len(p)*2)
 	for _, point := range p {
@@ -69,7 +107,8 @@ type Vertex struct {
 	inited bool
 }
 
-func (v Vertex) init() {
+// init fills in v.raw. It needs a pointer receiver, or it fills in a copy.
+func (v *Vertex) init() {
 	if v.inited {
 		return
 	}
@@ -91,7 +130,8 @@ type VertexElement struct {
 	inited bool
 }
 
-func (v VertexElement) init() {
+// init fills in v.raw. It needs a pointer receiver, or it fills in a copy.
+func (v *VertexElement) init() {
 	if v.inited {
 		return
 	}
@@ -257,6 +297,45 @@ func DrawFilledRectangle(p1, p2 Point, color allegro.Color) {
 		col(color))
 }
 
+// DrawLines draws each line segment in turn, making a single call into
+// Allegro for the whole slice rather than one per line.
+func DrawLines(lines []Line) {
+	if len(lines) == 0 {
+		return
+	}
+	lines_ := make([]C.line_draw, len(lines))
+	for i, l := range lines {
+		lines_[i] = C.line_draw{
+			x1:        C.float(l.P1.X),
+			y1:        C.float(l.P1.Y),
+			x2:        C.float(l.P2.X),
+			y2:        C.float(l.P2.Y),
+			color:     col(l.Color),
+			thickness: C.float(l.Thickness),
+		}
+	}
+	C.draw_lines((*C.line_draw)(unsafe.Pointer(&lines_[0])), C.int(len(lines_)))
+}
+
+// DrawFilledRectangles draws each filled rectangle in turn, making a single
+// call into Allegro for the whole slice rather than one per rectangle.
+func DrawFilledRectangles(rects []Rect) {
+	if len(rects) == 0 {
+		return
+	}
+	rects_ := make([]C.rect_draw, len(rects))
+	for i, r := range rects {
+		rects_[i] = C.rect_draw{
+			x1:    C.float(r.P1.X),
+			y1:    C.float(r.P1.Y),
+			x2:    C.float(r.P2.X),
+			y2:    C.float(r.P2.Y),
+			color: col(r.Color),
+		}
+	}
+	C.draw_filled_rectangles((*C.rect_draw)(unsafe.Pointer(&rects_[0])), C.int(len(rects_)))
+}
+
 // Draws an outlined rounded rectangle.
 //
 // See https://liballeg.org/a5docs/5.2.6/primitives.html#al_draw_rounded_rectangle
@@ -0,0 +1,96 @@
+package primitives
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/dradtke/go-allegro/allegro"
+)
+
+const benchDraws = 1000
+
+// benchDrawing runs f inside Allegro with the primitives addon installed and
+// a memory bitmap as the target.
+func benchDrawing(b *testing.B, f func()) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	allegro.Run(func() {
+		if err := Install(); err != nil {
+			b.Fatal(err)
+		}
+		defer Uninstall()
+		allegro.SetNewBitmapFlags(allegro.MEMORY_BITMAP)
+		target := allegro.CreateBitmap(256, 256)
+		defer target.Destroy()
+		allegro.SetTargetBitmap(target)
+		b.ResetTimer()
+		f()
+		b.StopTimer()
+		allegro.SetTargetBitmap(nil)
+	})
+}
+
+// TestVertexInit checks that the C copies of vertices and vertex elements
+// are filled in, which they weren't while init had a value receiver.
+func TestVertexInit(t *testing.T) {
+	raw := cVertices([]Vertex{{X: 1, Y: 2, Z: 3, U: 4, V: 5}})
+	if v := raw[0]; v.x != 1 || v.y != 2 || v.z != 3 || v.u != 4 || v.v != 5 {
+		t.Errorf("vertex was converted to %+v", v)
+	}
+
+	e := VertexElement{Attribute: PRIM_POSITION, Storage: PRIM_FLOAT_2, Offset: 8}
+	e.init()
+	if int(e.raw.attribute) != int(PRIM_POSITION) || int(e.raw.storage) != int(PRIM_FLOAT_2) || e.raw.offset != 8 {
+		t.Errorf("vertex element was converted to %+v", e.raw)
+	}
+}
+
+func BenchmarkDrawLine(b *testing.B) {
+	benchDrawing(b, func() {
+		color := allegro.MapRGB(0xFF, 0, 0)
+		for n := 0; n < b.N; n++ {
+			for i := 0; i < benchDraws; i++ {
+				DrawLine(Point{0, float32(i % 256)}, Point{255, float32(i % 256)}, color, 1)
+			}
+		}
+	})
+}
+
+func BenchmarkDrawLines(b *testing.B) {
+	benchDrawing(b, func() {
+		color := allegro.MapRGB(0xFF, 0, 0)
+		lines := make([]Line, benchDraws)
+		for i := range lines {
+			lines[i] = Line{Point{0, float32(i % 256)}, Point{255, float32(i % 256)}, color, 1}
+		}
+		for n := 0; n < b.N; n++ {
+			DrawLines(lines)
+		}
+	})
+}
+
+func BenchmarkDrawFilledRectangle(b *testing.B) {
+	benchDrawing(b, func() {
+		color := allegro.MapRGB(0, 0xFF, 0)
+		for n := 0; n < b.N; n++ {
+			for i := 0; i < benchDraws; i++ {
+				x, y := float32(i%248), float32(i/4%248)
+				DrawFilledRectangle(Point{x, y}, Point{x + 8, y + 8}, color)
+			}
+		}
+	})
+}
+
+func BenchmarkDrawFilledRectangles(b *testing.B) {
+	benchDrawing(b, func() {
+		color := allegro.MapRGB(0, 0xFF, 0)
+		rects := make([]Rect, benchDraws)
+		for i := range rects {
+			x, y := float32(i%248), float32(i/4%248)
+			rects[i] = Rect{Point{x, y}, Point{x + 8, y + 8}, color}
+		}
+		for n := 0; n < b.N; n++ {
+			DrawFilledRectangles(rects)
+		}
+	})
+}
@@ -0,0 +1,350 @@
+package allegro
+
+// #include <allegro5/allegro.h>
+import "C"
+import (
+	"errors"
+	"fmt"
+	"unsafe"
+)
+
+// An ALLEGRO_SHADER is a program that runs on the GPU. It combines both a
+// vertex and a pixel shader. (In OpenGL terms, an ALLEGRO_SHADER is actually
+// a program which has one or more shaders attached. This can be confusing.)
+type Shader C.ALLEGRO_SHADER
+
+type ShaderType int
+
+const (
+	VERTEX_SHADER ShaderType = C.ALLEGRO_VERTEX_SHADER
+	PIXEL_SHADER             = C.ALLEGRO_PIXEL_SHADER
+)
+
+type ShaderPlatform int
+
+const (
+	SHADER_AUTO ShaderPlatform = C.ALLEGRO_SHADER_AUTO
+	SHADER_GLSL                = C.ALLEGRO_SHADER_GLSL
+	SHADER_HLSL                = C.ALLEGRO_SHADER_HLSL
+)
+
+// Names of the variables used by Allegro's default shaders. Shaders that
+// replace the defaults should use the same names so that Allegro can set
+// them.
+const (
+	SHADER_VAR_COLOR           = "al_color"
+	SHADER_VAR_POS             = "al_pos"
+	SHADER_VAR_PROJVIEW_MATRIX = "al_projview_matrix"
+	SHADER_VAR_TEX             = "al_tex"
+	SHADER_VAR_TEXCOORD        = "al_texcoord"
+	SHADER_VAR_TEX_MATRIX      = "al_tex_matrix"
+	SHADER_VAR_USER_ATTR       = "al_user_attr_"
+	SHADER_VAR_USE_TEX         = "al_use_tex"
+	SHADER_VAR_USE_TEX_MATRIX  = "al_use_tex_matrix"
+)
+
+// Create a shader object.
+//
+// The platform argument is one of the ALLEGRO_SHADER_PLATFORM values, and
+// specifies the type of shader object to create, and which language is used
+// to program the shader.
+//
+// The shader platform must be compatible with the type of display that you
+// will use the shader with. For example, you cannot create and use a HLSL
+// shader on an OpenGL display, nor a GLSL shader on a Direct3D display.
+//
+// The ALLEGRO_SHADER_AUTO value automatically chooses the appropriate
+// platform for the display currently targeted by the calling thread; there
+// must be such a display. It will create a GLSL shader for an OpenGL
+// display, and a HLSL shader for a Direct3D display.
+//
+// See https://liballeg.org/a5docs/5.2.6/shader.html#al_create_shader
+func CreateShader(platform ShaderPlatform) (*Shader, error) {
+	shader := C.al_create_shader(C.ALLEGRO_SHADER_PLATFORM(platform))
+	if shader == nil {
+		return nil, errors.New("failed to create shader")
+	}
+	return (*Shader)(shader), nil
+}
+
+// NewShader creates a shader for the current display from vertex and pixel
+// shader sources and builds it. An empty source is replaced by Allegro's
+// default for that stage.
+func NewShader(vertexSource, pixelSource string) (*Shader, error) {
+	shader, err := CreateShader(SHADER_AUTO)
+	if err != nil {
+		return nil, err
+	}
+	platform := shader.Platform()
+	if vertexSource == "" {
+		vertexSource = DefaultShaderSource(platform, VERTEX_SHADER)
+	}
+	if pixelSource == "" {
+		pixelSource = DefaultShaderSource(platform, PIXEL_SHADER)
+	}
+	if err := shader.AttachSource(VERTEX_SHADER, vertexSource); err != nil {
+		shader.Destroy()
+		return nil, err
+	}
+	if err := shader.AttachSource(PIXEL_SHADER, pixelSource); err != nil {
+		shader.Destroy()
+		return nil, err
+	}
+	if err := shader.Build(); err != nil {
+		shader.Destroy()
+		return nil, err
+	}
+	return shader, nil
+}
+
+// Attaches the shader's source code to the shader object and compiles it.
+// Passing NULL deletes the underlying (OpenGL or DirectX) shader. See also
+// al_attach_shader_source_file if you prefer to obtain your shader source
+// from an external file.
+//
+// If you do not use ALLEGRO_PROGRAMMABLE_PIPELINE Allegro's default vertex
+// shader will still be used even if you attach a pixel shader with this
+// function.
+//
+// Returns true on success and false on error, in which case the error log is
+// updated. The error log can be retrieved with al_get_shader_log.
+//
+// See https://liballeg.org/a5docs/5.2.6/shader.html#al_attach_shader_source
+func (s *Shader) AttachSource(typ ShaderType, source string) error {
+	source_ := C.CString(source)
+	defer freeString(source_)
+	if !bool(C.al_attach_shader_source((*C.ALLEGRO_SHADER)(s), C.ALLEGRO_SHADER_TYPE(typ), source_)) {
+		return fmt.Errorf("failed to attach shader source: %s", s.Log())
+	}
+	return nil
+}
+
+// Like al_attach_shader_source but reads the source code for the shader from
+// the named file.
+//
+// Returns true on success and false on error, in which case the error log is
+// updated. The error log can be retrieved with al_get_shader_log.
+//
+// See https://liballeg.org/a5docs/5.2.6/shader.html#al_attach_shader_source_file
+func (s *Shader) AttachSourceFile(typ ShaderType, filename string) error {
+	filename_ := C.CString(filename)
+	defer freeString(filename_)
+	if !bool(C.al_attach_shader_source_file((*C.ALLEGRO_SHADER)(s), C.ALLEGRO_SHADER_TYPE(typ), filename_)) {
+		return fmt.Errorf("failed to attach shader source '%s': %s", filename, s.Log())
+	}
+	return nil
+}
+
+// This is required before the shader can be used with al_use_shader. It
+// should be called after successfully attaching the pixel and/or vertex
+// shaders with al_attach_shader_source or al_attach_shader_source_file.
+//
+// Returns true on success and false on error, in which case the error log is
+// updated. The error log can be retrieved with al_get_shader_log.
+//
+// See https://liballeg.org/a5docs/5.2.6/shader.html#al_build_shader
+func (s *Shader) Build() error {
+	if !bool(C.al_build_shader((*C.ALLEGRO_SHADER)(s))) {
+		return fmt.Errorf("failed to build shader: %s", s.Log())
+	}
+	return nil
+}
+
+// Return a read-only string containing the information log for a shader
+// program. The log is updated by certain functions, such as
+// al_attach_shader_source or al_build_shader when there is an error.
+//
+// This function never returns NULL.
+//
+// See https://liballeg.org/a5docs/5.2.6/shader.html#al_get_shader_log
+func (s *Shader) Log() string {
+	return C.GoString(C.al_get_shader_log((*C.ALLEGRO_SHADER)(s)))
+}
+
+// Returns the platform the shader was created with (either
+// ALLEGRO_SHADER_HLSL or ALLEGRO_SHADER_GLSL).
+//
+// See https://liballeg.org/a5docs/5.2.6/shader.html#al_get_shader_platform
+func (s *Shader) Platform() ShaderPlatform {
+	return ShaderPlatform(C.al_get_shader_platform((*C.ALLEGRO_SHADER)(s)))
+}
+
+// Destroy the shader object.
+//
+// See https://liballeg.org/a5docs/5.2.6/shader.html#al_destroy_shader
+func (s *Shader) Destroy() {
+	C.al_destroy_shader((*C.ALLEGRO_SHADER)(s))
+}
+
+// Uses the shader for subsequent drawing operations on the current target
+// bitmap. Pass NULL to stop using any shader on the current target bitmap.
+//
+// Returns true on success. Otherwise returns false, e.g. because the shader
+// is incompatible with the target bitmap.
+//
+// See https://liballeg.org/a5docs/5.2.6/shader.html#al_use_shader
+func UseShader(s *Shader) error {
+	if !bool(C.al_use_shader((*C.ALLEGRO_SHADER)(s))) {
+		return errors.New("failed to use shader")
+	}
+	return nil
+}
+
+// WithShader uses the shader for the drawing done by f, then switches back
+// to the shader that was in use before.
+func WithShader(s *Shader, f func()) error {
+	old := CurrentShader()
+	if err := UseShader(s); err != nil {
+		return err
+	}
+	defer UseShader(old)
+	f()
+	return nil
+}
+
+// Returns the shader that is used by the current target bitmap, or NULL if
+// there is no target bitmap or no shader is in use.
+//
+// See https://liballeg.org/a5docs/5.2.6/shader.html#al_get_current_shader
+func CurrentShader() *Shader {
+	return (*Shader)(C.al_get_current_shader())
+}
+
+// Sets a texture sampler uniform and texture unit of the current target
+// bitmap's shader. The given bitmap must be a video bitmap.
+//
+// Different samplers should use different units. The bitmap passed to
+// Allegro's drawing functions uses the 0th unit, so if you're planning on
+// using the al_tex variable in your pixel shader as well as another sampler,
+// set the other sampler to use a unit different from 0. With the primitives
+// addon, it is possible to free up the 0th unit by passing NULL as the
+// texture argument to the relevant drawing functions. In this case, you may
+// set a sampler to use the 0th unit and thus not use al_tex (the al_use_tex
+// variable will be set to false).
+//
+// See https://liballeg.org/a5docs/5.2.6/shader.html#al_set_shader_sampler
+func SetShaderSampler(name string, bmp *Bitmap, unit int) error {
+	name_ := C.CString(name)
+	defer freeString(name_)
+	if !bool(C.al_set_shader_sampler(name_, (*C.ALLEGRO_BITMAP)(bmp), C.int(unit))) {
+		return fmt.Errorf("failed to set shader sampler '%s'", name)
+	}
+	return nil
+}
+
+// Sets a matrix uniform of the current target bitmap's shader.
+//
+// See https://liballeg.org/a5docs/5.2.6/shader.html#al_set_shader_matrix
+func SetShaderMatrix(name string, matrix *Transform) error {
+	name_ := C.CString(name)
+	defer freeString(name_)
+	if !bool(C.al_set_shader_matrix(name_, (*C.ALLEGRO_TRANSFORM)(matrix))) {
+		return fmt.Errorf("failed to set shader matrix '%s'", name)
+	}
+	return nil
+}
+
+// Sets an integer uniform of the current target bitmap's shader.
+//
+// See https://liballeg.org/a5docs/5.2.6/shader.html#al_set_shader_int
+func SetShaderInt(name string, i int) error {
+	name_ := C.CString(name)
+	defer freeString(name_)
+	if !bool(C.al_set_shader_int(name_, C.int(i))) {
+		return fmt.Errorf("failed to set shader int '%s'", name)
+	}
+	return nil
+}
+
+// Sets a float uniform of the target bitmap's shader.
+//
+// See https://liballeg.org/a5docs/5.2.6/shader.html#al_set_shader_float
+func SetShaderFloat(name string, f float32) error {
+	name_ := C.CString(name)
+	defer freeString(name_)
+	if !bool(C.al_set_shader_float(name_, C.float(f))) {
+		return fmt.Errorf("failed to set shader float '%s'", name)
+	}
+	return nil
+}
+
+// Sets a boolean uniform of the target bitmap's shader.
+//
+// See https://liballeg.org/a5docs/5.2.6/shader.html#al_set_shader_bool
+func SetShaderBool(name string, b bool) error {
+	name_ := C.CString(name)
+	defer freeString(name_)
+	if !bool(C.al_set_shader_bool(name_, C.bool(b))) {
+		return fmt.Errorf("failed to set shader bool '%s'", name)
+	}
+	return nil
+}
+
+// Sets an integer vector array uniform of the current target bitmap's
+// shader. The 'num_components' parameter can take one of the values 1, 2, 3
+// or 4. If it is 1 then an array of 'num_elems' integer elements is added.
+// Otherwise each added array element is assumed to be a vector with 2, 3 or
+// 4 components in it.
+//
+// For example, if you have a GLSL uniform declared as uniform ivec3 flowers[4]
+// or an HLSL uniform declared as uniform int3 flowers[4], then you'd use this
+// function from your code like so:
+//
+//	flowers := []int32{
+//		1, 2, 3,
+//		4, 5, 6,
+//		7, 8, 9,
+//		2, 5, 7,
+//	}
+//	SetShaderIntVector("flowers", 3, flowers)
+//
+// See https://liballeg.org/a5docs/5.2.6/shader.html#al_set_shader_int_vector
+func SetShaderIntVector(name string, components int, values []int32) error {
+	if components < 1 || components > 4 || len(values)%components != 0 {
+		return fmt.Errorf("invalid shader vector '%s'", name)
+	}
+	if len(values) == 0 {
+		return nil
+	}
+	name_ := C.CString(name)
+	defer freeString(name_)
+	if !bool(C.al_set_shader_int_vector(name_, C.int(components), (*C.int)(unsafe.Pointer(&values[0])), C.int(len(values)/components))) {
+		return fmt.Errorf("failed to set shader int vector '%s'", name)
+	}
+	return nil
+}
+
+// Same as al_set_shader_int_vector except all values are float instead of
+// int.
+//
+// See https://liballeg.org/a5docs/5.2.6/shader.html#al_set_shader_float_vector
+func SetShaderFloatVector(name string, components int, values []float32) error {
+	if components < 1 || components > 4 || len(values)%components != 0 {
+		return fmt.Errorf("invalid shader vector '%s'", name)
+	}
+	if len(values) == 0 {
+		return nil
+	}
+	name_ := C.CString(name)
+	defer freeString(name_)
+	if !bool(C.al_set_shader_float_vector(name_, C.int(components), (*C.float)(unsafe.Pointer(&values[0])), C.int(len(values)/components))) {
+		return fmt.Errorf("failed to set shader float vector '%s'", name)
+	}
+	return nil
+}
+
+// Returns a string containing the source code to Allegro's default vertex or
+// pixel shader appropriate for the passed platform. The ALLEGRO_SHADER_AUTO
+// value means GLSL is used if OpenGL is being used otherwise HLSL.
+// ALLEGRO_SHADER_AUTO requires that there is a current display set on the
+// calling thread. This function can return NULL if Allegro was built without
+// support for shaders of the selected platform.
+//
+// See https://liballeg.org/a5docs/5.2.6/shader.html#al_get_default_shader_source
+func DefaultShaderSource(platform ShaderPlatform, typ ShaderType) string {
+	source := C.al_get_default_shader_source(C.ALLEGRO_SHADER_PLATFORM(platform), C.ALLEGRO_SHADER_TYPE(typ))
+	if source == nil {
+		return ""
+	}
+	return C.GoString(source)
+}
@@ -0,0 +1,131 @@
+package allegro_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/dradtke/go-allegro/allegro"
+	"github.com/dradtke/go-allegro/allegro/allegrotest"
+)
+
+// testPixelShader uses every uniform that TestShaderUniforms sets, since
+// unused uniforms are optimized away and can't be set.
+const testPixelShader = `
+#ifdef GL_ES
+precision mediump float;
+#endif
+varying vec4 varying_color;
+uniform float brightness;
+uniform int steps;
+uniform bool invert;
+uniform vec3 tint;
+
+void main() {
+	vec4 c = varying_color * brightness;
+	c.rgb = floor(c.rgb * float(steps)) / float(steps) * tint;
+	if (invert)
+		c.rgb = 1.0 - c.rgb;
+	gl_FragColor = c;
+}
+`
+
+// doWithShaders runs f on the Allegro thread, or skips the test if there is
+// no display or it doesn't support GLSL shaders.
+func doWithShaders(t *testing.T, f func()) {
+	allegrotest.RequireDisplay(t)
+	supported := false
+	allegrotest.Do(func() {
+		if shader, err := allegro.CreateShader(allegro.SHADER_GLSL); err == nil {
+			shader.Destroy()
+			supported = true
+			f()
+		}
+	})
+	if !supported {
+		t.Skip("display doesn't support GLSL shaders")
+	}
+}
+
+func TestShaderBuild(t *testing.T) {
+	var err error
+	doWithShaders(t, func() {
+		var shader *allegro.Shader
+		if shader, err = allegro.CreateShader(allegro.SHADER_GLSL); err != nil {
+			return
+		}
+		defer shader.Destroy()
+		if err = shader.AttachSource(allegro.VERTEX_SHADER, allegro.DefaultShaderSource(allegro.SHADER_GLSL, allegro.VERTEX_SHADER)); err != nil {
+			return
+		}
+		if err = shader.AttachSource(allegro.PIXEL_SHADER, testPixelShader); err != nil {
+			return
+		}
+		err = shader.Build()
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestShaderBuildError(t *testing.T) {
+	var attachErr, buildErr error
+	var log string
+	doWithShaders(t, func() {
+		shader, err := allegro.CreateShader(allegro.SHADER_GLSL)
+		if err != nil {
+			buildErr = err
+			return
+		}
+		defer shader.Destroy()
+		attachErr = shader.AttachSource(allegro.PIXEL_SHADER, "void main() { gl_FragColor = ; }")
+
+		// A pixel shader without main compiles, but can't be linked.
+		shader.AttachSource(allegro.VERTEX_SHADER, allegro.DefaultShaderSource(allegro.SHADER_GLSL, allegro.VERTEX_SHADER))
+		shader.AttachSource(allegro.PIXEL_SHADER, "uniform float unused;\n")
+		if buildErr = shader.Build(); buildErr != nil {
+			log = shader.Log()
+		}
+	})
+	if attachErr == nil {
+		t.Error("shader with a syntax error was attached")
+	}
+	if buildErr == nil {
+		t.Fatal("shader without main was built")
+	}
+	if log == "" || !strings.Contains(buildErr.Error(), log) {
+		t.Errorf("build error %q doesn't include the log %q", buildErr, log)
+	}
+}
+
+func TestShaderUniforms(t *testing.T) {
+	var errs []error
+	var missing error
+	doWithShaders(t, func() {
+		shader, err := allegro.NewShader("", testPixelShader)
+		if err != nil {
+			errs = append(errs, err)
+			return
+		}
+		defer shader.Destroy()
+		allegrotest.Target().AsTarget(func() {
+			err := allegro.WithShader(shader, func() {
+				errs = append(errs,
+					allegro.SetShaderFloat("brightness", 0.5),
+					allegro.SetShaderInt("steps", 4),
+					allegro.SetShaderBool("invert", true),
+					allegro.SetShaderFloatVector("tint", 3, []float32{1, 0.5, 0.25}),
+				)
+				missing = allegro.SetShaderFloat("missing", 1)
+			})
+			errs = append(errs, err)
+		})
+	})
+	for _, err := range errs {
+		if err != nil {
+			t.Error(err)
+		}
+	}
+	if missing == nil {
+		t.Error("set a uniform that the shader doesn't have")
+	}
+}
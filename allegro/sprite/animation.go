@@ -0,0 +1,220 @@
+// Package sprite provides frame-based sprite animation on top of sub-bitmaps.
+//
+// An Animation is shared, immutable data: a list of frames and how to step
+// through them. A Player holds the playback state for one use of an
+// animation, so many sprites can share the same Animation.
+//
+// Animations can be built by hand, cut from a grid-based sprite sheet, taken
+// from an atlas, or loaded from TexturePacker/Aseprite JSON exports and
+// animated GIFs.
+package sprite
+
+import (
+	"github.com/dradtke/go-allegro/allegro"
+)
+
+// Mode controls what happens when playback reaches the last frame.
+type Mode int
+
+const (
+	// Loop jumps back to the first frame.
+	Loop Mode = iota
+	// PingPong reverses direction at either end.
+	PingPong
+	// Once stops on the last frame.
+	Once
+)
+
+// Frame is a single frame of an animation.
+type Frame struct {
+	Bitmap *allegro.Bitmap
+
+	// Duration is how long the frame is shown, in seconds. Frames with a
+	// duration of zero or less are held until the player is reset.
+	Duration float64
+
+	// OffsetX and OffsetY position a trimmed frame within its original,
+	// untrimmed size, given by SourceW and SourceH. They are zero for
+	// frames that weren't trimmed.
+	OffsetX, OffsetY float32
+	SourceW, SourceH float32
+
+	// Event is an optional tag reported to Player.OnFrame when the frame is
+	// reached, e.g. "footstep".
+	Event string
+}
+
+// Animation is a named sequence of frames.
+type Animation struct {
+	Name   string
+	Frames []Frame
+	Mode   Mode
+}
+
+// NewAnimation returns an animation showing each bitmap for the same
+// duration, in seconds.
+func NewAnimation(name string, bitmaps []*allegro.Bitmap, duration float64, mode Mode) *Animation {
+	a := &Animation{Name: name, Frames: make([]Frame, len(bitmaps)), Mode: mode}
+	for i, bmp := range bitmaps {
+		a.Frames[i] = Frame{Bitmap: bmp, Duration: duration}
+	}
+	return a
+}
+
+// Duration returns the time taken to play every frame once, in seconds.
+func (a *Animation) Duration() float64 {
+	var total float64
+	for _, f := range a.Frames {
+		total += f.Duration
+	}
+	return total
+}
+
+// Player steps through an animation over time.
+type Player struct {
+	animation *Animation
+	frame     int
+	dir       int
+	elapsed   float64
+	done      bool
+
+	// Speed scales the passage of time; the zero value is treated as 1.
+	Speed float64
+
+	// OnFrame, if set, is called whenever playback moves to a new frame.
+	OnFrame func(index int, frame *Frame)
+
+	// OnLoop, if set, is called each time a Loop or PingPong animation
+	// wraps back around to its first frame.
+	OnLoop func()
+
+	// OnFinish, if set, is called when a Once animation reaches the end of
+	// its last frame.
+	OnFinish func()
+}
+
+// NewPlayer returns a player positioned at the first frame of a.
+func NewPlayer(a *Animation) *Player {
+	p := new(Player)
+	p.Play(a)
+	return p
+}
+
+// Play switches to a different animation and restarts playback. Playing the
+// animation that is already playing does nothing.
+func (p *Player) Play(a *Animation) {
+	if p.animation == a && a != nil {
+		return
+	}
+	p.animation = a
+	p.Reset()
+}
+
+// Reset restarts playback from the first frame.
+func (p *Player) Reset() {
+	p.frame, p.dir, p.elapsed, p.done = 0, 1, 0, false
+}
+
+// Animation returns the animation being played.
+func (p *Player) Animation() *Animation {
+	return p.animation
+}
+
+// Index returns the index of the current frame.
+func (p *Player) Index() int {
+	return p.frame
+}
+
+// Frame returns the current frame, or nil if there is nothing to play.
+func (p *Player) Frame() *Frame {
+	if p.animation == nil || len(p.animation.Frames) == 0 {
+		return nil
+	}
+	return &p.animation.Frames[p.frame]
+}
+
+// Bitmap returns the bitmap of the current frame.
+func (p *Player) Bitmap() *allegro.Bitmap {
+	if f := p.Frame(); f != nil {
+		return f.Bitmap
+	}
+	return nil
+}
+
+// Done reports whether a Once animation has finished.
+func (p *Player) Done() bool {
+	return p.done
+}
+
+// Update advances playback by dt seconds. When driving animations from a
+// timer, pass the timer's Speed() once per timer event.
+func (p *Player) Update(dt float64) {
+	if p.done || p.animation == nil || len(p.animation.Frames) == 0 {
+		return
+	}
+	if p.Speed != 0 {
+		dt *= p.Speed
+	}
+	p.elapsed += dt
+	for !p.done {
+		d := p.animation.Frames[p.frame].Duration
+		if d <= 0 || p.elapsed < d {
+			return
+		}
+		p.elapsed -= d
+		p.advance()
+	}
+}
+
+func (p *Player) advance() {
+	n := len(p.animation.Frames)
+	next := p.frame + p.dir
+	switch p.animation.Mode {
+	case Once:
+		if next >= n {
+			p.done, p.elapsed = true, 0
+			if p.OnFinish != nil {
+				p.OnFinish()
+			}
+			return
+		}
+	case PingPong:
+		if n == 1 {
+			next = 0
+		} else if next < 0 || next >= n {
+			p.dir = -p.dir
+			next = p.frame + p.dir
+		}
+		if next == 0 && p.OnLoop != nil {
+			p.OnLoop()
+		}
+	default:
+		if next >= n {
+			next = 0
+			if p.OnLoop != nil {
+				p.OnLoop()
+			}
+		}
+	}
+	p.frame = next
+	if p.OnFrame != nil {
+		p.OnFrame(p.frame, &p.animation.Frames[p.frame])
+	}
+}
+
+// Draw draws the current frame with its top-left corner (before trimming) at
+// dx, dy.
+func (p *Player) Draw(dx, dy float32, flags allegro.DrawFlags) {
+	f := p.Frame()
+	if f == nil || f.Bitmap == nil {
+		return
+	}
+	ox, oy := f.OffsetX, f.OffsetY
+	if f.SourceW > 0 && flags&allegro.FLIP_HORIZONTAL != 0 {
+		ox = f.SourceW - ox - float32(f.Bitmap.Width())
+	}
+	if f.SourceH > 0 && flags&allegro.FLIP_VERTICAL != 0 {
+		oy = f.SourceH - oy - float32(f.Bitmap.Height())
+	}
+	f.Bitmap.Draw(dx+ox, dy+oy, flags)
+}
@@ -0,0 +1,75 @@
+package sprite
+
+import (
+	"reflect"
+	"testing"
+)
+
+func frames(durations ...float64) []Frame {
+	f := make([]Frame, len(durations))
+	for i, d := range durations {
+		f[i] = Frame{Duration: d}
+	}
+	return f
+}
+
+func TestPlayerModes(t *testing.T) {
+	tests := []struct {
+		mode Mode
+		want []int
+	}{
+		{Loop, []int{1, 2, 0, 1, 2, 0}},
+		{PingPong, []int{1, 2, 1, 0, 1, 2}},
+		{Once, []int{1, 2, 2, 2, 2, 2}},
+	}
+	for _, test := range tests {
+		p := NewPlayer(&Animation{Frames: frames(1, 1, 1), Mode: test.mode})
+		var got []int
+		for i := 0; i < len(test.want); i++ {
+			p.Update(1)
+			got = append(got, p.Index())
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("mode %d: got %v, want %v", test.mode, got, test.want)
+		}
+	}
+}
+
+func TestPlayerVariableDurations(t *testing.T) {
+	p := NewPlayer(&Animation{Frames: frames(0.1, 0.5, 0.2)})
+	var events []int
+	p.OnFrame = func(index int, _ *Frame) { events = append(events, index) }
+	loops := 0
+	p.OnLoop = func() { loops++ }
+
+	p.Update(0.05)
+	if p.Index() != 0 {
+		t.Fatalf("index = %d, want 0", p.Index())
+	}
+	p.Update(0.1)
+	if p.Index() != 1 {
+		t.Fatalf("index = %d, want 1", p.Index())
+	}
+	// A large step passes through several frames at once.
+	p.Update(0.7)
+	if p.Index() != 0 || loops != 1 {
+		t.Fatalf("index = %d, loops = %d, want 0 and 1", p.Index(), loops)
+	}
+	if want := []int{1, 2, 0}; !reflect.DeepEqual(events, want) {
+		t.Fatalf("events = %v, want %v", events, want)
+	}
+}
+
+func TestPlayerOnce(t *testing.T) {
+	finished := 0
+	p := NewPlayer(&Animation{Frames: frames(1, 1), Mode: Once})
+	p.OnFinish = func() { finished++ }
+	p.Update(5)
+	if !p.Done() || finished != 1 || p.Index() != 1 {
+		t.Fatalf("done = %v, finished = %d, index = %d", p.Done(), finished, p.Index())
+	}
+	p.Reset()
+	if p.Done() || p.Index() != 0 {
+		t.Fatal("Reset should restart playback")
+	}
+}
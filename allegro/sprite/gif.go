@@ -0,0 +1,111 @@
+package sprite
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/gif"
+	"io"
+	"os"
+
+	"github.com/dradtke/go-allegro/allegro/atlas"
+)
+
+// LoadGIF loads an animated GIF into a sheet, packing its frames into an
+// atlas. The sheet holds a single animation named "" that loops unless the
+// GIF asks to be played once.
+func LoadGIF(filename string) (*Sheet, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	s, err := ReadGIF(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load GIF '%s': %s", filename, err)
+	}
+	return s, nil
+}
+
+// ReadGIF decodes an animated GIF from r into a sheet; see LoadGIF.
+func ReadGIF(r io.Reader) (*Sheet, error) {
+	g, err := gif.DecodeAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(g.Image) == 0 {
+		return nil, errors.New("GIF has no frames")
+	}
+	frames := compositeGIF(g)
+
+	b := atlas.NewBuilder(0, 0)
+	size := frames[0].Bounds().Size()
+	if size.X > b.PageWidth {
+		b.PageWidth = size.X
+	}
+	if size.Y > b.PageHeight {
+		b.PageHeight = size.Y
+	}
+	for i, frame := range frames {
+		if err := b.AddImage(fmt.Sprint(i), frame); err != nil {
+			return nil, err
+		}
+	}
+	a, err := b.Build()
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Sheet{Animations: make(map[string]*Animation), atlas: a}
+	for i := range frames {
+		bmp, _ := a.Bitmap(fmt.Sprint(i))
+		duration := float64(g.Delay[i]) / 100
+		if duration <= 0 {
+			duration = DefaultFrameDuration
+		}
+		s.Frames = append(s.Frames, Frame{Bitmap: bmp, Duration: duration})
+	}
+	mode := Loop
+	if g.LoopCount == -1 {
+		mode = Once
+	}
+	s.Animations[""] = &Animation{Frames: s.Frames, Mode: mode}
+	return s, nil
+}
+
+// compositeGIF renders each GIF frame onto a full-size canvas, honouring each
+// frame's disposal method.
+func compositeGIF(g *gif.GIF) []*image.RGBA {
+	bounds := image.Rect(0, 0, g.Config.Width, g.Config.Height)
+	if bounds.Empty() && len(g.Image) > 0 {
+		bounds = g.Image[0].Bounds()
+	}
+	canvas := image.NewRGBA(bounds)
+	frames := make([]*image.RGBA, len(g.Image))
+	for i, img := range g.Image {
+		var previous *image.RGBA
+		if g.Disposal != nil && g.Disposal[i] == gif.DisposalPrevious {
+			previous = clone(canvas)
+		}
+		draw.Draw(canvas, img.Bounds(), img, img.Bounds().Min, draw.Over)
+		frames[i] = clone(canvas)
+
+		if g.Disposal == nil {
+			continue
+		}
+		switch g.Disposal[i] {
+		case gif.DisposalBackground:
+			draw.Draw(canvas, img.Bounds(), image.Transparent, image.Point{}, draw.Src)
+		case gif.DisposalPrevious:
+			canvas = previous
+		}
+	}
+	return frames
+}
+
+func clone(img *image.RGBA) *image.RGBA {
+	c := image.NewRGBA(img.Bounds())
+	copy(c.Pix, img.Pix)
+	return c
+}
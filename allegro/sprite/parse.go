@@ -0,0 +1,211 @@
+package sprite
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultFrameDuration is used for sheet frames that don't specify their own
+// duration, in seconds.
+var DefaultFrameDuration = 0.1
+
+type sheetData struct {
+	image     string
+	frames    []frameData
+	sequences []sequence
+}
+
+type frameData struct {
+	name             string
+	x, y, w, h       int
+	offsetX, offsetY int
+	sourceW, sourceH int
+	duration         float64
+}
+
+type sequence struct {
+	name   string
+	mode   Mode
+	frames []int
+}
+
+type jsonRect struct {
+	X, Y, W, H int
+}
+
+type jsonFrame struct {
+	Filename         string   `json:"filename"`
+	Frame            jsonRect `json:"frame"`
+	Rotated          bool     `json:"rotated"`
+	Trimmed          bool     `json:"trimmed"`
+	SpriteSourceSize jsonRect `json:"spriteSourceSize"`
+	SourceSize       jsonRect `json:"sourceSize"`
+	Duration         *float64 `json:"duration"`
+}
+
+type jsonSheet struct {
+	Frames json.RawMessage `json:"frames"`
+	Meta   struct {
+		Image     string `json:"image"`
+		FrameTags []struct {
+			Name      string `json:"name"`
+			From      int    `json:"from"`
+			To        int    `json:"to"`
+			Direction string `json:"direction"`
+			Repeat    string `json:"repeat"`
+		} `json:"frameTags"`
+	} `json:"meta"`
+}
+
+// parseSheet decodes a TexturePacker or Aseprite JSON sheet.
+func parseSheet(r io.Reader) (*sheetData, error) {
+	var js jsonSheet
+	if err := json.NewDecoder(r).Decode(&js); err != nil {
+		return nil, err
+	}
+	frames, err := decodeFrames(js.Frames)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &sheetData{image: js.Meta.Image}
+	for _, f := range frames {
+		if f.Rotated {
+			return nil, fmt.Errorf("frame '%s' is rotated, which is not supported", f.Filename)
+		}
+		fd := frameData{
+			name:     f.Filename,
+			x:        f.Frame.X,
+			y:        f.Frame.Y,
+			w:        f.Frame.W,
+			h:        f.Frame.H,
+			duration: DefaultFrameDuration,
+		}
+		if f.Duration != nil {
+			// Aseprite stores durations in milliseconds.
+			fd.duration = *f.Duration / 1000
+		}
+		if f.Trimmed {
+			fd.offsetX, fd.offsetY = f.SpriteSourceSize.X, f.SpriteSourceSize.Y
+			fd.sourceW, fd.sourceH = f.SourceSize.W, f.SourceSize.H
+		}
+		d.frames = append(d.frames, fd)
+	}
+
+	if len(js.Meta.FrameTags) > 0 {
+		for _, tag := range js.Meta.FrameTags {
+			if tag.From < 0 || tag.To >= len(d.frames) || tag.From > tag.To {
+				return nil, fmt.Errorf("tag '%s' has invalid frame range %d-%d", tag.Name, tag.From, tag.To)
+			}
+			seq := sequence{name: tag.Name}
+			for i := tag.From; i <= tag.To; i++ {
+				seq.frames = append(seq.frames, i)
+			}
+			switch tag.Direction {
+			case "reverse":
+				reverse(seq.frames)
+			case "pingpong":
+				seq.mode = PingPong
+			case "pingpong_reverse":
+				reverse(seq.frames)
+				seq.mode = PingPong
+			}
+			if tag.Repeat == "1" {
+				seq.mode = Once
+			}
+			d.sequences = append(d.sequences, seq)
+		}
+		return d, nil
+	}
+
+	groups := make(map[string]int)
+	for i, f := range d.frames {
+		name := groupName(f.name)
+		if name == "" {
+			continue
+		}
+		g, ok := groups[name]
+		if !ok {
+			g = len(d.sequences)
+			groups[name] = g
+			d.sequences = append(d.sequences, sequence{name: name})
+		}
+		d.sequences[g].frames = append(d.sequences[g].frames, i)
+	}
+	// Hash keys aren't necessarily in frame order, so go by the numbers that
+	// groupName strips instead.
+	for _, seq := range d.sequences {
+		frames := seq.frames
+		sort.SliceStable(frames, func(i, j int) bool {
+			return frameNumber(d.frames[frames[i]].name) < frameNumber(d.frames[frames[j]].name)
+		})
+	}
+	return d, nil
+}
+
+// decodeFrames accepts both the array and hash forms of the frame list. The
+// hash form is decoded token by token so that frame order is preserved.
+func decodeFrames(raw json.RawMessage) ([]jsonFrame, error) {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 {
+		return nil, errors.New("missing frames")
+	}
+	if raw[0] == '[' {
+		var frames []jsonFrame
+		err := json.Unmarshal(raw, &frames)
+		return frames, err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+	var frames []jsonFrame
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		var f jsonFrame
+		if err := dec.Decode(&f); err != nil {
+			return nil, err
+		}
+		if f.Filename == "" {
+			f.Filename, _ = key.(string)
+		}
+		frames = append(frames, f)
+	}
+	return frames, nil
+}
+
+// groupName strips the extension and trailing frame number from a frame name.
+func groupName(name string) string {
+	name = strings.TrimSuffix(name, filepath.Ext(name))
+	name = strings.TrimRight(name, "0123456789")
+	return strings.TrimRight(name, " _-.")
+}
+
+// frameNumber returns the number at the end of a frame's name, or -1 if it
+// doesn't have one.
+func frameNumber(name string) int {
+	name = strings.TrimSuffix(name, filepath.Ext(name))
+	digits := name[len(strings.TrimRight(name, "0123456789")):]
+	n, err := strconv.Atoi(digits)
+	if err != nil {
+		return -1
+	}
+	return n
+}
+
+func reverse(xs []int) {
+	for i, j := 0, len(xs)-1; i < j; i, j = i+1, j-1 {
+		xs[i], xs[j] = xs[j], xs[i]
+	}
+}
@@ -0,0 +1,70 @@
+package sprite
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const texturePackerHash = `{
+	"frames": {
+		"walk_02.png": {"frame": {"x": 32, "y": 0, "w": 32, "h": 32}, "rotated": false, "trimmed": false},
+		"walk_01.png": {"frame": {"x": 0, "y": 0, "w": 32, "h": 32}, "rotated": false, "trimmed": false},
+		"idle.png": {"frame": {"x": 64, "y": 0, "w": 30, "h": 28}, "rotated": false, "trimmed": true,
+			"spriteSourceSize": {"x": 1, "y": 4, "w": 30, "h": 28}, "sourceSize": {"w": 32, "h": 32}}
+	},
+	"meta": {"image": "sheet.png"}
+}`
+
+const asepriteArray = `{
+	"frames": [
+		{"filename": "hero 0.aseprite", "frame": {"x": 0, "y": 0, "w": 16, "h": 16}, "duration": 100},
+		{"filename": "hero 1.aseprite", "frame": {"x": 16, "y": 0, "w": 16, "h": 16}, "duration": 250},
+		{"filename": "hero 2.aseprite", "frame": {"x": 32, "y": 0, "w": 16, "h": 16}, "duration": 100}
+	],
+	"meta": {
+		"image": "hero.png",
+		"frameTags": [
+			{"name": "run", "from": 0, "to": 2, "direction": "pingpong"},
+			{"name": "back", "from": 1, "to": 2, "direction": "reverse", "repeat": "1"}
+		]
+	}
+}`
+
+func TestParseTexturePackerHash(t *testing.T) {
+	d, err := parseSheet(strings.NewReader(texturePackerHash))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.image != "sheet.png" || len(d.frames) != 3 {
+		t.Fatalf("got image %q with %d frames", d.image, len(d.frames))
+	}
+	// Hash order must be preserved.
+	if d.frames[0].name != "walk_02.png" || d.frames[0].x != 32 {
+		t.Errorf("first frame = %+v", d.frames[0])
+	}
+	if f := d.frames[2]; f.offsetX != 1 || f.offsetY != 4 || f.sourceW != 32 || f.sourceH != 32 {
+		t.Errorf("trimmed frame = %+v", f)
+	}
+	want := []sequence{{name: "walk", frames: []int{1, 0}}, {name: "idle", frames: []int{2}}}
+	if !reflect.DeepEqual(d.sequences, want) {
+		t.Errorf("sequences = %+v, want %+v", d.sequences, want)
+	}
+}
+
+func TestParseAsepriteTags(t *testing.T) {
+	d, err := parseSheet(strings.NewReader(asepriteArray))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.frames[1].duration != 0.25 {
+		t.Errorf("duration = %v, want 0.25", d.frames[1].duration)
+	}
+	want := []sequence{
+		{name: "run", mode: PingPong, frames: []int{0, 1, 2}},
+		{name: "back", mode: Once, frames: []int{2, 1}},
+	}
+	if !reflect.DeepEqual(d.sequences, want) {
+		t.Errorf("sequences = %+v, want %+v", d.sequences, want)
+	}
+}
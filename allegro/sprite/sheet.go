@@ -0,0 +1,159 @@
+package sprite
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/dradtke/go-allegro/allegro"
+	"github.com/dradtke/go-allegro/allegro/atlas"
+)
+
+// Sheet is a set of frames cut from one or more bitmaps, along with the
+// animations that use them. A sheet owns the bitmaps it creates; call
+// Destroy to free them.
+type Sheet struct {
+	// Frames lists every frame in the order it was defined.
+	Frames []Frame
+
+	// Animations maps animation names to animations built from Frames.
+	Animations map[string]*Animation
+
+	owned []*allegro.Bitmap
+	atlas *atlas.Atlas
+}
+
+// Animation returns the named animation, or nil if there is none.
+func (s *Sheet) Animation(name string) *Animation {
+	return s.Animations[name]
+}
+
+// Destroy frees every bitmap created for the sheet, including the source
+// image if the sheet loaded it. Bitmaps passed in by the caller are left
+// alone.
+func (s *Sheet) Destroy() {
+	for i := len(s.owned) - 1; i >= 0; i-- {
+		s.owned[i].Destroy()
+	}
+	s.owned = nil
+	if s.atlas != nil {
+		s.atlas.Destroy()
+		s.atlas = nil
+	}
+}
+
+// Grid cuts a grid-based sprite sheet into frames of w by h pixels, reading
+// left to right and top to bottom. Margin is the empty border around the
+// whole sheet and spacing the gap between neighbouring frames. A count of
+// zero or less uses every whole frame in the sheet.
+//
+// The returned sheet holds a single animation named "" that plays every
+// frame for the given duration, in seconds.
+func Grid(sheet *allegro.Bitmap, w, h, margin, spacing, count int, duration float64) (*Sheet, error) {
+	if sheet == nil {
+		return nil, allegro.BitmapIsNull
+	}
+	if w <= 0 || h <= 0 {
+		return nil, fmt.Errorf("invalid frame size %dx%d", w, h)
+	}
+	cols := (sheet.Width() - 2*margin + spacing) / (w + spacing)
+	rows := (sheet.Height() - 2*margin + spacing) / (h + spacing)
+	if count <= 0 || count > cols*rows {
+		count = cols * rows
+	}
+
+	s := &Sheet{Animations: make(map[string]*Animation)}
+	for i := 0; i < count; i++ {
+		x := margin + (i%cols)*(w+spacing)
+		y := margin + (i/cols)*(h+spacing)
+		sub, err := sheet.CreateSubBitmap(x, y, w, h)
+		if err != nil {
+			s.Destroy()
+			return nil, err
+		}
+		s.owned = append(s.owned, sub)
+		s.Frames = append(s.Frames, Frame{Bitmap: sub, Duration: duration})
+	}
+	s.Animations[""] = &Animation{Frames: s.Frames}
+	return s, nil
+}
+
+// FromAtlas builds an animation from named atlas entries, showing each for the
+// given duration, in seconds.
+func FromAtlas(a *atlas.Atlas, name string, entries []string, duration float64, mode Mode) (*Animation, error) {
+	anim := &Animation{Name: name, Frames: make([]Frame, len(entries)), Mode: mode}
+	for i, entry := range entries {
+		bmp, ok := a.Bitmap(entry)
+		if !ok {
+			return nil, fmt.Errorf("atlas has no entry '%s'", entry)
+		}
+		anim.Frames[i] = Frame{Bitmap: bmp, Duration: duration}
+	}
+	return anim, nil
+}
+
+// LoadSheet loads a JSON sprite sheet as exported by TexturePacker (hash or
+// array format) or Aseprite, along with the image it refers to. Loading the
+// image requires the image addon.
+//
+// Aseprite frame tags become animations with the tag's name and direction.
+// Without tags, frames are grouped into animations by name, with any
+// extension and trailing frame number removed, so "walk_01.png" and
+// "walk_02.png" both belong to "walk". Every sheet also has an animation
+// named "" containing all of its frames.
+func LoadSheet(filename string) (*Sheet, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	data, err := parseSheet(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse sprite sheet '%s': %s", filename, err)
+	}
+	if data.image == "" {
+		return nil, fmt.Errorf("sprite sheet '%s' does not name an image", filename)
+	}
+	img, err := allegro.LoadBitmap(filepath.Join(filepath.Dir(filename), data.image))
+	if err != nil {
+		return nil, err
+	}
+	s, err := data.build(img)
+	if err != nil {
+		img.Destroy()
+		return nil, err
+	}
+	// The image is destroyed last, after its sub-bitmaps.
+	s.owned = append([]*allegro.Bitmap{img}, s.owned...)
+	return s, nil
+}
+
+// build cuts the parsed frames out of img and assembles the animations.
+func (d *sheetData) build(img *allegro.Bitmap) (*Sheet, error) {
+	s := &Sheet{Animations: make(map[string]*Animation)}
+	for _, fr := range d.frames {
+		sub, err := img.CreateSubBitmap(fr.x, fr.y, fr.w, fr.h)
+		if err != nil {
+			s.Destroy()
+			return nil, fmt.Errorf("frame '%s': %s", fr.name, err)
+		}
+		s.owned = append(s.owned, sub)
+		s.Frames = append(s.Frames, Frame{
+			Bitmap:   sub,
+			Duration: fr.duration,
+			OffsetX:  float32(fr.offsetX),
+			OffsetY:  float32(fr.offsetY),
+			SourceW:  float32(fr.sourceW),
+			SourceH:  float32(fr.sourceH),
+		})
+	}
+	for _, seq := range d.sequences {
+		anim := &Animation{Name: seq.name, Mode: seq.mode, Frames: make([]Frame, len(seq.frames))}
+		for i, index := range seq.frames {
+			anim.Frames[i] = s.Frames[index]
+		}
+		s.Animations[seq.name] = anim
+	}
+	s.Animations[""] = &Animation{Frames: s.Frames}
+	return s, nil
+}
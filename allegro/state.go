@@ -35,9 +35,9 @@ func (e *Error) Error() string {
 //
 // See https://liballeg.org/a5docs/5.2.6/state.html#al_store_state
 func StoreState(flags StateFlags) *State {
-	var state C.ALLEGRO_STATE
-	C.al_store_state(&state, C.int(flags))
-	return (*State)(&state)
+	state := new(State)
+	C.al_store_state((*C.ALLEGRO_STATE)(state), C.int(flags))
+	return state
 }
 
 // Restores part of the state of the current thread from the given
@@ -48,6 +48,38 @@ func RestoreState(state *State) {
 	C.al_restore_state((*C.ALLEGRO_STATE)(state))
 }
 
+// WithState stores the parts of the current thread's state given by flags,
+// calls f, and then restores them, even if f returns early or panics.
+func WithState(flags StateFlags, f func()) {
+	state := StoreState(flags)
+	defer RestoreState(state)
+	f()
+}
+
+// stateStack holds the states saved by PushState. Like the state it saves,
+// it is only meant to be used from the thread doing the drawing.
+var stateStack []*State
+
+// PushState stores the parts of the current thread's state given by flags
+// on a stack, to be restored by a matching call to PopState.
+func PushState(flags StateFlags) {
+	stateStack = append(stateStack, StoreState(flags))
+}
+
+// PopState restores the state saved by the most recent call to PushState and
+// removes it from the stack. It returns false if the stack is empty.
+func PopState() bool {
+	n := len(stateStack)
+	if n == 0 {
+		return false
+	}
+	state := stateStack[n-1]
+	stateStack[n-1] = nil
+	stateStack = stateStack[:n-1]
+	RestoreState(state)
+	return true
+}
+
 // Some Allegro functions will set an error number as well as returning an
 // error code. Call this function to retrieve the last error number set for the
 // calling thread.
@@ -1,4 +0,0 @@
-package tiled
-
-// #cgo !windows pkg-config: allegro_tiled-5
-import "C"
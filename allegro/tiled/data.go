@@ -0,0 +1,190 @@
+package tiled
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image/color"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+
+	"github.com/dradtke/go-allegro/allegro/tilemap"
+	"github.com/klauspost/compress/zstd"
+)
+
+// flipHexagonal is Tiled's flag for rotating a hexagonal tile by 120
+// degrees, which tilemap doesn't support.
+const flipHexagonal = 1 << 28
+
+// gid converts a global tile ID from a file to a Tile.
+func gid(v uint32) tilemap.Tile {
+	return tilemap.Tile(v &^ flipHexagonal)
+}
+
+// decodeTiles decodes n tiles of layer data.
+func decodeTiles(data, encoding, compression string, n int) ([]tilemap.Tile, error) {
+	var tiles []tilemap.Tile
+	switch encoding {
+	case "csv":
+		tiles = make([]tilemap.Tile, 0, n)
+		for _, field := range strings.Split(data, ",") {
+			field = strings.TrimSpace(field)
+			if field == "" {
+				continue
+			}
+			v, err := strconv.ParseUint(field, 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("invalid tile %q", field)
+			}
+			tiles = append(tiles, gid(uint32(v)))
+		}
+	case "base64":
+		raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(data))
+		if err != nil {
+			return nil, err
+		}
+		if raw, err = decompress(raw, compression); err != nil {
+			return nil, err
+		}
+		if len(raw) != n*4 {
+			return nil, fmt.Errorf("got %d bytes of tile data, want %d", len(raw), n*4)
+		}
+		tiles = make([]tilemap.Tile, n)
+		for i := range tiles {
+			tiles[i] = gid(binary.LittleEndian.Uint32(raw[i*4:]))
+		}
+	default:
+		return nil, fmt.Errorf("unsupported encoding %q", encoding)
+	}
+	if len(tiles) != n {
+		return nil, fmt.Errorf("got %d tiles, want %d", len(tiles), n)
+	}
+	return tiles, nil
+}
+
+func decompress(data []byte, compression string) ([]byte, error) {
+	var r io.ReadCloser
+	var err error
+	switch compression {
+	case "":
+		return data, nil
+	case "zlib":
+		r, err = zlib.NewReader(bytes.NewReader(data))
+	case "gzip":
+		r, err = gzip.NewReader(bytes.NewReader(data))
+	case "zstd":
+		var d *zstd.Decoder
+		if d, err = zstd.NewReader(bytes.NewReader(data)); err == nil {
+			r = d.IOReadCloser()
+		}
+	default:
+		return nil, fmt.Errorf("unsupported compression %q", compression)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return ioutil.ReadAll(r)
+}
+
+// parseColor parses a color in Tiled's #RRGGBB or #AARRGGBB form. An empty
+// string is transparent.
+func parseColor(s string) (color.NRGBA, error) {
+	s = strings.TrimPrefix(s, "#")
+	if s == "" {
+		return color.NRGBA{}, nil
+	}
+	v, err := strconv.ParseUint(s, 16, 32)
+	switch {
+	case err != nil:
+	case len(s) == 6:
+		return color.NRGBA{uint8(v >> 16), uint8(v >> 8), uint8(v), 0xFF}, nil
+	case len(s) == 8:
+		return color.NRGBA{uint8(v >> 16), uint8(v >> 8), uint8(v), uint8(v >> 24)}, nil
+	}
+	return color.NRGBA{}, errors.New("invalid color #" + s)
+}
+
+// setOrientation parses the orientation and stagger settings of a map.
+func (m *Map) setOrientation(orientation, axis, index string) error {
+	switch orientation {
+	case "orthogonal", "":
+		m.Orientation = tilemap.Orthogonal
+	case "isometric":
+		m.Orientation = tilemap.Isometric
+	case "staggered", "hexagonal":
+		m.Orientation = tilemap.Staggered
+	default:
+		return fmt.Errorf("unsupported orientation %q", orientation)
+	}
+	if orientation != "hexagonal" {
+		m.HexSideLength = 0
+	}
+	if axis == "x" {
+		m.StaggerAxis = tilemap.StaggerX
+	}
+	if index == "even" {
+		m.StaggerIndex = tilemap.StaggerEven
+	}
+	return nil
+}
+
+// decoder holds the state shared by the TMX and JSON parsers.
+type decoder struct {
+	// dir is the directory of the file being parsed.
+	dir string
+
+	// err is the first error encountered, so that parsing can carry on
+	// without checking each field.
+	err error
+}
+
+func (d *decoder) fail(err error) {
+	if d.err == nil {
+		d.err = err
+	}
+}
+
+func (d *decoder) color(s string) color.NRGBA {
+	c, err := parseColor(s)
+	if err != nil {
+		d.fail(err)
+	}
+	return c
+}
+
+func (d *decoder) tiles(data, encoding, compression string, n int) []tilemap.Tile {
+	tiles, err := decodeTiles(data, encoding, compression, n)
+	if err != nil {
+		d.fail(err)
+	}
+	return tiles
+}
+
+func (d *decoder) image(source string, width, height int, trans string) *Image {
+	if source == "" {
+		return nil
+	}
+	img := &Image{Source: resolve(d.dir, source), Width: width, Height: height}
+	if trans != "" {
+		img.Trans = d.color(trans)
+		img.Trans.A = 0xFF
+	}
+	return img
+}
+
+func (d *decoder) tileset(source string, firstGID tilemap.Tile) *Tileset {
+	ts, err := LoadTileset(resolve(d.dir, source))
+	if err != nil {
+		d.fail(err)
+		return &Tileset{FirstGID: firstGID}
+	}
+	ts.FirstGID = firstGID
+	return ts
+}
@@ -0,0 +1,414 @@
+package tiled
+
+import (
+	"image/color"
+	"math"
+	"sort"
+
+	"github.com/dradtke/go-allegro/allegro"
+	"github.com/dradtke/go-allegro/allegro/camera"
+	"github.com/dradtke/go-allegro/allegro/tilemap"
+)
+
+// Tileset returns the tileset that a tile belongs to and the tile's local ID
+// within it, or nil if the tile is empty or not part of any tileset.
+func (m *Map) Tileset(gid tilemap.Tile) (*Tileset, int) {
+	id := gid.ID()
+	if id == 0 {
+		return nil, 0
+	}
+	i := sort.Search(len(m.Tilesets), func(i int) bool {
+		return m.Tilesets[i].FirstGID > id
+	}) - 1
+	if i < 0 {
+		return nil, 0
+	}
+	return m.Tilesets[i], int(id - m.Tilesets[i].FirstGID)
+}
+
+// LoadImages loads the bitmaps of the map's tilesets and image layers. Images
+// that are already loaded are skipped.
+func (m *Map) LoadImages() error {
+	var err error
+	m.images(func(img *Image) {
+		if err != nil || img.Bitmap != nil || img.Source == "" {
+			return
+		}
+		var bmp *allegro.Bitmap
+		if bmp, err = allegro.LoadBitmap(img.Source); err != nil {
+			return
+		}
+		if img.Trans.A != 0 {
+			bmp.ConvertMaskToAlpha(allegro.MapRGB(img.Trans.R, img.Trans.G, img.Trans.B))
+		}
+		img.Bitmap = bmp
+		m.bitmaps = append(m.bitmaps, bmp)
+	})
+	m.destroyTiles()
+	return err
+}
+
+// Destroy frees the bitmaps loaded by LoadImages and the cached chunks of
+// the map's tile layers.
+func (m *Map) Destroy() {
+	m.destroyTiles()
+	for _, bmp := range m.bitmaps {
+		bmp.Destroy()
+	}
+	m.bitmaps = nil
+	m.images(func(img *Image) {
+		img.Bitmap = nil
+	})
+}
+
+func (m *Map) destroyTiles() {
+	if m.tiles != nil {
+		m.tiles.Destroy()
+		m.tiles, m.tileLayers = nil, nil
+	}
+}
+
+// images calls f for every image in the map.
+func (m *Map) images(f func(*Image)) {
+	for _, ts := range m.Tilesets {
+		if ts.Image != nil {
+			f(ts.Image)
+		}
+		for _, t := range ts.Tiles {
+			if t.Image != nil {
+				f(t.Image)
+			}
+		}
+	}
+	walk(m.Layers, func(l *Layer) {
+		if l.Image != nil {
+			f(l.Image)
+		}
+	})
+}
+
+// walk calls f for every layer in layers and their groups, in drawing order.
+func walk(layers []*Layer, f func(*Layer)) {
+	for _, l := range layers {
+		f(l)
+		walk(l.Layers, f)
+	}
+}
+
+// Tilemap returns the map's tile layers as a tilemap.Map, which is created
+// the first time it is called, after which changes to the tile layers aren't
+// reflected in it. Tile layers inside groups become top-level layers. For
+// infinite maps, the tilemap.Map covers every chunk, and its layers are
+// offset so that its tiles are drawn where Tiled would draw them.
+//
+// LoadImages should be called first, as tiles with no bitmap are not drawn.
+func (m *Map) Tilemap() *tilemap.Map {
+	if m.tiles != nil {
+		return m.tiles
+	}
+	x0, y0, x1, y1 := 0, 0, m.Width, m.Height
+	if m.Infinite {
+		x0, y0, x1, y1 = m.chunkBounds()
+	}
+	if m.Orientation == tilemap.Staggered {
+		// Keep the parity of rows and columns, which decides which of them
+		// are shifted.
+		x0, y0 = x0-x0&1, y0-y0&1
+	}
+	tm := tilemap.New(m.Orientation, x1-x0, y1-y0, m.TileWidth, m.TileHeight)
+	tm.StaggerAxis = m.StaggerAxis
+	tm.StaggerIndex = m.StaggerIndex
+	tm.HexSideLength = m.HexSideLength
+	for _, ts := range m.Tilesets {
+		tm.AddTileset(ts.tileset())
+	}
+	ox, oy := tm.TileToPixel(x0, y0)
+	zx, zy := tm.TileToPixel(0, 0)
+	m.originX, m.originY = ox-zx, oy-zy
+	m.tileLayers = make(map[*Layer]*tilemap.Layer)
+	walkState(m.Layers, rootState, func(l *Layer, s layerState) {
+		if l.Type != TileLayer {
+			return
+		}
+		tl := tm.AddLayer(l.Name)
+		tl.Visible = s.visible
+		tl.Opacity = s.opacity
+		tl.OffsetX, tl.OffsetY = s.offsetX+m.originX, s.offsetY+m.originY
+		if l.Chunks == nil {
+			for y := y0; y < y1; y++ {
+				for x := x0; x < x1; x++ {
+					tl.Tiles[(y-y0)*tm.Width+x-x0] = l.Tile(x, y)
+				}
+			}
+		}
+		// Tile searches every chunk, so they are copied one at a time instead.
+		for _, c := range l.Chunks {
+			for y := max(c.Y, y0); y < min(c.Y+c.Height, y1); y++ {
+				for x := max(c.X, x0); x < min(c.X+c.Width, x1); x++ {
+					tl.Tiles[(y-y0)*tm.Width+x-x0] = c.Tiles[(y-c.Y)*c.Width+x-c.X]
+				}
+			}
+		}
+		m.tileLayers[l] = tl
+	})
+	m.tiles = tm
+	return tm
+}
+
+// chunkBounds returns the range of tiles covered by the chunks of an
+// infinite map.
+func (m *Map) chunkBounds() (x0, y0, x1, y1 int) {
+	first := true
+	walk(m.Layers, func(l *Layer) {
+		for _, c := range l.Chunks {
+			if first {
+				x0, y0, x1, y1 = c.X, c.Y, c.X+c.Width, c.Y+c.Height
+				first = false
+				continue
+			}
+			x0, y0 = min(x0, c.X), min(y0, c.Y)
+			x1, y1 = max(x1, c.X+c.Width), max(y1, c.Y+c.Height)
+		}
+	})
+	return x0, y0, x1, y1
+}
+
+// tileset converts a tileset for use by tilemap.
+func (ts *Tileset) tileset() *tilemap.Tileset {
+	var t *tilemap.Tileset
+	switch {
+	case ts.Image != nil && ts.Image.Bitmap != nil:
+		t = tilemap.NewTileset(ts.Image.Bitmap, ts.TileWidth, ts.TileHeight, ts.Margin, ts.Spacing)
+	case ts.Image != nil:
+		t = tilemap.NewCollection(make([]*allegro.Bitmap, ts.TileCount))
+	default:
+		n := ts.TileCount
+		for _, tile := range ts.Tiles {
+			n = max(n, tile.ID+1)
+		}
+		images := make([]*allegro.Bitmap, n)
+		for _, tile := range ts.Tiles {
+			if tile.Image != nil {
+				images[tile.ID] = tile.Image.Bitmap
+			}
+		}
+		t = tilemap.NewCollection(images)
+	}
+	t.Name = ts.Name
+	t.FirstID = ts.FirstGID
+	t.OffsetX, t.OffsetY = ts.TileOffsetX, ts.TileOffsetY
+	for _, tile := range ts.Tiles {
+		if tile.Animation != nil {
+			t.Animate(tile.ID, tile.Animation...)
+		}
+	}
+	return t
+}
+
+// Update advances animated tiles by dt seconds.
+func (m *Map) Update(dt float64) {
+	m.Tilemap().Update(dt)
+}
+
+// Draw draws the map's visible layers, skipping everything outside of view,
+// which is in map pixel coordinates, such as the result of Camera.View.
+// Tile layers are drawn by Tilemap, image layers as bitmaps, and object
+// groups by drawing their tile objects. Layer visibility, opacity, offsets
+// and parallax are taken from the layers each time, but tile layers aren't
+// tinted. The background color is left to the caller.
+func (m *Map) Draw(view camera.Rect) {
+	m.Tilemap()
+	walkState(m.Layers, rootState, func(l *Layer, s layerState) {
+		if !s.visible {
+			return
+		}
+		s.offsetX += view.X * (1 - s.parallaxX)
+		s.offsetY += view.Y * (1 - s.parallaxY)
+		switch l.Type {
+		case TileLayer:
+			if tl := m.tileLayers[l]; tl != nil {
+				tl.OffsetX, tl.OffsetY = s.offsetX+m.originX, s.offsetY+m.originY
+				tl.Opacity = s.opacity
+				tl.Draw(view)
+			}
+		case ImageLayer:
+			m.drawImage(l, s, view)
+		case ObjectGroup:
+			m.drawObjects(l, s)
+		}
+	})
+}
+
+// layerState is what a layer inherits from the groups that it is in.
+type layerState struct {
+	visible              bool
+	opacity              float32
+	offsetX, offsetY     float32
+	parallaxX, parallaxY float32
+	tint                 [3]float32
+}
+
+var rootState = layerState{visible: true, opacity: 1, parallaxX: 1, parallaxY: 1, tint: [3]float32{1, 1, 1}}
+
+// walkState calls f for every layer in layers and their groups other than
+// the groups themselves, in drawing order, along with its combined state.
+func walkState(layers []*Layer, parent layerState, f func(*Layer, layerState)) {
+	for _, l := range layers {
+		s := parent
+		s.visible = s.visible && l.Visible
+		s.opacity *= l.Opacity
+		s.offsetX += l.OffsetX
+		s.offsetY += l.OffsetY
+		s.parallaxX *= l.ParallaxX
+		s.parallaxY *= l.ParallaxY
+		if c := l.TintColor; c != (color.NRGBA{}) {
+			s.tint[0] *= float32(c.R) / 255
+			s.tint[1] *= float32(c.G) / 255
+			s.tint[2] *= float32(c.B) / 255
+			s.opacity *= float32(c.A) / 255
+		}
+		if l.Type == Group {
+			walkState(l.Layers, s, f)
+		} else {
+			f(l, s)
+		}
+	}
+}
+
+// color returns the premultiplied color to tint a layer's bitmaps with.
+func (s layerState) color() allegro.Color {
+	a := s.opacity
+	return allegro.MapRGBAf(s.tint[0]*a, s.tint[1]*a, s.tint[2]*a, a)
+}
+
+// drawImage draws an image layer, repeating it across the view if needed.
+func (m *Map) drawImage(l *Layer, s layerState, view camera.Rect) {
+	if l.Image == nil || l.Image.Bitmap == nil {
+		return
+	}
+	bmp := l.Image.Bitmap
+	w, h := float32(bmp.Width()), float32(bmp.Height())
+	x0, x1 := s.offsetX, s.offsetX+1
+	if l.RepeatX && w > 0 {
+		x0 += float32(math.Floor(float64((view.X-x0)/w))) * w
+		x1 = view.X + view.W
+	}
+	y0, y1 := s.offsetY, s.offsetY+1
+	if l.RepeatY && h > 0 {
+		y0 += float32(math.Floor(float64((view.Y-y0)/h))) * h
+		y1 = view.Y + view.H
+	}
+	tint := s.color()
+	for y := y0; y < y1; y += h {
+		for x := x0; x < x1; x += w {
+			if overlaps(camera.Rect{X: x, Y: y, W: w, H: h}, view) {
+				bmp.DrawTinted(tint, x, y, allegro.FLIP_NONE)
+			}
+		}
+	}
+}
+
+// drawObjects draws the tile objects of an object group.
+func (m *Map) drawObjects(l *Layer, s layerState) {
+	tint := s.color()
+	objects := l.Objects
+	if l.DrawOrder == "topdown" && !sort.SliceIsSorted(objects, func(i, j int) bool {
+		return objects[i].Y < objects[j].Y
+	}) {
+		objects = append([]*Object(nil), objects...)
+		sort.SliceStable(objects, func(i, j int) bool {
+			return objects[i].Y < objects[j].Y
+		})
+	}
+	for _, o := range objects {
+		if !o.Visible || o.GID.ID() == 0 {
+			continue
+		}
+		ts, _ := m.Tileset(o.GID)
+		t, index := m.tiles.Tileset(o.GID)
+		if ts == nil || t == nil {
+			continue
+		}
+		bmp, sx, sy, sw, sh := t.Source(index)
+		if bmp == nil || sw == 0 || sh == 0 {
+			continue
+		}
+		xscale, yscale := float32(1), float32(1)
+		if o.Width != 0 && o.Height != 0 {
+			xscale, yscale = o.Width/sw, o.Height/sh
+		}
+		cx, cy := m.anchor(ts.ObjectAlignment, sw, sh)
+		px, py := m.objectToPixel(o.X, o.Y)
+		var flags allegro.DrawFlags
+		if o.GID&tilemap.FlipHorizontal != 0 {
+			flags |= allegro.FLIP_HORIZONTAL
+		}
+		if o.GID&tilemap.FlipVertical != 0 {
+			flags |= allegro.FLIP_VERTICAL
+		}
+		bmp.DrawTintedScaledRotatedRegion(sx, sy, sw, sh, tint, cx, cy,
+			px+s.offsetX+t.OffsetX, py+s.offsetY+t.OffsetY,
+			xscale, yscale, o.Rotation*math.Pi/180, flags)
+	}
+}
+
+// anchor returns the point of a tile object's image that is placed at the
+// object's position.
+func (m *Map) anchor(alignment string, w, h float32) (float32, float32) {
+	if alignment == "" || alignment == "unspecified" {
+		alignment = "bottomleft"
+		if m.Orientation == tilemap.Isometric {
+			alignment = "bottom"
+		}
+	}
+	switch alignment {
+	case "topleft":
+		return 0, 0
+	case "top":
+		return w / 2, 0
+	case "topright":
+		return w, 0
+	case "left":
+		return 0, h / 2
+	case "center":
+		return w / 2, h / 2
+	case "right":
+		return w, h / 2
+	case "bottom":
+		return w / 2, h
+	case "bottomright":
+		return w, h
+	}
+	return 0, h
+}
+
+// objectToPixel converts an object position to map pixel coordinates. On
+// isometric maps, objects are positioned along the tile axes, in units of
+// TileHeight pixels.
+func (m *Map) objectToPixel(x, y float32) (float32, float32) {
+	if m.Orientation != tilemap.Isometric || m.TileHeight == 0 {
+		return x, y
+	}
+	tw, th := float32(m.TileWidth), float32(m.TileHeight)
+	tx, ty := x/th, y/th
+	originX := float32(m.tiles.Height) * tw / 2
+	return (tx-ty)*tw/2 + originX + m.originX, (tx+ty)*th/2 + m.originY
+}
+
+func overlaps(a, b camera.Rect) bool {
+	return a.X < b.X+b.W && b.X < a.X+a.W && a.Y < b.Y+b.H && b.Y < a.Y+a.H
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
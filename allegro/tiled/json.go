@@ -0,0 +1,476 @@
+package tiled
+
+import (
+	"encoding/json"
+	"fmt"
+	"image/color"
+	"sort"
+	"strings"
+
+	"github.com/dradtke/go-allegro/allegro/tilemap"
+)
+
+type jsonMap struct {
+	Orientation     string         `json:"orientation"`
+	RenderOrder     string         `json:"renderorder"`
+	Class           string         `json:"class"`
+	Width           int            `json:"width"`
+	Height          int            `json:"height"`
+	TileWidth       int            `json:"tilewidth"`
+	TileHeight      int            `json:"tileheight"`
+	HexSideLength   int            `json:"hexsidelength"`
+	StaggerAxis     string         `json:"staggeraxis"`
+	StaggerIndex    string         `json:"staggerindex"`
+	Infinite        bool           `json:"infinite"`
+	BackgroundColor string         `json:"backgroundcolor"`
+	Properties      []jsonProperty `json:"properties"`
+	Tilesets        []jsonTileset  `json:"tilesets"`
+	Layers          []jsonLayer    `json:"layers"`
+}
+
+type jsonProperty struct {
+	Name  string          `json:"name"`
+	Type  string          `json:"type"`
+	Value json.RawMessage `json:"value"`
+}
+
+type jsonTileset struct {
+	FirstGID         uint32         `json:"firstgid"`
+	Source           string         `json:"source"`
+	Name             string         `json:"name"`
+	Class            string         `json:"class"`
+	TileWidth        int            `json:"tilewidth"`
+	TileHeight       int            `json:"tileheight"`
+	Spacing          int            `json:"spacing"`
+	Margin           int            `json:"margin"`
+	TileCount        int            `json:"tilecount"`
+	Columns          int            `json:"columns"`
+	ObjectAlignment  string         `json:"objectalignment"`
+	TileOffset       jsonPoint      `json:"tileoffset"`
+	Image            string         `json:"image"`
+	ImageWidth       int            `json:"imagewidth"`
+	ImageHeight      int            `json:"imageheight"`
+	TransparentColor string         `json:"transparentcolor"`
+	Tiles            []jsonTile     `json:"tiles"`
+	Properties       []jsonProperty `json:"properties"`
+}
+
+type jsonTile struct {
+	ID          int            `json:"id"`
+	Type        string         `json:"type"`
+	Class       string         `json:"class"`
+	Image       string         `json:"image"`
+	ImageWidth  int            `json:"imagewidth"`
+	ImageHeight int            `json:"imageheight"`
+	Animation   []jsonFrame    `json:"animation"`
+	ObjectGroup *jsonLayer     `json:"objectgroup"`
+	Properties  []jsonProperty `json:"properties"`
+}
+
+type jsonFrame struct {
+	TileID   int `json:"tileid"`
+	Duration int `json:"duration"`
+}
+
+type jsonPoint struct {
+	X float32 `json:"x"`
+	Y float32 `json:"y"`
+}
+
+type jsonLayer struct {
+	Type       string         `json:"type"`
+	ID         int            `json:"id"`
+	Name       string         `json:"name"`
+	Class      string         `json:"class"`
+	Visible    *bool          `json:"visible"`
+	Opacity    *float32       `json:"opacity"`
+	OffsetX    float32        `json:"offsetx"`
+	OffsetY    float32        `json:"offsety"`
+	ParallaxX  *float32       `json:"parallaxx"`
+	ParallaxY  *float32       `json:"parallaxy"`
+	TintColor  string         `json:"tintcolor"`
+	Properties []jsonProperty `json:"properties"`
+
+	X           int             `json:"x"`
+	Y           int             `json:"y"`
+	Width       int             `json:"width"`
+	Height      int             `json:"height"`
+	Data        json.RawMessage `json:"data"`
+	Encoding    string          `json:"encoding"`
+	Compression string          `json:"compression"`
+	Chunks      []jsonChunk     `json:"chunks"`
+
+	Color     string       `json:"color"`
+	DrawOrder string       `json:"draworder"`
+	Objects   []jsonObject `json:"objects"`
+
+	Image            string `json:"image"`
+	ImageWidth       int    `json:"imagewidth"`
+	ImageHeight      int    `json:"imageheight"`
+	TransparentColor string `json:"transparentcolor"`
+	RepeatX          bool   `json:"repeatx"`
+	RepeatY          bool   `json:"repeaty"`
+
+	Layers []jsonLayer `json:"layers"`
+}
+
+type jsonChunk struct {
+	X      int             `json:"x"`
+	Y      int             `json:"y"`
+	Width  int             `json:"width"`
+	Height int             `json:"height"`
+	Data   json.RawMessage `json:"data"`
+}
+
+type jsonObject struct {
+	ID         int            `json:"id"`
+	Name       string         `json:"name"`
+	Type       string         `json:"type"`
+	Class      string         `json:"class"`
+	X          float32        `json:"x"`
+	Y          float32        `json:"y"`
+	Width      float32        `json:"width"`
+	Height     float32        `json:"height"`
+	Rotation   float32        `json:"rotation"`
+	GID        uint32         `json:"gid"`
+	Visible    *bool          `json:"visible"`
+	Template   string         `json:"template"`
+	Ellipse    bool           `json:"ellipse"`
+	Point      bool           `json:"point"`
+	Polygon    []jsonPoint    `json:"polygon"`
+	Polyline   []jsonPoint    `json:"polyline"`
+	Text       *jsonText      `json:"text"`
+	Properties []jsonProperty `json:"properties"`
+}
+
+type jsonText struct {
+	Text       string `json:"text"`
+	FontFamily string `json:"fontfamily"`
+	PixelSize  *int   `json:"pixelsize"`
+	Wrap       bool   `json:"wrap"`
+	Color      string `json:"color"`
+	Bold       bool   `json:"bold"`
+	Italic     bool   `json:"italic"`
+	Underline  bool   `json:"underline"`
+	Strikeout  bool   `json:"strikeout"`
+	Kerning    *bool  `json:"kerning"`
+	HAlign     string `json:"halign"`
+	VAlign     string `json:"valign"`
+}
+
+func parseJSONMap(data []byte, dir string) (*Map, error) {
+	var j jsonMap
+	if err := json.Unmarshal(data, &j); err != nil {
+		return nil, err
+	}
+	d := &decoder{dir: dir}
+	m := &Map{
+		RenderOrder:     j.RenderOrder,
+		Width:           j.Width,
+		Height:          j.Height,
+		TileWidth:       j.TileWidth,
+		TileHeight:      j.TileHeight,
+		Infinite:        j.Infinite,
+		HexSideLength:   j.HexSideLength,
+		BackgroundColor: d.color(j.BackgroundColor),
+		Class:           j.Class,
+		Properties:      d.jsonProperties(j.Properties),
+	}
+	if err := m.setOrientation(j.Orientation, j.StaggerAxis, j.StaggerIndex); err != nil {
+		return nil, err
+	}
+	for i := range j.Tilesets {
+		t := &j.Tilesets[i]
+		if t.Source != "" {
+			m.Tilesets = append(m.Tilesets, d.tileset(t.Source, tilemap.Tile(t.FirstGID)))
+		} else {
+			m.Tilesets = append(m.Tilesets, d.jsonTileset(t))
+		}
+	}
+	sort.SliceStable(m.Tilesets, func(i, j int) bool {
+		return m.Tilesets[i].FirstGID < m.Tilesets[j].FirstGID
+	})
+	m.Layers = d.jsonLayers(j.Layers)
+	if d.err != nil {
+		return nil, d.err
+	}
+	return m, nil
+}
+
+func parseJSONTileset(data []byte, dir string) (*Tileset, error) {
+	var j jsonTileset
+	if err := json.Unmarshal(data, &j); err != nil {
+		return nil, err
+	}
+	d := &decoder{dir: dir}
+	ts := d.jsonTileset(&j)
+	if d.err != nil {
+		return nil, d.err
+	}
+	return ts, nil
+}
+
+func (d *decoder) jsonTileset(j *jsonTileset) *Tileset {
+	ts := &Tileset{
+		FirstGID:        tilemap.Tile(j.FirstGID),
+		Name:            j.Name,
+		Class:           j.Class,
+		TileWidth:       j.TileWidth,
+		TileHeight:      j.TileHeight,
+		Spacing:         j.Spacing,
+		Margin:          j.Margin,
+		TileCount:       j.TileCount,
+		Columns:         j.Columns,
+		TileOffsetX:     j.TileOffset.X,
+		TileOffsetY:     j.TileOffset.Y,
+		ObjectAlignment: j.ObjectAlignment,
+		Image:           d.image(j.Image, j.ImageWidth, j.ImageHeight, j.TransparentColor),
+		Properties:      d.jsonProperties(j.Properties),
+	}
+	for _, jt := range j.Tiles {
+		t := &Tile{
+			ID:         jt.ID,
+			Class:      jt.Class,
+			Image:      d.image(jt.Image, jt.ImageWidth, jt.ImageHeight, ""),
+			Properties: d.jsonProperties(jt.Properties),
+		}
+		if t.Class == "" {
+			t.Class = jt.Type
+		}
+		for _, f := range jt.Animation {
+			t.Animation = append(t.Animation, tilemap.Frame{Tile: f.TileID, Duration: float64(f.Duration) / 1000})
+		}
+		if jt.ObjectGroup != nil {
+			t.ObjectGroup = d.jsonLayer(jt.ObjectGroup)
+		}
+		ts.Tiles = append(ts.Tiles, t)
+	}
+	return ts
+}
+
+func (d *decoder) jsonLayers(js []jsonLayer) []*Layer {
+	var layers []*Layer
+	for i := range js {
+		if l := d.jsonLayer(&js[i]); l != nil {
+			layers = append(layers, l)
+		}
+	}
+	return layers
+}
+
+// jsonLayer converts a layer, or returns nil if it is of an unknown type.
+func (d *decoder) jsonLayer(j *jsonLayer) *Layer {
+	l := &Layer{
+		ID:         j.ID,
+		Name:       j.Name,
+		Class:      j.Class,
+		Visible:    j.Visible == nil || *j.Visible,
+		Opacity:    1,
+		OffsetX:    j.OffsetX,
+		OffsetY:    j.OffsetY,
+		ParallaxX:  1,
+		ParallaxY:  1,
+		TintColor:  d.color(j.TintColor),
+		Properties: d.jsonProperties(j.Properties),
+	}
+	if j.Opacity != nil {
+		l.Opacity = *j.Opacity
+	}
+	if j.ParallaxX != nil {
+		l.ParallaxX = *j.ParallaxX
+	}
+	if j.ParallaxY != nil {
+		l.ParallaxY = *j.ParallaxY
+	}
+	switch j.Type {
+	case "tilelayer":
+		l.Type = TileLayer
+		l.X, l.Y, l.Width, l.Height = j.X, j.Y, j.Width, j.Height
+		if j.Chunks != nil {
+			for _, c := range j.Chunks {
+				l.Chunks = append(l.Chunks, Chunk{
+					X: c.X, Y: c.Y, Width: c.Width, Height: c.Height,
+					Tiles: d.jsonTiles(j, c.Data, c.Width*c.Height),
+				})
+			}
+			break
+		}
+		l.Tiles = d.jsonTiles(j, j.Data, l.Width*l.Height)
+	case "objectgroup":
+		l.Type = ObjectGroup
+		l.Color = d.color(j.Color)
+		l.DrawOrder = j.DrawOrder
+		if l.DrawOrder == "" {
+			l.DrawOrder = "topdown"
+		}
+		for i := range j.Objects {
+			l.Objects = append(l.Objects, d.jsonObject(&j.Objects[i]))
+		}
+	case "imagelayer":
+		l.Type = ImageLayer
+		l.Image = d.image(j.Image, j.ImageWidth, j.ImageHeight, j.TransparentColor)
+		l.RepeatX, l.RepeatY = j.RepeatX, j.RepeatY
+	case "group":
+		l.Type = Group
+		l.Layers = d.jsonLayers(j.Layers)
+	default:
+		return nil
+	}
+	return l
+}
+
+// jsonTiles decodes the tiles of a layer or chunk, which are either an array
+// of numbers or an encoded string.
+func (d *decoder) jsonTiles(j *jsonLayer, data json.RawMessage, n int) []tilemap.Tile {
+	if len(data) == 0 {
+		return make([]tilemap.Tile, n)
+	}
+	if data[0] == '"' {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			d.fail(err)
+			return nil
+		}
+		return d.tiles(s, j.Encoding, j.Compression, n)
+	}
+	var gids []uint32
+	if err := json.Unmarshal(data, &gids); err != nil {
+		d.fail(err)
+		return nil
+	}
+	if len(gids) != n {
+		d.fail(fmt.Errorf("got %d tiles, want %d", len(gids), n))
+		return nil
+	}
+	tiles := make([]tilemap.Tile, n)
+	for i, v := range gids {
+		tiles[i] = gid(v)
+	}
+	return tiles
+}
+
+func (d *decoder) jsonObject(j *jsonObject) *Object {
+	o := &Object{
+		ID:         j.ID,
+		Name:       j.Name,
+		Class:      j.Class,
+		X:          j.X,
+		Y:          j.Y,
+		Width:      j.Width,
+		Height:     j.Height,
+		Rotation:   j.Rotation,
+		Visible:    j.Visible == nil || *j.Visible,
+		GID:        gid(j.GID),
+		Template:   resolve(d.dir, j.Template),
+		Properties: d.jsonProperties(j.Properties),
+	}
+	if o.Class == "" {
+		o.Class = j.Type
+	}
+	switch {
+	case j.Ellipse:
+		o.Shape = ShapeEllipse
+	case j.Point:
+		o.Shape = ShapePoint
+	case j.Polygon != nil:
+		o.Shape = ShapePolygon
+		o.Points = jsonPoints(j.Polygon)
+	case j.Polyline != nil:
+		o.Shape = ShapePolyline
+		o.Points = jsonPoints(j.Polyline)
+	case j.Text != nil:
+		o.Shape = ShapeText
+		t := j.Text
+		o.Text = &Text{
+			Text:       t.Text,
+			FontFamily: t.FontFamily,
+			PixelSize:  16,
+			Color:      color.NRGBA{A: 0xFF},
+			Wrap:       t.Wrap,
+			Bold:       t.Bold,
+			Italic:     t.Italic,
+			Underline:  t.Underline,
+			Strikeout:  t.Strikeout,
+			Kerning:    t.Kerning == nil || *t.Kerning,
+			HAlign:     t.HAlign,
+			VAlign:     t.VAlign,
+		}
+		if t.PixelSize != nil {
+			o.Text.PixelSize = *t.PixelSize
+		}
+		if t.Color != "" {
+			o.Text.Color = d.color(t.Color)
+		}
+	}
+	return o
+}
+
+func jsonPoints(js []jsonPoint) []Point {
+	points := make([]Point, len(js))
+	for i, p := range js {
+		points[i] = Point{p.X, p.Y}
+	}
+	return points
+}
+
+func (d *decoder) jsonProperties(js []jsonProperty) Properties {
+	var props Properties
+	for _, j := range js {
+		p := Property{Name: j.Name, Type: j.Type}
+		if p.Type == "" {
+			p.Type = "string"
+		}
+		if p.Type == "class" {
+			p.Properties = d.jsonMembers(j.Value)
+		} else {
+			p.Value = jsonValue(j.Value)
+		}
+		props = append(props, p)
+	}
+	return props
+}
+
+// jsonMembers converts the value of a class property, an object of member
+// names and values, to properties. The members' types aren't recorded, so
+// they are guessed from their values.
+func (d *decoder) jsonMembers(data json.RawMessage) Properties {
+	var members map[string]json.RawMessage
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &members); err != nil {
+			d.fail(err)
+			return nil
+		}
+	}
+	var props Properties
+	for name, value := range members {
+		p := Property{Name: name}
+		switch v := strings.TrimSpace(string(value)); {
+		case strings.HasPrefix(v, "{"):
+			p.Type = "class"
+			p.Properties = d.jsonMembers(value)
+		case strings.HasPrefix(v, `"`):
+			p.Type = "string"
+		case v == "true" || v == "false":
+			p.Type = "bool"
+		case strings.ContainsAny(v, ".eE"):
+			p.Type = "float"
+		default:
+			p.Type = "int"
+		}
+		if p.Type != "class" {
+			p.Value = jsonValue(value)
+		}
+		props = append(props, p)
+	}
+	sort.Slice(props, func(i, j int) bool { return props[i].Name < props[j].Name })
+	return props
+}
+
+// jsonValue returns a property value as text.
+func jsonValue(data json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		return s
+	}
+	return string(data)
+}
@@ -1,60 +1,423 @@
-// Package tiled implements support for the Allegro Tiled addon.
+// Package tiled loads maps made with the Tiled map editor, in its TMX and
+// JSON formats, and draws them with the tilemap package.
+//
+//	m, err := tiled.Load("levels/forest.tmx")
+//	if err != nil {
+//		...
+//	}
+//	if err := m.LoadImages(); err != nil {
+//		...
+//	}
+//	defer m.Destroy()
+//	...
+//	case allegro.TimerEvent:
+//		m.Update(timer.Speed())
+//	...
+//	cam.Draw(func() {
+//		m.Draw(cam.View())
+//	})
+//
+// Orthogonal, isometric, staggered and hexagonal maps are supported, as are
+// infinite maps, external tilesets, image collection tilesets, tile
+// animations, and every layer data encoding and compression that Tiled
+// writes. Object templates are not resolved.
 package tiled
 
-// #include <allegro5/allegro_tiled.h>
-// #include "../util.c"
-import "C"
 import (
-	"fmt"
+	"bytes"
+	"errors"
+	"image/color"
+	"io"
+	"io/ioutil"
 	"path/filepath"
+	"strconv"
+
+	"github.com/dradtke/go-allegro/allegro"
+	"github.com/dradtke/go-allegro/allegro/tilemap"
+)
+
+// Map is a Tiled map.
+type Map struct {
+	// Orientation is the shape of the map's tiles. Hexagonal maps are
+	// Staggered, with a non-zero HexSideLength.
+	Orientation tilemap.Orientation
+	RenderOrder string
+
+	// Width and Height are the size of the map in tiles, and TileWidth and
+	// TileHeight the size of its grid cells in pixels. For infinite maps,
+	// the tile layers' chunks can extend beyond Width and Height in any
+	// direction.
+	Width, Height         int
+	TileWidth, TileHeight int
+	Infinite              bool
+
+	// StaggerAxis, StaggerIndex and HexSideLength only apply to Staggered
+	// maps.
+	StaggerAxis   tilemap.StaggerAxis
+	StaggerIndex  tilemap.StaggerIndex
+	HexSideLength int
+
+	BackgroundColor color.NRGBA
+	Class           string
+	Properties      Properties
+
+	// Tilesets are sorted by FirstGID.
+	Tilesets []*Tileset
+	Layers   []*Layer
+
+	tiles      *tilemap.Map
+	tileLayers map[*Layer]*tilemap.Layer
+	bitmaps    []*allegro.Bitmap
+
+	// originX and originY offset the tiles of an infinite map, whose
+	// tilemap.Map starts at its top-left chunk rather than at tile 0, 0.
+	originX, originY float32
+}
+
+// LayerType is the kind of a layer.
+type LayerType int
+
+const (
+	TileLayer LayerType = iota
+	ObjectGroup
+	ImageLayer
+	Group
 )
 
-type Map *C.ALLEGRO_MAP
+// Layer is a map layer. Which of its fields are used depends on its Type.
+type Layer struct {
+	Type  LayerType
+	ID    int
+	Name  string
+	Class string
+
+	Visible bool
+	Opacity float32
+
+	// OffsetX and OffsetY shift the layer, in pixels.
+	OffsetX, OffsetY float32
+
+	// ParallaxX and ParallaxY are how fast the layer scrolls relative to
+	// the camera, where 1 is normal speed.
+	ParallaxX, ParallaxY float32
+
+	// TintColor is multiplied with the layer's images. Zero is no tint.
+	TintColor color.NRGBA
+
+	Properties Properties
+
+	// X, Y, Width and Height are the area covered by a finite tile layer,
+	// in tiles, and Tiles holds its tiles row by row. Infinite maps store
+	// their tiles in Chunks instead.
+	X, Y, Width, Height int
+	Tiles               []tilemap.Tile
+	Chunks              []Chunk
+
+	// Color is the color that an object group is shown in by the editor.
+	// DrawOrder is either "topdown" or "index".
+	Color     color.NRGBA
+	DrawOrder string
+	Objects   []*Object
+
+	// Image is the picture shown by an image layer, optionally repeated to
+	// fill the view.
+	Image            *Image
+	RepeatX, RepeatY bool
+
+	// Layers are the children of a group, from bottom to top.
+	Layers []*Layer
+}
+
+// Chunk is a block of tiles in an infinite map.
+type Chunk struct {
+	X, Y, Width, Height int
+	Tiles               []tilemap.Tile
+}
 
-func OpenMap(filename string) (*Map, error) {
-	base := filepath.Base(filename)
-	base_ := C.CString(base)
-	defer C.free_string(base_)
-	dir := filename[:base]
-	dir_ := C.CString(dir)
-	defer C.free_string(dir_)
-	m := C.al_open_map(dir_, base_)
-	if m == nil {
-		return nil, fmt.Errorf("failed to load map file: %s", filename)
+// Tile returns the tile at column x, row y of a tile layer, or zero if there
+// is none.
+func (l *Layer) Tile(x, y int) tilemap.Tile {
+	if l.Chunks != nil {
+		for _, c := range l.Chunks {
+			if x >= c.X && y >= c.Y && x < c.X+c.Width && y < c.Y+c.Height {
+				return c.Tiles[(y-c.Y)*c.Width+x-c.X]
+			}
+		}
+		return 0
 	}
-	return (*Map)(m), nil
+	x, y = x-l.X, y-l.Y
+	if x < 0 || y < 0 || x >= l.Width || y >= l.Height {
+		return 0
+	}
+	return l.Tiles[y*l.Width+x]
 }
 
-// TODO: add a bunch of drawing methods
+// Shape is the shape of an object.
+type Shape int
+
+const (
+	ShapeRectangle Shape = iota
+	ShapeEllipse
+	ShapePoint
+	ShapePolygon
+	ShapePolyline
+	ShapeText
+)
 
-func (m *Map) Width() int {
-	return int(C.al_get_map_width((*C.ALLEGRO_MAP)(m)))
+// Point is a vertex of a polygon or polyline, relative to its object.
+type Point struct {
+	X, Y float32
 }
 
-func (m *Map) Height() int {
-	return int(C.al_get_map_height((*C.ALLEGRO_MAP)(m)))
+// Object is an object in an object group.
+type Object struct {
+	ID    int
+	Name  string
+	Class string
+
+	// X and Y are the object's position in pixels. For tile objects, this
+	// is the bottom-left corner of the tile. Rotation is in degrees,
+	// clockwise around X and Y.
+	X, Y, Width, Height float32
+	Rotation            float32
+
+	Visible bool
+	Shape   Shape
+
+	// GID is set for tile objects, which show a tile, including its flip
+	// flags.
+	GID tilemap.Tile
+
+	// Points is the outline of a polygon or polyline.
+	Points []Point
+
+	// Text is the text of a text object.
+	Text *Text
+
+	// Template is the path of the template that the object was created
+	// from, if any. Its contents are not loaded.
+	Template string
+
+	Properties Properties
 }
 
-type Tile *C.ALLEGRO_MAP_TILE
+// Text is the contents and style of a text object.
+type Text struct {
+	Text       string
+	FontFamily string
+	PixelSize  int
+	Color      color.NRGBA
+
+	Wrap, Bold, Italic, Underline, Strikeout, Kerning bool
 
-func (t *Tile) Prop(name, def string) string {
-	name_ := C.CString(name)
-	defer C.free_string(name_)
-	def_ := C.CString(def)
-	defer C.free_string(def_)
-	p := C.al_get_tile_property((*C.ALLEGRO_MAP_TILE)(t), name_, def_)
-	defer C.free_string(p)
-	return C.GoString(p)
+	// HAlign is "left", "center", "right" or "justify", and VAlign "top",
+	// "center" or "bottom".
+	HAlign, VAlign string
 }
 
-type Object *C.ALLEGRO_MAP_OBJECT
+// Image is an image file used by a tileset, tile or image layer.
+type Image struct {
+	// Source is the path of the image file, relative to the current
+	// directory rather than the file that refers to it.
+	Source        string
+	Width, Height int
+
+	// Trans is the color that should be treated as transparent, if its
+	// alpha is non-zero.
+	Trans color.NRGBA
+
+	// Bitmap is the loaded image, set by Map.LoadImages.
+	Bitmap *allegro.Bitmap
+}
+
+// Tileset is a set of tiles, either cut from one image or a collection of
+// separate images.
+type Tileset struct {
+	// FirstGID is the global ID of the tileset's first tile within the map.
+	FirstGID tilemap.Tile
+
+	// Source is the path of the file that the tileset was loaded from, if
+	// it is external to the map.
+	Source string
+
+	Name  string
+	Class string
+
+	TileWidth, TileHeight int
+	Spacing, Margin       int
+	TileCount, Columns    int
+
+	// TileOffsetX and TileOffsetY shift where the tiles are drawn, in
+	// pixels.
+	TileOffsetX, TileOffsetY float32
+	ObjectAlignment          string
+
+	// Image is the image that the tiles are cut from, or nil for a
+	// collection of images.
+	Image *Image
+
+	// Tiles holds the tiles that have their own properties, images,
+	// animations or collision shapes.
+	Tiles []*Tile
+
+	Properties Properties
+}
 
-func (o *Object) Prop(name, def string) string {
-	name_ := C.CString(name)
-	defer C.free_string(name_)
-	def_ := C.CString(def)
-	defer C.free_string(def_)
-	p := C.al_get_tile_property((*C.ALLEGRO_MAP_OBJECT)(o), name_, def_)
-	defer C.free_string(p)
-	return C.GoString(p)
+// Tile returns the data for the tile with a local ID, or nil if the tileset
+// has none.
+func (ts *Tileset) Tile(id int) *Tile {
+	for _, t := range ts.Tiles {
+		if t.ID == id {
+			return t
+		}
+	}
+	return nil
+}
+
+// Tile is the data attached to one tile of a tileset.
+type Tile struct {
+	// ID is the tile's local ID within its tileset.
+	ID    int
+	Class string
+
+	// Image is the tile's own image, in a collection tileset.
+	Image *Image
+
+	// Animation holds the frames of an animated tile, whose Tile fields
+	// are local IDs within the same tileset.
+	Animation []tilemap.Frame
+
+	// ObjectGroup holds the tile's collision shapes.
+	ObjectGroup *Layer
+
+	Properties Properties
+}
+
+// Property is a custom property.
+type Property struct {
+	Name string
+
+	// Type is "string", "int", "float", "bool", "color", "file", "object"
+	// or "class". Value holds the value as text, and Properties the
+	// members of a class.
+	Type       string
+	Value      string
+	Properties Properties
+}
+
+// Properties is a list of custom properties.
+type Properties []Property
+
+// Get returns the property with a name.
+func (p Properties) Get(name string) (Property, bool) {
+	for _, prop := range p {
+		if prop.Name == name {
+			return prop, true
+		}
+	}
+	return Property{}, false
+}
+
+// String returns the value of a property, or def if it is missing.
+func (p Properties) String(name, def string) string {
+	if prop, ok := p.Get(name); ok {
+		return prop.Value
+	}
+	return def
+}
+
+// Int returns the value of a property as an int, or def if it is missing or
+// not a number.
+func (p Properties) Int(name string, def int) int {
+	if prop, ok := p.Get(name); ok {
+		if v, err := strconv.ParseFloat(prop.Value, 64); err == nil {
+			return int(v)
+		}
+	}
+	return def
+}
+
+// Float returns the value of a property as a float64, or def if it is
+// missing or not a number.
+func (p Properties) Float(name string, def float64) float64 {
+	if prop, ok := p.Get(name); ok {
+		if v, err := strconv.ParseFloat(prop.Value, 64); err == nil {
+			return v
+		}
+	}
+	return def
+}
+
+// Bool returns the value of a property as a bool, or def if it is missing or
+// not a bool.
+func (p Properties) Bool(name string, def bool) bool {
+	if prop, ok := p.Get(name); ok {
+		if v, err := strconv.ParseBool(prop.Value); err == nil {
+			return v
+		}
+	}
+	return def
+}
+
+// Load loads a map from a TMX or JSON file.
+func Load(filename string) (*Map, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	m, err := parseMap(data, filepath.Dir(filename))
+	if err != nil {
+		return nil, errors.New(filename + ": " + err.Error())
+	}
+	return m, nil
+}
+
+// Read reads a map in TMX or JSON format. Files that it refers to, such as
+// tilesets and images, are relative to dir.
+func Read(r io.Reader, dir string) (*Map, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return parseMap(data, dir)
+}
+
+// LoadTileset loads a tileset from a TSX or JSON file. Its FirstGID is zero.
+func LoadTileset(filename string) (*Tileset, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var ts *Tileset
+	if isXML(data) {
+		ts, err = parseXMLTileset(data, filepath.Dir(filename))
+	} else {
+		ts, err = parseJSONTileset(data, filepath.Dir(filename))
+	}
+	if err != nil {
+		return nil, errors.New(filename + ": " + err.Error())
+	}
+	ts.Source = filename
+	return ts, nil
+}
+
+func parseMap(data []byte, dir string) (*Map, error) {
+	if isXML(data) {
+		return parseXMLMap(data, dir)
+	}
+	return parseJSONMap(data, dir)
+}
+
+// isXML reports whether data looks like XML rather than JSON.
+func isXML(data []byte) bool {
+	data = bytes.TrimSpace(data)
+	return len(data) > 0 && data[0] == '<'
+}
+
+// resolve returns the path of a file referred to from dir.
+func resolve(dir, path string) string {
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, filepath.FromSlash(path))
 }
@@ -0,0 +1,243 @@
+package tiled
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dradtke/go-allegro/allegro/tilemap"
+	"github.com/klauspost/compress/zstd"
+)
+
+var gids = []uint32{1, 2, 0, 3, 0x80000001, 5}
+
+// encode returns gids in base64 with a compression.
+func encode(t *testing.T, compression string) string {
+	var raw bytes.Buffer
+	for _, g := range gids {
+		binary.Write(&raw, binary.LittleEndian, g)
+	}
+	var buf bytes.Buffer
+	var w io.WriteCloser
+	switch compression {
+	case "":
+		buf = raw
+	case "zlib":
+		w = zlib.NewWriter(&buf)
+	case "gzip":
+		w = gzip.NewWriter(&buf)
+	case "zstd":
+		var err error
+		if w, err = zstd.NewWriter(&buf); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if w != nil {
+		w.Write(raw.Bytes())
+		w.Close()
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes())
+}
+
+func checkTiles(t *testing.T, name string, tiles []tilemap.Tile) {
+	if len(tiles) != len(gids) {
+		t.Fatalf("%s: got %d tiles, want %d", name, len(tiles), len(gids))
+	}
+	for i, g := range gids {
+		if tiles[i] != tilemap.Tile(g) {
+			t.Errorf("%s: tile %d is %#x, want %#x", name, i, tiles[i], g)
+		}
+	}
+}
+
+func TestTMX(t *testing.T) {
+	var layers strings.Builder
+	for _, c := range []string{"", "zlib", "gzip", "zstd"} {
+		fmt.Fprintf(&layers, `<layer name="%s" width="3" height="2"><data encoding="base64" compression="%s">%s</data></layer>`, c, c, encode(t, c))
+	}
+	m, err := Read(strings.NewReader(`<?xml version="1.0" encoding="UTF-8"?>
+<map version="1.10" orientation="hexagonal" width="3" height="2" tilewidth="28" tileheight="32" hexsidelength="16" staggeraxis="x" staggerindex="even" backgroundcolor="#80ff0000">
+ <properties>
+  <property name="title" value="Test"/>
+  <property name="gravity" type="float" value="9.5"/>
+  <property name="spawn" type="class">
+   <properties><property name="x" type="int" value="4"/></properties>
+  </property>
+ </properties>
+ <tileset firstgid="10" name="items" tilewidth="16" tileheight="16" tilecount="2" columns="0">
+  <tile id="1"><image source="img/key.png" width="16" height="16"/></tile>
+ </tileset>
+ <tileset firstgid="1" name="ground" tilewidth="28" tileheight="32" tilecount="8" columns="4">
+  <image source="ground.png" trans="ff00ff" width="112" height="64"/>
+  <tile id="2" type="water">
+   <animation><frame tileid="2" duration="100"/><frame tileid="3" duration="250"/></animation>
+  </tile>
+ </tileset>
+ <layer name="csv" width="3" height="2"><data encoding="csv">
+1,2,0,
+3,2147483649,5
+</data></layer>
+ <layer name="xml" width="3" height="2"><data><tile gid="1"/><tile gid="2"/><tile/><tile gid="3"/><tile gid="2147483649"/><tile gid="5"/></data></layer>
+ `+layers.String()+`
+ <group name="things" offsetx="5" opacity="0.5">
+  <objectgroup name="objects" visible="0">
+   <object id="1" name="door" type="door" x="10" y="20" width="16" height="16" gid="11"/>
+   <object id="2" x="1" y="2"><polygon points="0,0 4.5,0 4.5,-3"/></object>
+   <object id="3" x="0" y="0"><text wrap="1" color="#00ff00">Hello</text></object>
+   <object id="4" x="0" y="0"><ellipse/></object>
+  </objectgroup>
+  <imagelayer name="sky" repeatx="1"><image source="sky.png"/></imagelayer>
+ </group>
+</map>`), "maps")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Orientation != tilemap.Staggered || m.HexSideLength != 16 || m.StaggerAxis != tilemap.StaggerX || m.StaggerIndex != tilemap.StaggerEven {
+		t.Errorf("map shape is %+v", m)
+	}
+	if c := m.BackgroundColor; c.R != 0xFF || c.A != 0x80 {
+		t.Errorf("background color is %v", c)
+	}
+	if m.Properties.String("title", "") != "Test" || m.Properties.Float("gravity", 0) != 9.5 || m.Properties.Int("missing", 7) != 7 {
+		t.Errorf("properties are %+v", m.Properties)
+	}
+	if spawn, _ := m.Properties.Get("spawn"); spawn.Properties.Int("x", 0) != 4 {
+		t.Errorf("class property is %+v", spawn)
+	}
+
+	if len(m.Tilesets) != 2 || m.Tilesets[0].Name != "ground" {
+		t.Fatalf("tilesets are not sorted")
+	}
+	ground := m.Tilesets[0]
+	if img := ground.Image; img.Source != filepath.Join("maps", "ground.png") || img.Trans.R != 0xFF || img.Trans.A != 0xFF {
+		t.Errorf("tileset image is %+v", img)
+	}
+	if tile := ground.Tile(2); tile == nil || tile.Class != "water" || len(tile.Animation) != 2 || tile.Animation[1].Duration != 0.25 {
+		t.Errorf("animated tile is %+v", tile)
+	}
+	if ts, id := m.Tileset(11 | tilemap.FlipVertical); ts != m.Tilesets[1] || id != 1 {
+		t.Errorf("tile 11 is %d of %p", id, ts)
+	}
+
+	for _, l := range m.Layers[:6] {
+		checkTiles(t, l.Name, l.Tiles)
+	}
+	group := m.Layers[6]
+	if group.Type != Group || len(group.Layers) != 2 || group.Opacity != 0.5 {
+		t.Fatalf("group is %+v", group)
+	}
+	objects := group.Layers[0]
+	if objects.Visible || len(objects.Objects) != 4 || objects.DrawOrder != "topdown" {
+		t.Fatalf("object group is %+v", objects)
+	}
+	if o := objects.Objects[0]; o.Name != "door" || o.Class != "door" || o.GID != 11 || o.Shape != ShapeRectangle {
+		t.Errorf("tile object is %+v", o)
+	}
+	if o := objects.Objects[1]; o.Shape != ShapePolygon || len(o.Points) != 3 || o.Points[2] != (Point{4.5, -3}) {
+		t.Errorf("polygon is %+v", o)
+	}
+	if o := objects.Objects[2]; o.Shape != ShapeText || o.Text.Text != "Hello" || !o.Text.Wrap || o.Text.PixelSize != 16 || o.Text.Color.G != 0xFF {
+		t.Errorf("text is %+v", o.Text)
+	}
+	if o := objects.Objects[3]; o.Shape != ShapeEllipse {
+		t.Errorf("ellipse is %+v", o)
+	}
+	if sky := group.Layers[1]; sky.Type != ImageLayer || !sky.RepeatX || sky.Image.Source != filepath.Join("maps", "sky.png") {
+		t.Errorf("image layer is %+v", sky)
+	}
+}
+
+func TestJSON(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tiled")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	tsx := `<tileset name="external" tilewidth="16" tileheight="16" tilecount="16" columns="4"><image source="tiles.png" width="64" height="64"/></tileset>`
+	if err := ioutil.WriteFile(filepath.Join(dir, "external.tsx"), []byte(tsx), 0644); err != nil {
+		t.Fatal(err)
+	}
+	data := `{
+		"type": "map", "orientation": "orthogonal", "infinite": true,
+		"width": 4, "height": 4, "tilewidth": 16, "tileheight": 16,
+		"properties": [{"name": "spawn", "type": "class", "value": {"x": 3, "name": "start"}}],
+		"tilesets": [{"firstgid": 1, "source": "external.tsx"}],
+		"layers": [
+			{"type": "tilelayer", "name": "ground", "chunks": [
+				{"x": -16, "y": 0, "width": 3, "height": 2, "data": [1, 2, 0, 3, 2147483649, 5]},
+				{"x": 0, "y": 16, "width": 3, "height": 2, "data": "` + encode(t, "zstd") + `"}
+			], "encoding": "base64", "compression": "zstd"},
+			{"type": "objectgroup", "name": "objects", "draworder": "index", "objects": [
+				{"id": 1, "x": 1, "y": 2, "polyline": [{"x": 0, "y": 0}, {"x": 3, "y": 4}], "visible": false}
+			]}
+		]
+	}`
+	if err := ioutil.WriteFile(filepath.Join(dir, "map.json"), []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	m, err := Load(filepath.Join(dir, "map.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if spawn, _ := m.Properties.Get("spawn"); spawn.Properties.Int("x", 0) != 3 || spawn.Properties.String("name", "") != "start" {
+		t.Errorf("class property is %+v", spawn)
+	}
+	ts := m.Tilesets[0]
+	if ts.Name != "external" || ts.FirstGID != 1 || ts.Source != filepath.Join(dir, "external.tsx") || ts.Image.Source != filepath.Join(dir, "tiles.png") {
+		t.Errorf("external tileset is %+v", ts)
+	}
+	ground := m.Layers[0]
+	if len(ground.Chunks) != 2 {
+		t.Fatalf("got %d chunks", len(ground.Chunks))
+	}
+	for _, c := range ground.Chunks {
+		checkTiles(t, "chunk", c.Tiles)
+	}
+	if tile := ground.Tile(-15, 0); tile != 2 {
+		t.Errorf("tile -15, 0 is %d, want 2", tile)
+	}
+	if tile := ground.Tile(2, 17); tile != 5 {
+		t.Errorf("tile 2, 17 is %d, want 5", tile)
+	}
+	if o := m.Layers[1].Objects[0]; o.Shape != ShapePolyline || o.Visible || o.Points[1] != (Point{3, 4}) {
+		t.Errorf("polyline is %+v", o)
+	}
+
+	tm := m.Tilemap()
+	if tm.Width != 19 || tm.Height != 18 {
+		t.Errorf("tilemap is %dx%d, want 19x18", tm.Width, tm.Height)
+	}
+	l := tm.Layer("ground")
+	if l.OffsetX != -16*16 || l.OffsetY != 0 {
+		t.Errorf("tilemap layer is offset by %v, %v", l.OffsetX, l.OffsetY)
+	}
+	if tile := l.Tile(1, 0); tile != 2 {
+		t.Errorf("tilemap tile 1, 0 is %d, want 2", tile)
+	}
+	if tile := l.Tile(18, 17); tile != 5 {
+		t.Errorf("tilemap tile 18, 17 is %d, want 5", tile)
+	}
+}
+
+func TestBadData(t *testing.T) {
+	for _, data := range []string{
+		`<map orientation="weird"/>`,
+		`<map width="2" height="1"><layer width="2" height="1"><data encoding="csv">1</data></layer></map>`,
+		`<map width="2" height="1"><layer width="2" height="1"><data encoding="base64" compression="lzma">AAAA</data></layer></map>`,
+		`{"layers": [{"type": "tilelayer", "width": 1, "height": 1, "data": [1, 2]}]}`,
+		`{"backgroundcolor": "#12"}`,
+	} {
+		if _, err := Read(strings.NewReader(data), ""); err == nil {
+			t.Errorf("no error for %s", data)
+		}
+	}
+}
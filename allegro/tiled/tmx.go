@@ -0,0 +1,457 @@
+package tiled
+
+import (
+	"encoding/xml"
+	"fmt"
+	"image/color"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/dradtke/go-allegro/allegro/tilemap"
+)
+
+type xmlMap struct {
+	Orientation     string        `xml:"orientation,attr"`
+	RenderOrder     string        `xml:"renderorder,attr"`
+	Class           string        `xml:"class,attr"`
+	Width           int           `xml:"width,attr"`
+	Height          int           `xml:"height,attr"`
+	TileWidth       int           `xml:"tilewidth,attr"`
+	TileHeight      int           `xml:"tileheight,attr"`
+	HexSideLength   int           `xml:"hexsidelength,attr"`
+	StaggerAxis     string        `xml:"staggeraxis,attr"`
+	StaggerIndex    string        `xml:"staggerindex,attr"`
+	Infinite        int           `xml:"infinite,attr"`
+	BackgroundColor string        `xml:"backgroundcolor,attr"`
+	Properties      []xmlProperty `xml:"properties>property"`
+	Tilesets        []xmlTileset  `xml:"tileset"`
+	Layers          []xmlLayer    `xml:",any"`
+}
+
+type xmlProperty struct {
+	Name       string        `xml:"name,attr"`
+	Type       string        `xml:"type,attr"`
+	Value      *string       `xml:"value,attr"`
+	Text       string        `xml:",chardata"`
+	Properties []xmlProperty `xml:"properties>property"`
+}
+
+type xmlImage struct {
+	Source string `xml:"source,attr"`
+	Trans  string `xml:"trans,attr"`
+	Width  int    `xml:"width,attr"`
+	Height int    `xml:"height,attr"`
+}
+
+type xmlTileset struct {
+	FirstGID        uint32        `xml:"firstgid,attr"`
+	Source          string        `xml:"source,attr"`
+	Name            string        `xml:"name,attr"`
+	Class           string        `xml:"class,attr"`
+	TileWidth       int           `xml:"tilewidth,attr"`
+	TileHeight      int           `xml:"tileheight,attr"`
+	Spacing         int           `xml:"spacing,attr"`
+	Margin          int           `xml:"margin,attr"`
+	TileCount       int           `xml:"tilecount,attr"`
+	Columns         int           `xml:"columns,attr"`
+	ObjectAlignment string        `xml:"objectalignment,attr"`
+	TileOffset      xmlPoint      `xml:"tileoffset"`
+	Image           *xmlImage     `xml:"image"`
+	Tiles           []xmlTile     `xml:"tile"`
+	Properties      []xmlProperty `xml:"properties>property"`
+}
+
+type xmlTile struct {
+	ID          int           `xml:"id,attr"`
+	Type        string        `xml:"type,attr"`
+	Class       string        `xml:"class,attr"`
+	Image       *xmlImage     `xml:"image"`
+	Animation   []xmlFrame    `xml:"animation>frame"`
+	ObjectGroup *xmlLayer     `xml:"objectgroup"`
+	Properties  []xmlProperty `xml:"properties>property"`
+}
+
+type xmlFrame struct {
+	TileID   int `xml:"tileid,attr"`
+	Duration int `xml:"duration,attr"`
+}
+
+type xmlPoint struct {
+	X float32 `xml:"x,attr"`
+	Y float32 `xml:"y,attr"`
+}
+
+type xmlLayer struct {
+	XMLName    xml.Name
+	ID         int           `xml:"id,attr"`
+	Name       string        `xml:"name,attr"`
+	Class      string        `xml:"class,attr"`
+	Visible    *int          `xml:"visible,attr"`
+	Opacity    *float32      `xml:"opacity,attr"`
+	OffsetX    float32       `xml:"offsetx,attr"`
+	OffsetY    float32       `xml:"offsety,attr"`
+	ParallaxX  *float32      `xml:"parallaxx,attr"`
+	ParallaxY  *float32      `xml:"parallaxy,attr"`
+	TintColor  string        `xml:"tintcolor,attr"`
+	Properties []xmlProperty `xml:"properties>property"`
+
+	X      int      `xml:"x,attr"`
+	Y      int      `xml:"y,attr"`
+	Width  int      `xml:"width,attr"`
+	Height int      `xml:"height,attr"`
+	Data   *xmlData `xml:"data"`
+
+	Color     string      `xml:"color,attr"`
+	DrawOrder string      `xml:"draworder,attr"`
+	Objects   []xmlObject `xml:"object"`
+
+	Image   *xmlImage `xml:"image"`
+	RepeatX int       `xml:"repeatx,attr"`
+	RepeatY int       `xml:"repeaty,attr"`
+
+	Layers []xmlLayer `xml:",any"`
+}
+
+type xmlData struct {
+	Encoding    string     `xml:"encoding,attr"`
+	Compression string     `xml:"compression,attr"`
+	Text        string     `xml:",chardata"`
+	Tiles       []xmlGID   `xml:"tile"`
+	Chunks      []xmlChunk `xml:"chunk"`
+}
+
+type xmlChunk struct {
+	X      int      `xml:"x,attr"`
+	Y      int      `xml:"y,attr"`
+	Width  int      `xml:"width,attr"`
+	Height int      `xml:"height,attr"`
+	Text   string   `xml:",chardata"`
+	Tiles  []xmlGID `xml:"tile"`
+}
+
+type xmlGID struct {
+	GID uint32 `xml:"gid,attr"`
+}
+
+type xmlObject struct {
+	ID         int           `xml:"id,attr"`
+	Name       string        `xml:"name,attr"`
+	Type       string        `xml:"type,attr"`
+	Class      string        `xml:"class,attr"`
+	X          float32       `xml:"x,attr"`
+	Y          float32       `xml:"y,attr"`
+	Width      float32       `xml:"width,attr"`
+	Height     float32       `xml:"height,attr"`
+	Rotation   float32       `xml:"rotation,attr"`
+	GID        uint32        `xml:"gid,attr"`
+	Visible    *int          `xml:"visible,attr"`
+	Template   string        `xml:"template,attr"`
+	Ellipse    *struct{}     `xml:"ellipse"`
+	Point      *struct{}     `xml:"point"`
+	Polygon    *xmlPoints    `xml:"polygon"`
+	Polyline   *xmlPoints    `xml:"polyline"`
+	Text       *xmlText      `xml:"text"`
+	Properties []xmlProperty `xml:"properties>property"`
+}
+
+type xmlPoints struct {
+	Points string `xml:"points,attr"`
+}
+
+type xmlText struct {
+	Text       string `xml:",chardata"`
+	FontFamily string `xml:"fontfamily,attr"`
+	PixelSize  *int   `xml:"pixelsize,attr"`
+	Wrap       int    `xml:"wrap,attr"`
+	Color      string `xml:"color,attr"`
+	Bold       int    `xml:"bold,attr"`
+	Italic     int    `xml:"italic,attr"`
+	Underline  int    `xml:"underline,attr"`
+	Strikeout  int    `xml:"strikeout,attr"`
+	Kerning    *int   `xml:"kerning,attr"`
+	HAlign     string `xml:"halign,attr"`
+	VAlign     string `xml:"valign,attr"`
+}
+
+func parseXMLMap(data []byte, dir string) (*Map, error) {
+	var x xmlMap
+	if err := xml.Unmarshal(data, &x); err != nil {
+		return nil, err
+	}
+	d := &decoder{dir: dir}
+	m := &Map{
+		RenderOrder:     x.RenderOrder,
+		Width:           x.Width,
+		Height:          x.Height,
+		TileWidth:       x.TileWidth,
+		TileHeight:      x.TileHeight,
+		Infinite:        x.Infinite != 0,
+		HexSideLength:   x.HexSideLength,
+		BackgroundColor: d.color(x.BackgroundColor),
+		Class:           x.Class,
+		Properties:      xmlProperties(x.Properties),
+	}
+	if err := m.setOrientation(x.Orientation, x.StaggerAxis, x.StaggerIndex); err != nil {
+		return nil, err
+	}
+	for i := range x.Tilesets {
+		t := &x.Tilesets[i]
+		if t.Source != "" {
+			m.Tilesets = append(m.Tilesets, d.tileset(t.Source, tilemap.Tile(t.FirstGID)))
+		} else {
+			m.Tilesets = append(m.Tilesets, d.xmlTileset(t))
+		}
+	}
+	sort.SliceStable(m.Tilesets, func(i, j int) bool {
+		return m.Tilesets[i].FirstGID < m.Tilesets[j].FirstGID
+	})
+	m.Layers = d.xmlLayers(x.Layers)
+	if d.err != nil {
+		return nil, d.err
+	}
+	return m, nil
+}
+
+func parseXMLTileset(data []byte, dir string) (*Tileset, error) {
+	var x xmlTileset
+	if err := xml.Unmarshal(data, &x); err != nil {
+		return nil, err
+	}
+	d := &decoder{dir: dir}
+	ts := d.xmlTileset(&x)
+	if d.err != nil {
+		return nil, d.err
+	}
+	return ts, nil
+}
+
+func (d *decoder) xmlTileset(x *xmlTileset) *Tileset {
+	ts := &Tileset{
+		FirstGID:        tilemap.Tile(x.FirstGID),
+		Name:            x.Name,
+		Class:           x.Class,
+		TileWidth:       x.TileWidth,
+		TileHeight:      x.TileHeight,
+		Spacing:         x.Spacing,
+		Margin:          x.Margin,
+		TileCount:       x.TileCount,
+		Columns:         x.Columns,
+		TileOffsetX:     x.TileOffset.X,
+		TileOffsetY:     x.TileOffset.Y,
+		ObjectAlignment: x.ObjectAlignment,
+		Image:           d.xmlImage(x.Image),
+		Properties:      xmlProperties(x.Properties),
+	}
+	for _, xt := range x.Tiles {
+		t := &Tile{
+			ID:         xt.ID,
+			Class:      xt.Class,
+			Image:      d.xmlImage(xt.Image),
+			Properties: xmlProperties(xt.Properties),
+		}
+		if t.Class == "" {
+			t.Class = xt.Type
+		}
+		for _, f := range xt.Animation {
+			t.Animation = append(t.Animation, tilemap.Frame{Tile: f.TileID, Duration: float64(f.Duration) / 1000})
+		}
+		if xt.ObjectGroup != nil {
+			t.ObjectGroup = d.xmlLayer(xt.ObjectGroup)
+		}
+		ts.Tiles = append(ts.Tiles, t)
+	}
+	return ts
+}
+
+func (d *decoder) xmlImage(x *xmlImage) *Image {
+	if x == nil {
+		return nil
+	}
+	return d.image(x.Source, x.Width, x.Height, x.Trans)
+}
+
+func (d *decoder) xmlLayers(xs []xmlLayer) []*Layer {
+	var layers []*Layer
+	for i := range xs {
+		if l := d.xmlLayer(&xs[i]); l != nil {
+			layers = append(layers, l)
+		}
+	}
+	return layers
+}
+
+// xmlLayer converts a layer element, or returns nil if the element isn't a
+// layer.
+func (d *decoder) xmlLayer(x *xmlLayer) *Layer {
+	l := &Layer{
+		ID:         x.ID,
+		Name:       x.Name,
+		Class:      x.Class,
+		Visible:    x.Visible == nil || *x.Visible != 0,
+		Opacity:    1,
+		OffsetX:    x.OffsetX,
+		OffsetY:    x.OffsetY,
+		ParallaxX:  1,
+		ParallaxY:  1,
+		TintColor:  d.color(x.TintColor),
+		Properties: xmlProperties(x.Properties),
+	}
+	if x.Opacity != nil {
+		l.Opacity = *x.Opacity
+	}
+	if x.ParallaxX != nil {
+		l.ParallaxX = *x.ParallaxX
+	}
+	if x.ParallaxY != nil {
+		l.ParallaxY = *x.ParallaxY
+	}
+	switch x.XMLName.Local {
+	case "layer":
+		l.Type = TileLayer
+		l.X, l.Y, l.Width, l.Height = x.X, x.Y, x.Width, x.Height
+		if x.Data == nil {
+			l.Tiles = make([]tilemap.Tile, l.Width*l.Height)
+			break
+		}
+		if x.Data.Chunks != nil {
+			for _, c := range x.Data.Chunks {
+				l.Chunks = append(l.Chunks, Chunk{
+					X: c.X, Y: c.Y, Width: c.Width, Height: c.Height,
+					Tiles: d.xmlTiles(x.Data, c.Text, c.Tiles, c.Width*c.Height),
+				})
+			}
+			break
+		}
+		l.Tiles = d.xmlTiles(x.Data, x.Data.Text, x.Data.Tiles, l.Width*l.Height)
+	case "objectgroup":
+		l.Type = ObjectGroup
+		l.Color = d.color(x.Color)
+		l.DrawOrder = x.DrawOrder
+		if l.DrawOrder == "" {
+			l.DrawOrder = "topdown"
+		}
+		for i := range x.Objects {
+			l.Objects = append(l.Objects, d.xmlObject(&x.Objects[i]))
+		}
+	case "imagelayer":
+		l.Type = ImageLayer
+		l.Image = d.xmlImage(x.Image)
+		l.RepeatX, l.RepeatY = x.RepeatX != 0, x.RepeatY != 0
+	case "group":
+		l.Type = Group
+		l.Layers = d.xmlLayers(x.Layers)
+	default:
+		return nil
+	}
+	return l
+}
+
+// xmlTiles decodes the tiles of a layer or chunk.
+func (d *decoder) xmlTiles(data *xmlData, text string, gids []xmlGID, n int) []tilemap.Tile {
+	if data.Encoding != "" {
+		return d.tiles(text, data.Encoding, data.Compression, n)
+	}
+	if len(gids) != n {
+		d.fail(fmt.Errorf("got %d tiles, want %d", len(gids), n))
+		return nil
+	}
+	tiles := make([]tilemap.Tile, n)
+	for i, t := range gids {
+		tiles[i] = gid(t.GID)
+	}
+	return tiles
+}
+
+func (d *decoder) xmlObject(x *xmlObject) *Object {
+	o := &Object{
+		ID:         x.ID,
+		Name:       x.Name,
+		Class:      x.Class,
+		X:          x.X,
+		Y:          x.Y,
+		Width:      x.Width,
+		Height:     x.Height,
+		Rotation:   x.Rotation,
+		Visible:    x.Visible == nil || *x.Visible != 0,
+		GID:        gid(x.GID),
+		Template:   resolve(d.dir, x.Template),
+		Properties: xmlProperties(x.Properties),
+	}
+	if o.Class == "" {
+		o.Class = x.Type
+	}
+	switch {
+	case x.Ellipse != nil:
+		o.Shape = ShapeEllipse
+	case x.Point != nil:
+		o.Shape = ShapePoint
+	case x.Polygon != nil:
+		o.Shape = ShapePolygon
+		o.Points = d.points(x.Polygon.Points)
+	case x.Polyline != nil:
+		o.Shape = ShapePolyline
+		o.Points = d.points(x.Polyline.Points)
+	case x.Text != nil:
+		o.Shape = ShapeText
+		t := x.Text
+		o.Text = &Text{
+			Text:       t.Text,
+			FontFamily: t.FontFamily,
+			PixelSize:  16,
+			Color:      color.NRGBA{A: 0xFF},
+			Wrap:       t.Wrap != 0,
+			Bold:       t.Bold != 0,
+			Italic:     t.Italic != 0,
+			Underline:  t.Underline != 0,
+			Strikeout:  t.Strikeout != 0,
+			Kerning:    t.Kerning == nil || *t.Kerning != 0,
+			HAlign:     t.HAlign,
+			VAlign:     t.VAlign,
+		}
+		if t.PixelSize != nil {
+			o.Text.PixelSize = *t.PixelSize
+		}
+		if t.Color != "" {
+			o.Text.Color = d.color(t.Color)
+		}
+	}
+	return o
+}
+
+// points parses a list of points in the form "x1,y1 x2,y2 ...".
+func (d *decoder) points(s string) []Point {
+	var points []Point
+	for _, pair := range strings.Fields(s) {
+		i := strings.IndexByte(pair, ',')
+		if i < 0 {
+			d.fail(fmt.Errorf("invalid point %q", pair))
+			return nil
+		}
+		x, err1 := strconv.ParseFloat(pair[:i], 32)
+		y, err2 := strconv.ParseFloat(pair[i+1:], 32)
+		if err1 != nil || err2 != nil {
+			d.fail(fmt.Errorf("invalid point %q", pair))
+			return nil
+		}
+		points = append(points, Point{float32(x), float32(y)})
+	}
+	return points
+}
+
+func xmlProperties(xs []xmlProperty) Properties {
+	var props Properties
+	for _, x := range xs {
+		p := Property{Name: x.Name, Type: x.Type, Properties: xmlProperties(x.Properties)}
+		if p.Type == "" {
+			p.Type = "string"
+		}
+		if x.Value != nil {
+			p.Value = *x.Value
+		} else if x.Properties == nil {
+			p.Value = x.Text
+		}
+		props = append(props, p)
+	}
+	return props
+}
@@ -0,0 +1,179 @@
+// Package autotile picks tiles for a tilemap layer from painted terrain.
+//
+// An Autotiler keeps a grid of terrain types alongside a layer. Painting a
+// cell changes its terrain, and the cell and its eight neighbors are given
+// new tiles by the ruleset of their terrain, based on which of their
+// neighbors share it:
+//
+//	rules, err := autotile.Load("terrain.json")
+//	...
+//	at := autotile.New(m.Layer("ground"), rules)
+//	at.Paint(x, y, grass)
+//
+// Rulesets can be 16-tile Wang edge or corner sets, 47-tile blob sets, or
+// lists of custom neighbor patterns.
+package autotile
+
+import (
+	"github.com/dradtke/go-allegro/allegro/tilemap"
+)
+
+// Terrain is a kind of ground, such as grass or water. Zero is no terrain.
+type Terrain int
+
+// Neighbors is a set of directions, used to record which of a cell's
+// neighbors share its terrain.
+type Neighbors uint8
+
+const (
+	North Neighbors = 1 << iota
+	NorthEast
+	East
+	SouthEast
+	South
+	SouthWest
+	West
+	NorthWest
+
+	All   = North | NorthEast | East | SouthEast | South | SouthWest | West | NorthWest
+	Edges = North | East | South | West
+)
+
+// offsets holds the position of each neighbor, in the order of their bits.
+var offsets = [8][2]int{{0, -1}, {1, -1}, {1, 0}, {1, 1}, {0, 1}, {-1, 1}, {-1, 0}, {-1, -1}}
+
+// Reduce removes diagonal neighbors unless both of the edge neighbors next
+// to them are set too, since a corner only affects a tile's shape when both
+// of its sides are filled.
+func (n Neighbors) Reduce() Neighbors {
+	for _, c := range [4][3]Neighbors{
+		{NorthEast, North, East},
+		{SouthEast, South, East},
+		{SouthWest, South, West},
+		{NorthWest, North, West},
+	} {
+		if n&c[1] == 0 || n&c[2] == 0 {
+			n &^= c[0]
+		}
+	}
+	return n
+}
+
+// Ruleset chooses the tile for a cell.
+type Ruleset interface {
+	// Tile returns the tile for the cell at x, y, given which of its
+	// neighbors share its terrain, or zero for none.
+	Tile(n Neighbors, x, y int) tilemap.Tile
+}
+
+// Autotiler keeps a layer's tiles in sync with a grid of terrain.
+type Autotiler struct {
+	Layer *tilemap.Layer
+
+	// Rules holds the ruleset for each terrain. Cells whose terrain has no
+	// ruleset are left empty.
+	Rules map[Terrain]Ruleset
+
+	// Match, if set, reports whether a neighbor with terrain other
+	// blends with a cell of terrain t. By default, only the same terrain
+	// does.
+	Match func(t, other Terrain) bool
+
+	// BorderOutside makes the area beyond the edges of the layer count as
+	// a different terrain, so that terrain gets a border there. By default
+	// it matches, as if the terrain carried on.
+	BorderOutside bool
+
+	width, height int
+	terrain       []Terrain
+}
+
+// New creates an autotiler for a layer, with no terrain painted.
+func New(layer *tilemap.Layer, rules map[Terrain]Ruleset) *Autotiler {
+	w, h := layer.Map().Width, layer.Map().Height
+	return &Autotiler{
+		Layer:   layer,
+		Rules:   rules,
+		width:   w,
+		height:  h,
+		terrain: make([]Terrain, w*h),
+	}
+}
+
+// Terrain returns the terrain at x, y, or zero if that is outside of the
+// layer.
+func (a *Autotiler) Terrain(x, y int) Terrain {
+	if !a.inside(x, y) {
+		return 0
+	}
+	return a.terrain[y*a.width+x]
+}
+
+// Paint sets the terrain at x, y and updates the tiles around it.
+func (a *Autotiler) Paint(x, y int, t Terrain) {
+	a.Fill(x, y, 1, 1, t)
+}
+
+// Fill sets the terrain of a rectangle of cells and updates the tiles
+// around it.
+func (a *Autotiler) Fill(x, y, w, h int, t Terrain) {
+	for j := y; j < y+h; j++ {
+		for i := x; i < x+w; i++ {
+			if a.inside(i, j) {
+				a.terrain[j*a.width+i] = t
+			}
+		}
+	}
+	a.Update(x-1, y-1, w+2, h+2)
+}
+
+// SetTerrain replaces all of the terrain, row by row, and updates every
+// tile. It is meant for loading levels and for procedural generators.
+func (a *Autotiler) SetTerrain(terrain []Terrain) {
+	copy(a.terrain, terrain)
+	a.Update(0, 0, a.width, a.height)
+}
+
+// Update recomputes the tiles of a rectangle of cells. It only needs to be
+// called directly after changing Rules or Match.
+func (a *Autotiler) Update(x, y, w, h int) {
+	for j := y; j < y+h; j++ {
+		for i := x; i < x+w; i++ {
+			if !a.inside(i, j) {
+				continue
+			}
+			var tile tilemap.Tile
+			t := a.terrain[j*a.width+i]
+			if rules, ok := a.Rules[t]; ok && t != 0 {
+				tile = rules.Tile(a.Neighbors(i, j), i, j)
+			}
+			a.Layer.SetTile(i, j, tile)
+		}
+	}
+}
+
+// Neighbors returns which of the neighbors of x, y blend with its terrain.
+func (a *Autotiler) Neighbors(x, y int) Neighbors {
+	t := a.Terrain(x, y)
+	var n Neighbors
+	for i, o := range offsets {
+		nx, ny := x+o[0], y+o[1]
+		var match bool
+		switch {
+		case !a.inside(nx, ny):
+			match = !a.BorderOutside
+		case a.Match != nil:
+			match = a.Match(t, a.terrain[ny*a.width+nx])
+		default:
+			match = a.terrain[ny*a.width+nx] == t
+		}
+		if match {
+			n |= 1 << uint(i)
+		}
+	}
+	return n
+}
+
+func (a *Autotiler) inside(x, y int) bool {
+	return x >= 0 && y >= 0 && x < a.width && y < a.height
+}
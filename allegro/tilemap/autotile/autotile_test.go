@@ -0,0 +1,74 @@
+package autotile
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/dradtke/go-allegro/allegro/tilemap"
+)
+
+func TestBlobMasks(t *testing.T) {
+	masks := BlobMasks()
+	if len(masks) != 47 {
+		t.Fatalf("got %d blob masks, want 47", len(masks))
+	}
+	if masks[0] != 0 || masks[46] != All {
+		t.Errorf("blob masks run from %#x to %#x, want 0 to %#x", masks[0], masks[46], All)
+	}
+	if r := (North | NorthEast | South).Reduce(); r != North|South {
+		t.Errorf("reduced to %#x, want %#x", r, North|South)
+	}
+}
+
+func TestParsePattern(t *testing.T) {
+	care, match, err := ParsePattern([]string{"?1?", "0.0", "?1?"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if care != Edges || match != North|South {
+		t.Errorf("care %#x, match %#x", care, match)
+	}
+	if _, _, err := ParsePattern([]string{"?1?", "0x0", "?1"}); err == nil {
+		t.Error("short row was accepted")
+	}
+}
+
+func TestPaint(t *testing.T) {
+	rules, err := Read(strings.NewReader(`{"terrains": [
+		{"id": 1, "name": "grass", "type": "edge", "first": 1},
+		{"id": 2, "name": "wall", "type": "rules", "rules": [
+			{"pattern": ["???", "0.0", "???"], "tiles": [100]},
+			{"pattern": ["???", "?.?", "???"], "tiles": [101, 102]}
+		]}
+	]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := tilemap.New(tilemap.Orthogonal, 5, 5, 16, 16)
+	a := New(m.AddLayer("ground"), rules)
+	a.BorderOutside = true
+
+	a.Paint(2, 2, 1)
+	if tile := a.Layer.Tile(2, 2); tile != 1 {
+		t.Errorf("lone grass is tile %d, want 1", tile)
+	}
+	a.Paint(2, 1, 1)
+	if tile := a.Layer.Tile(2, 2); tile != 1+1 {
+		t.Errorf("grass with a northern neighbor is tile %d, want 2", tile)
+	}
+	if tile := a.Layer.Tile(2, 1); tile != 1+4 {
+		t.Errorf("grass with a southern neighbor is tile %d, want 5", tile)
+	}
+
+	a.Fill(0, 4, 5, 1, 2)
+	if tile := a.Layer.Tile(2, 4); tile != 101 && tile != 102 {
+		t.Errorf("wall in a row is tile %d, want 101 or 102", tile)
+	}
+	a.Paint(2, 4, 0)
+	if tile := a.Layer.Tile(2, 4); tile != 0 {
+		t.Errorf("erased cell is tile %d", tile)
+	}
+	if tile := a.Layer.Tile(1, 4); tile != 101 && tile != 102 {
+		t.Errorf("wall with a western neighbor is tile %d, want 101 or 102", tile)
+	}
+}
@@ -0,0 +1,153 @@
+package autotile
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/dradtke/go-allegro/allegro/tilemap"
+)
+
+// file is the layout of a ruleset data file.
+type file struct {
+	Terrains []struct {
+		ID    Terrain        `json:"id"`
+		Name  string         `json:"name"`
+		Type  string         `json:"type"`
+		Tiles []tilemap.Tile `json:"tiles"`
+		First tilemap.Tile   `json:"first"`
+		Rules []struct {
+			Pattern []string       `json:"pattern"`
+			Tiles   []tilemap.Tile `json:"tiles"`
+		} `json:"rules"`
+	} `json:"terrains"`
+}
+
+// Load reads rulesets from a JSON file. See Read for the format.
+func Load(filename string) (map[Terrain]Ruleset, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Read(f)
+}
+
+// Read reads rulesets from JSON, in the form:
+//
+//	{"terrains": [
+//		{"id": 1, "name": "grass", "type": "blob", "first": 1},
+//		{"id": 2, "name": "water", "type": "edge", "tiles": [48, 49, ...]},
+//		{"id": 3, "name": "wall", "type": "rules", "rules": [
+//			{"pattern": ["?1?",
+//			             "0.0",
+//			             "?1?"], "tiles": [70, 71]},
+//			{"pattern": ["???",
+//			             "?.?",
+//			             "???"], "tiles": [72]}
+//		]}
+//	]}
+//
+// Edge, corner and blob sets take either all of their tiles in order, or
+// the first of a run of consecutive tiles. Rule patterns are three rows of
+// three characters around the cell: '1' for a neighbor that must match, '0'
+// for one that must not, and '?' for one that doesn't matter. The center is
+// ignored.
+func Read(r io.Reader) (map[Terrain]Ruleset, error) {
+	var f file
+	if err := json.NewDecoder(r).Decode(&f); err != nil {
+		return nil, err
+	}
+	rules := make(map[Terrain]Ruleset, len(f.Terrains))
+	for _, t := range f.Terrains {
+		if t.ID == 0 {
+			return nil, fmt.Errorf("terrain %q: id must not be zero", t.Name)
+		}
+		var err error
+		switch t.Type {
+		case "edge":
+			var s EdgeSet
+			err = fill(s[:], t.Tiles, t.First)
+			rules[t.ID] = s
+		case "corner":
+			var s CornerSet
+			err = fill(s[:], t.Tiles, t.First)
+			rules[t.ID] = s
+		case "blob":
+			var s BlobSet
+			err = fill(s[:], t.Tiles, t.First)
+			rules[t.ID] = s
+		case "rules":
+			var s Rules
+			for _, r := range t.Rules {
+				var rule Rule
+				rule.Care, rule.Match, err = ParsePattern(r.Pattern)
+				if err != nil {
+					break
+				}
+				rule.Tiles = r.Tiles
+				s = append(s, rule)
+			}
+			rules[t.ID] = s
+		default:
+			err = fmt.Errorf("unknown type %q", t.Type)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("terrain %q: %v", t.Name, err)
+		}
+	}
+	return rules, nil
+}
+
+// fill fills a set with either tiles or the run of tiles starting at first.
+func fill(set, tiles []tilemap.Tile, first tilemap.Tile) error {
+	switch {
+	case len(tiles) == len(set):
+		copy(set, tiles)
+	case len(tiles) == 0 && first != 0:
+		for i := range set {
+			set[i] = first + tilemap.Tile(i)
+		}
+	default:
+		return fmt.Errorf("need %d tiles or a first tile, got %d tiles", len(set), len(tiles))
+	}
+	return nil
+}
+
+// ParsePattern parses a rule pattern of three rows of three characters, as
+// described by Read, into the neighbors that the rule cares about and the
+// ones among them that must match.
+func ParsePattern(rows []string) (care, match Neighbors, err error) {
+	if len(rows) != 3 {
+		return 0, 0, errors.New("pattern must have 3 rows")
+	}
+	for y, row := range rows {
+		if len(row) != 3 {
+			return 0, 0, fmt.Errorf("pattern row %q must have 3 characters", row)
+		}
+		for x := 0; x < 3; x++ {
+			if x == 1 && y == 1 {
+				continue
+			}
+			var dir Neighbors
+			for i, o := range offsets {
+				if o[0] == x-1 && o[1] == y-1 {
+					dir = 1 << uint(i)
+				}
+			}
+			switch row[x] {
+			case '1':
+				care |= dir
+				match |= dir
+			case '0':
+				care |= dir
+			case '?', '.':
+			default:
+				return 0, 0, fmt.Errorf("invalid character %q in pattern", row[x])
+			}
+		}
+	}
+	return care, match, nil
+}
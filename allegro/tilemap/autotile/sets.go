@@ -0,0 +1,142 @@
+package autotile
+
+import (
+	"sort"
+
+	"github.com/dradtke/go-allegro/allegro/tilemap"
+)
+
+// EdgeSet is a 16-tile Wang edge set, where each tile's edges either join
+// the terrain or border it. The tile for a cell is indexed by its matching
+// edge neighbors: 1 for north, 2 for east, 4 for south and 8 for west.
+type EdgeSet [16]tilemap.Tile
+
+// NewEdgeSet returns an edge set of 16 consecutive tiles.
+func NewEdgeSet(first tilemap.Tile) EdgeSet {
+	var s EdgeSet
+	for i := range s {
+		s[i] = first + tilemap.Tile(i)
+	}
+	return s
+}
+
+// Tile implements Ruleset.
+func (s EdgeSet) Tile(n Neighbors, x, y int) tilemap.Tile {
+	var i int
+	for bit, dir := range [4]Neighbors{North, East, South, West} {
+		if n&dir != 0 {
+			i |= 1 << uint(bit)
+		}
+	}
+	return s[i]
+}
+
+// CornerSet is a 16-tile Wang corner set, where each tile's corners are
+// either inside the terrain or outside of it. A corner is inside when the
+// three neighbors around it all match. The tile for a cell is indexed by its
+// inside corners: 1 for north-east, 2 for south-east, 4 for south-west and
+// 8 for north-west.
+type CornerSet [16]tilemap.Tile
+
+// NewCornerSet returns a corner set of 16 consecutive tiles.
+func NewCornerSet(first tilemap.Tile) CornerSet {
+	var s CornerSet
+	for i := range s {
+		s[i] = first + tilemap.Tile(i)
+	}
+	return s
+}
+
+// Tile implements Ruleset.
+func (s CornerSet) Tile(n Neighbors, x, y int) tilemap.Tile {
+	n = n.Reduce()
+	var i int
+	for bit, dir := range [4]Neighbors{NorthEast, SouthEast, SouthWest, NorthWest} {
+		if n&dir != 0 {
+			i |= 1 << uint(bit)
+		}
+	}
+	return s[i]
+}
+
+// blobMasks holds the 47 distinct reduced neighbor sets, in ascending order.
+var blobMasks []Neighbors
+
+// blobIndex maps every reduced neighbor set to its index in blobMasks.
+var blobIndex [256]uint8
+
+func init() {
+	seen := make(map[Neighbors]bool)
+	for n := 0; n < 256; n++ {
+		r := Neighbors(n).Reduce()
+		if !seen[r] {
+			seen[r] = true
+			blobMasks = append(blobMasks, r)
+		}
+	}
+	sort.Slice(blobMasks, func(i, j int) bool { return blobMasks[i] < blobMasks[j] })
+	for i, n := range blobMasks {
+		blobIndex[n] = uint8(i)
+	}
+}
+
+// BlobMasks returns the 47 neighbor sets that a BlobSet distinguishes, in
+// the order of its tiles: every set of matching neighbors, after Reduce, in
+// ascending order of its bits. It can be used to lay out a blob tileset or
+// to map an existing one onto a BlobSet.
+func BlobMasks() []Neighbors {
+	return append([]Neighbors(nil), blobMasks...)
+}
+
+// BlobSet is a 47-tile blob set, which has a tile for every combination of
+// matching edges and corners. Tiles are in the order given by BlobMasks.
+type BlobSet [47]tilemap.Tile
+
+// NewBlobSet returns a blob set of 47 consecutive tiles.
+func NewBlobSet(first tilemap.Tile) BlobSet {
+	var s BlobSet
+	for i := range s {
+		s[i] = first + tilemap.Tile(i)
+	}
+	return s
+}
+
+// Tile implements Ruleset.
+func (s BlobSet) Tile(n Neighbors, x, y int) tilemap.Tile {
+	return s[blobIndex[n.Reduce()]]
+}
+
+// Rule matches a pattern of neighbors.
+type Rule struct {
+	// Care is the set of neighbors that the rule checks, and Match the
+	// ones among them that must match the cell's terrain. The rest of Care
+	// must not.
+	Care, Match Neighbors
+
+	// Tiles are the tiles to choose from when the rule matches. If there
+	// is more than one, one is picked based on the cell's position, so
+	// that the choice is random-looking but stable.
+	Tiles []tilemap.Tile
+}
+
+// Rules is a list of custom rules, checked in order until one matches.
+type Rules []Rule
+
+// Tile implements Ruleset.
+func (r Rules) Tile(n Neighbors, x, y int) tilemap.Tile {
+	for _, rule := range r {
+		if n&rule.Care == rule.Match&rule.Care && len(rule.Tiles) > 0 {
+			return rule.Tiles[variant(x, y, len(rule.Tiles))]
+		}
+	}
+	return 0
+}
+
+// variant hashes a position into a choice between n variants.
+func variant(x, y, n int) int {
+	h := uint32(x)*0x9E3779B1 ^ uint32(y)*0x85EBCA77
+	h ^= h >> 15
+	h *= 0xC2B2AE3D
+	h ^= h >> 13
+	return int(h % uint32(n))
+}
@@ -0,0 +1,241 @@
+package tilemap
+
+import (
+	"math"
+
+	"github.com/dradtke/go-allegro/allegro"
+	"github.com/dradtke/go-allegro/allegro/camera"
+)
+
+// Layer is one grid of tiles in a map.
+type Layer struct {
+	Name    string
+	Visible bool
+
+	// Opacity is multiplied with the layer's colors, from 0 to 1.
+	Opacity float32
+
+	// OffsetX and OffsetY shift the layer, in pixels.
+	OffsetX, OffsetY float32
+
+	// Tiles holds the layer's tiles row by row. Call Invalidate after
+	// changing it directly; SetTile only marks the affected chunk.
+	Tiles []Tile
+
+	m      *Map
+	chunks []chunk
+	size   int // chunk size that chunks was made with
+	across int // chunks per row
+}
+
+// chunk is a cached, pre-rendered block of a layer.
+type chunk struct {
+	bitmap *allegro.Bitmap
+	dirty  bool
+
+	// bounds is the area the chunk can draw to, in map pixels.
+	bounds camera.Rect
+
+	// x0, y0, x1 and y1 are the chunk's range of tiles.
+	x0, y0, x1, y1 int
+
+	// animated is set for a chunk with animated tiles. It isn't cached, but
+	// drawn tile by tile each frame, so that its animated tiles stay in
+	// their place in the drawing order.
+	animated bool
+}
+
+// Map returns the map that the layer belongs to.
+func (l *Layer) Map() *Map {
+	return l.m
+}
+
+// Tile returns the tile at column x, row y, or zero if that is outside of
+// the layer.
+func (l *Layer) Tile(x, y int) Tile {
+	if x < 0 || y < 0 || x >= l.m.Width || y >= l.m.Height {
+		return 0
+	}
+	return l.Tiles[y*l.m.Width+x]
+}
+
+// SetTile sets the tile at column x, row y, and marks its chunk for
+// redrawing. Positions outside of the layer are ignored.
+func (l *Layer) SetTile(x, y int, t Tile) {
+	if x < 0 || y < 0 || x >= l.m.Width || y >= l.m.Height {
+		return
+	}
+	i := y*l.m.Width + x
+	if l.Tiles[i] == t {
+		return
+	}
+	l.Tiles[i] = t
+	if l.chunks != nil {
+		l.chunks[(y/l.size)*l.across+x/l.size].dirty = true
+	}
+}
+
+// Invalidate discards the layer's cached chunks, so that they are redrawn
+// the next time they are visible.
+func (l *Layer) Invalidate() {
+	l.destroyChunks()
+}
+
+// Draw draws the parts of the layer that fall within view, regardless of
+// whether the layer is visible.
+func (l *Layer) Draw(view camera.Rect) {
+	m := l.m
+	if l.chunks == nil || l.size != m.ChunkSize {
+		l.makeChunks()
+	}
+	view.X -= l.OffsetX
+	view.Y -= l.OffsetY
+	tint := allegro.MapRGBAf(l.Opacity, l.Opacity, l.Opacity, l.Opacity)
+	for i := range l.chunks {
+		c := &l.chunks[i]
+		if !overlaps(c.bounds, view) {
+			continue
+		}
+		if c.dirty {
+			l.render(c)
+		}
+		if !c.animated {
+			if c.bitmap != nil {
+				c.bitmap.DrawTinted(tint, c.bounds.X+l.OffsetX, c.bounds.Y+l.OffsetY, allegro.FLIP_NONE)
+			}
+			continue
+		}
+		for y := c.y0; y < c.y1; y++ {
+			for x := c.x0; x < c.x1; x++ {
+				px, py := m.TileToPixel(x, y)
+				m.addTile(&m.batch, l.Tiles[y*m.Width+x], px+l.OffsetX, py+l.OffsetY, tint, true)
+			}
+		}
+		// Flushed per chunk so that later chunks are drawn over this one.
+		m.batch.Flush()
+	}
+}
+
+// makeChunks splits the layer into chunks, discarding any old ones.
+func (l *Layer) makeChunks() {
+	l.destroyChunks()
+	m := l.m
+	if m.ChunkSize <= 0 {
+		m.ChunkSize = DefaultChunkSize
+	}
+	size := m.ChunkSize
+	l.size = size
+	l.across = (m.Width + size - 1) / size
+	down := (m.Height + size - 1) / size
+	right, up := m.overflow()
+	l.chunks = make([]chunk, 0, l.across*down)
+	for cy := 0; cy < down; cy++ {
+		for cx := 0; cx < l.across; cx++ {
+			c := chunk{
+				dirty: true,
+				x0:    cx * size, y0: cy * size,
+				x1: min(cx*size+size, m.Width), y1: min(cy*size+size, m.Height),
+			}
+			c.bounds = l.chunkBounds(c.x0, c.y0, c.x1, c.y1, right, up)
+			l.chunks = append(l.chunks, c)
+		}
+	}
+}
+
+// chunkBounds returns the area covered by a range of tiles, including
+// tiles that extend past their grid cells.
+func (l *Layer) chunkBounds(x0, y0, x1, y1 int, right, up float32) camera.Rect {
+	m := l.m
+	minX, minY := float32(math.Inf(1)), float32(math.Inf(1))
+	maxX, maxY := float32(math.Inf(-1)), float32(math.Inf(-1))
+	for y := y0; y < y1; y++ {
+		for x := x0; x < x1; x++ {
+			px, py := m.TileToPixel(x, y)
+			if px < minX {
+				minX = px
+			}
+			if py < minY {
+				minY = py
+			}
+			if px > maxX {
+				maxX = px
+			}
+			if py > maxY {
+				maxY = py
+			}
+		}
+	}
+	minX = float32(math.Floor(float64(minX)))
+	minY = float32(math.Floor(float64(minY - up)))
+	maxX = float32(math.Ceil(float64(maxX + float32(m.TileWidth) + right)))
+	maxY = float32(math.Ceil(float64(maxY + float32(m.TileHeight))))
+	return camera.Rect{X: minX, Y: minY, W: maxX - minX, H: maxY - minY}
+}
+
+// render redraws a chunk's cached bitmap.
+func (l *Layer) render(c *chunk) {
+	m := l.m
+	c.dirty = false
+	c.animated = false
+	static := false
+	for y := c.y0; y < c.y1; y++ {
+		for x := c.x0; x < c.x1; x++ {
+			ts, index := m.Tileset(l.Tiles[y*m.Width+x])
+			switch {
+			case ts == nil:
+			case ts.animated(index):
+				c.animated = true
+			default:
+				static = true
+			}
+		}
+	}
+	if !static || c.animated {
+		if c.bitmap != nil {
+			c.bitmap.Destroy()
+			c.bitmap = nil
+		}
+		return
+	}
+
+	if c.bitmap == nil {
+		c.bitmap = allegro.CreateBitmap(int(c.bounds.W), int(c.bounds.H))
+		if c.bitmap == nil {
+			return
+		}
+	}
+	white := allegro.MapRGBAf(1, 1, 1, 1)
+	allegro.WithState(allegro.STATE_TARGET_BITMAP|allegro.STATE_TRANSFORM|allegro.STATE_BLENDER, func() {
+		allegro.SetTargetBitmap(c.bitmap)
+		allegro.UseTransform(allegro.IdentityTransform())
+		allegro.SetBlendMode(allegro.BlendPremultiplied)
+		allegro.ClearToColor(allegro.MapRGBA(0, 0, 0, 0))
+		for y := c.y0; y < c.y1; y++ {
+			for x := c.x0; x < c.x1; x++ {
+				px, py := m.TileToPixel(x, y)
+				m.addTile(&m.cache, l.Tiles[y*m.Width+x], px-c.bounds.X, py-c.bounds.Y, white, false)
+			}
+		}
+		m.cache.Flush()
+	})
+}
+
+func (l *Layer) destroyChunks() {
+	for i := range l.chunks {
+		if bmp := l.chunks[i].bitmap; bmp != nil {
+			bmp.Destroy()
+		}
+	}
+	l.chunks = nil
+}
+
+func overlaps(a, b camera.Rect) bool {
+	return a.X < b.X+b.W && b.X < a.X+a.W && a.Y < b.Y+b.H && b.Y < a.Y+a.H
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,376 @@
+// Package tilemap draws large tile maps efficiently.
+//
+// A Map is a grid of tiles in one or more layers, drawn from tilesets. Each
+// layer is split into chunks that are pre-rendered into bitmaps the first
+// time they are seen and only redrawn after one of their tiles changes, so
+// drawing a frame costs one bitmap draw per visible chunk rather than one per
+// tile. Chunks with animated tiles aren't cached, but drawn tile by tile
+// each frame in a single batch.
+//
+//	m := tilemap.New(tilemap.Orthogonal, 256, 256, 16, 16)
+//	m.AddTileset(tilemap.NewTileset(sheet, 16, 16, 0, 0))
+//	ground := m.AddLayer("ground")
+//	ground.SetTile(3, 4, 1)
+//	...
+//	case allegro.TimerEvent:
+//		m.Update(timer.Speed())
+//	...
+//	cam.Draw(func() {
+//		m.Draw(cam.View())
+//	})
+//
+// Drawing requires the primitives addon.
+package tilemap
+
+import (
+	"math"
+	"sort"
+
+	"github.com/dradtke/go-allegro/allegro"
+	"github.com/dradtke/go-allegro/allegro/camera"
+	"github.com/dradtke/go-allegro/allegro/primitives"
+)
+
+// Tile is the contents of a map cell: the ID of a tile within the map's
+// tilesets, plus flags for flipping it. Zero is an empty cell.
+//
+// The flags use the same bits as Tiled, so Tiled's global tile IDs can be
+// used directly.
+type Tile uint32
+
+const (
+	FlipHorizontal Tile = 1 << 31
+	FlipVertical   Tile = 1 << 30
+	// FlipDiagonal swaps the tile's x and y axes, before any horizontal or
+	// vertical flip is applied. Combined with them, it rotates the tile in
+	// steps of 90 degrees.
+	FlipDiagonal Tile = 1 << 29
+
+	flipMask = FlipHorizontal | FlipVertical | FlipDiagonal
+)
+
+// ID returns the tile's ID without its flip flags.
+func (t Tile) ID() Tile {
+	return t &^ flipMask
+}
+
+// Orientation is the shape and arrangement of a map's tiles.
+type Orientation int
+
+const (
+	// Orthogonal maps are plain grids of rectangles.
+	Orthogonal Orientation = iota
+	// Isometric maps are diamonds arranged into a larger diamond.
+	Isometric
+	// Staggered maps are diamonds, or hexagons if HexSideLength is set,
+	// with every other row or column shifted by half a tile, forming a
+	// roughly rectangular map.
+	Staggered
+)
+
+// StaggerAxis is the axis along which a staggered map is shifted.
+type StaggerAxis int
+
+const (
+	// StaggerY shifts every other row horizontally.
+	StaggerY StaggerAxis = iota
+	// StaggerX shifts every other column vertically.
+	StaggerX
+)
+
+// StaggerIndex is which rows or columns of a staggered map are shifted.
+type StaggerIndex int
+
+const (
+	StaggerOdd StaggerIndex = iota
+	StaggerEven
+)
+
+// DefaultChunkSize is the width and height of a chunk in tiles.
+const DefaultChunkSize = 16
+
+// Map is a tile map.
+type Map struct {
+	Orientation Orientation
+
+	// Width and Height are the size of the map in tiles, and TileWidth and
+	// TileHeight the size of its grid cells in pixels.
+	Width, Height         int
+	TileWidth, TileHeight int
+
+	// StaggerAxis, StaggerIndex and HexSideLength only apply to Staggered
+	// maps. HexSideLength is the length in pixels of the flat sides of
+	// hexagonal tiles, along the stagger axis, and zero for diamonds.
+	StaggerAxis   StaggerAxis
+	StaggerIndex  StaggerIndex
+	HexSideLength int
+
+	// Tilesets are kept sorted by FirstID.
+	Tilesets []*Tileset
+	Layers   []*Layer
+
+	// ChunkSize is the width and height of a chunk in tiles. Changing it
+	// discards the cached chunks.
+	ChunkSize int
+
+	time float64
+
+	// batch draws chunks with animated tiles, and cache draws the others
+	// into their bitmaps.
+	batch, cache primitives.SpriteBatch
+}
+
+// New creates an empty map.
+func New(orientation Orientation, width, height, tileWidth, tileHeight int) *Map {
+	return &Map{
+		Orientation: orientation,
+		Width:       width,
+		Height:      height,
+		TileWidth:   tileWidth,
+		TileHeight:  tileHeight,
+		ChunkSize:   DefaultChunkSize,
+	}
+}
+
+// AddTileset adds a tileset to the map. If its FirstID is zero, it is given
+// the first ID after the map's other tilesets. It returns the tileset's
+// FirstID.
+func (m *Map) AddTileset(ts *Tileset) Tile {
+	if ts.FirstID == 0 {
+		ts.FirstID = 1
+		for _, other := range m.Tilesets {
+			if next := other.FirstID + Tile(other.Len()); next > ts.FirstID {
+				ts.FirstID = next
+			}
+		}
+	}
+	m.Tilesets = append(m.Tilesets, ts)
+	sort.SliceStable(m.Tilesets, func(i, j int) bool {
+		return m.Tilesets[i].FirstID < m.Tilesets[j].FirstID
+	})
+	m.Invalidate()
+	return ts.FirstID
+}
+
+// AddLayer adds an empty layer on top of the map's other layers.
+func (m *Map) AddLayer(name string) *Layer {
+	l := &Layer{
+		Name:    name,
+		Visible: true,
+		Opacity: 1,
+		Tiles:   make([]Tile, m.Width*m.Height),
+		m:       m,
+	}
+	m.Layers = append(m.Layers, l)
+	return l
+}
+
+// Layer returns the first layer with the given name, or nil.
+func (m *Map) Layer(name string) *Layer {
+	for _, l := range m.Layers {
+		if l.Name == name {
+			return l
+		}
+	}
+	return nil
+}
+
+// Tileset returns the tileset that a tile belongs to and the tile's index
+// within it, or nil if the tile is empty or not part of any tileset.
+func (m *Map) Tileset(t Tile) (*Tileset, int) {
+	id := t.ID()
+	if id == 0 {
+		return nil, 0
+	}
+	i := sort.Search(len(m.Tilesets), func(i int) bool {
+		return m.Tilesets[i].FirstID > id
+	}) - 1
+	if i < 0 {
+		return nil, 0
+	}
+	ts := m.Tilesets[i]
+	index := int(id - ts.FirstID)
+	if index >= ts.Len() {
+		return nil, 0
+	}
+	return ts, index
+}
+
+// Update advances animated tiles by dt seconds.
+func (m *Map) Update(dt float64) {
+	m.time += dt
+}
+
+// Invalidate discards every layer's cached chunks, so that they are
+// redrawn the next time they are visible. It is called by AddTileset, and
+// should be called after changing a tileset or the map's geometry.
+func (m *Map) Invalidate() {
+	for _, l := range m.Layers {
+		l.Invalidate()
+	}
+}
+
+// Destroy frees the bitmaps of every layer's cached chunks.
+func (m *Map) Destroy() {
+	for _, l := range m.Layers {
+		l.destroyChunks()
+	}
+}
+
+// Draw draws the map's visible layers, skipping everything outside of view,
+// which is in map pixel coordinates, such as the result of Camera.View.
+func (m *Map) Draw(view camera.Rect) {
+	for _, l := range m.Layers {
+		if l.Visible {
+			l.Draw(view)
+		}
+	}
+}
+
+// PixelSize returns the size of the whole map in pixels, not counting tiles
+// that are larger than the grid.
+func (m *Map) PixelSize() (w, h float32) {
+	tw, th := float32(m.TileWidth), float32(m.TileHeight)
+	switch m.Orientation {
+	case Isometric:
+		return float32(m.Width+m.Height) * tw / 2, float32(m.Width+m.Height) * th / 2
+	case Staggered:
+		side := float32(m.HexSideLength)
+		if m.StaggerAxis == StaggerX {
+			colWidth := (tw + side) / 2
+			return float32(m.Width)*colWidth + (tw-side)/2, float32(m.Height)*th + th/2
+		}
+		rowHeight := (th + side) / 2
+		return float32(m.Width)*tw + tw/2, float32(m.Height)*rowHeight + (th-side)/2
+	}
+	return float32(m.Width) * tw, float32(m.Height) * th
+}
+
+// TileToPixel returns the top-left corner of the grid cell of the tile at
+// column x, row y. For isometric and staggered maps, this is the corner of
+// the rectangle surrounding the tile's diamond or hexagon.
+func (m *Map) TileToPixel(x, y int) (float32, float32) {
+	tw, th := float32(m.TileWidth), float32(m.TileHeight)
+	switch m.Orientation {
+	case Isometric:
+		return float32(x-y+m.Height-1) * tw / 2, float32(x+y) * th / 2
+	case Staggered:
+		side := float32(m.HexSideLength)
+		if m.StaggerAxis == StaggerX {
+			px, py := float32(x)*(tw+side)/2, float32(y)*th
+			if m.staggered(x) {
+				py += th / 2
+			}
+			return px, py
+		}
+		px, py := float32(x)*tw, float32(y)*(th+side)/2
+		if m.staggered(y) {
+			px += tw / 2
+		}
+		return px, py
+	}
+	return float32(x) * tw, float32(y) * th
+}
+
+// staggered reports whether row or column i of a staggered map is shifted.
+func (m *Map) staggered(i int) bool {
+	odd := i&1 != 0
+	if m.StaggerIndex == StaggerEven {
+		return !odd
+	}
+	return odd
+}
+
+// PixelToTile returns the column and row of the tile containing a point in
+// map pixel coordinates. The result may be outside of the map.
+func (m *Map) PixelToTile(px, py float32) (x, y int) {
+	tw, th := float64(m.TileWidth), float64(m.TileHeight)
+	switch m.Orientation {
+	case Isometric:
+		fx := float64(px) - float64(m.Height)*tw/2
+		fy := float64(py)
+		return int(math.Floor(fy/th + fx/tw)), int(math.Floor(fy/th - fx/tw))
+	case Staggered:
+		// Find the tile whose center is nearest, measuring in units of
+		// tiles so that the cells around each center are the tile
+		// shapes.
+		side := float64(m.HexSideLength)
+		var gx, gy int
+		if m.StaggerAxis == StaggerX {
+			gx = int(math.Floor(float64(px) / ((tw + side) / 2)))
+			gy = int(math.Floor(float64(py) / th))
+		} else {
+			gx = int(math.Floor(float64(px) / tw))
+			gy = int(math.Floor(float64(py) / ((th + side) / 2)))
+		}
+		best := math.Inf(1)
+		for cy := gy - 1; cy <= gy+1; cy++ {
+			for cx := gx - 1; cx <= gx+1; cx++ {
+				ox, oy := m.TileToPixel(cx, cy)
+				dx := (float64(px) - float64(ox) - tw/2) / tw
+				dy := (float64(py) - float64(oy) - th/2) / th
+				if d := math.Abs(dx) + math.Abs(dy); d < best {
+					best, x, y = d, cx, cy
+				}
+			}
+		}
+		return x, y
+	}
+	return int(math.Floor(float64(px) / tw)), int(math.Floor(float64(py) / th))
+}
+
+// addTile adds the sprite for a tile drawn in the grid cell at px, py to a
+// batch. The tile is aligned to the bottom-left of the cell, so tiles taller
+// than the grid extend upwards.
+func (m *Map) addTile(batch *primitives.SpriteBatch, t Tile, px, py float32, tint allegro.Color, animate bool) {
+	ts, index := m.Tileset(t)
+	if ts == nil {
+		return
+	}
+	if animate {
+		index = ts.frame(index, m.time)
+	}
+	bmp, sx, sy, sw, sh := ts.Source(index)
+	if bmp == nil {
+		return
+	}
+	x := px + ts.OffsetX
+	y := py + float32(m.TileHeight) - sh + ts.OffsetY
+	s := primitives.Sprite{
+		Bitmap: bmp,
+		SX:     sx, SY: sy, SW: sw, SH: sh,
+		Tint: tint,
+		CX:   sw / 2, CY: sh / 2,
+		DX: x + sw/2, DY: y + sh/2,
+	}
+	h, v := t&FlipHorizontal != 0, t&FlipVertical != 0
+	if t&FlipDiagonal != 0 {
+		// Swapping the axes is a quarter turn plus a vertical flip.
+		s.Angle = math.Pi / 2
+		h, v = v, !h
+	}
+	if h {
+		s.Flags |= allegro.FLIP_HORIZONTAL
+	}
+	if v {
+		s.Flags |= allegro.FLIP_VERTICAL
+	}
+	batch.Add(s)
+}
+
+// overflow returns how far tiles can extend past their grid cells, up and to
+// the right, with the map's tilesets.
+func (m *Map) overflow() (right, up float32) {
+	for _, ts := range m.Tilesets {
+		right = max(right, float32(ts.TileWidth-m.TileWidth)+ts.OffsetX)
+		up = max(up, float32(ts.TileHeight-m.TileHeight)-ts.OffsetY)
+	}
+	return right, up
+}
+
+func max(a, b float32) float32 {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,115 @@
+package tilemap
+
+import (
+	"testing"
+)
+
+func TestTileToPixel(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		m    *Map
+		x, y int
+		px   float32
+		py   float32
+	}{
+		{"orthogonal", New(Orthogonal, 10, 10, 16, 8), 3, 2, 48, 16},
+		{"isometric", New(Isometric, 10, 10, 64, 32), 0, 0, 288, 0},
+		{"isometric", New(Isometric, 10, 10, 64, 32), 2, 1, 320, 48},
+		{"staggered", New(Staggered, 10, 10, 64, 32), 2, 1, 160, 16},
+		{"staggered", New(Staggered, 10, 10, 64, 32), 2, 2, 128, 32},
+		{"staggered even", &Map{Orientation: Staggered, TileWidth: 64, TileHeight: 32, StaggerIndex: StaggerEven}, 2, 2, 160, 32},
+		{"staggered x", &Map{Orientation: Staggered, TileWidth: 64, TileHeight: 32, StaggerAxis: StaggerX}, 1, 2, 32, 80},
+		{"hexagonal", &Map{Orientation: Staggered, TileWidth: 28, TileHeight: 32, HexSideLength: 16}, 1, 3, 42, 72},
+	} {
+		if px, py := tc.m.TileToPixel(tc.x, tc.y); px != tc.px || py != tc.py {
+			t.Errorf("%s: tile %d, %d is at %v, %v; want %v, %v", tc.name, tc.x, tc.y, px, py, tc.px, tc.py)
+		}
+	}
+}
+
+func TestPixelToTile(t *testing.T) {
+	for _, m := range []*Map{
+		New(Orthogonal, 10, 10, 16, 8),
+		New(Isometric, 10, 10, 64, 32),
+		New(Staggered, 10, 10, 64, 32),
+		{Orientation: Staggered, Width: 10, Height: 10, TileWidth: 64, TileHeight: 32, StaggerAxis: StaggerX, StaggerIndex: StaggerEven},
+		{Orientation: Staggered, Width: 10, Height: 10, TileWidth: 28, TileHeight: 32, HexSideLength: 16},
+	} {
+		for y := 0; y < m.Height; y++ {
+			for x := 0; x < m.Width; x++ {
+				px, py := m.TileToPixel(x, y)
+				cx, cy := px+float32(m.TileWidth)/2, py+float32(m.TileHeight)/2
+				if gx, gy := m.PixelToTile(cx, cy); gx != x || gy != y {
+					t.Fatalf("orientation %d: center of tile %d, %d maps back to %d, %d", m.Orientation, x, y, gx, gy)
+				}
+			}
+		}
+	}
+}
+
+func TestTileset(t *testing.T) {
+	m := New(Orthogonal, 4, 4, 16, 16)
+	a := &Tileset{TileWidth: 16, TileHeight: 16, Margin: 1, Spacing: 2}
+	a.layout(1+16+2+16+1, 1+16+1) // 2x1 tiles
+	b := &Tileset{TileWidth: 16, TileHeight: 16}
+	b.layout(64, 64)
+
+	if first := m.AddTileset(a); first != 1 {
+		t.Errorf("first tileset starts at %d, want 1", first)
+	}
+	if first := m.AddTileset(b); first != 3 {
+		t.Errorf("second tileset starts at %d, want 3", first)
+	}
+	for _, tc := range []struct {
+		tile  Tile
+		ts    *Tileset
+		index int
+	}{
+		{0, nil, 0},
+		{2, a, 1},
+		{2 | FlipHorizontal | FlipDiagonal, a, 1},
+		{3, b, 0},
+		{18, b, 15},
+		{19, nil, 0},
+	} {
+		if ts, index := m.Tileset(tc.tile); ts != tc.ts || index != tc.index {
+			t.Errorf("tile %#x is index %d of %p, want %d of %p", tc.tile, index, ts, tc.index, tc.ts)
+		}
+	}
+	if _, sx, sy, _, _ := a.Source(1); sx != 19 || sy != 1 {
+		t.Errorf("second tile is at %v, %v; want 19, 1", sx, sy)
+	}
+}
+
+func TestAnimation(t *testing.T) {
+	a := &Animation{Frames: []Frame{{Tile: 4, Duration: 0.5}, {Tile: 5, Duration: 0.25}}}
+	for _, tc := range []struct {
+		t    float64
+		want int
+	}{{0, 4}, {0.4, 4}, {0.6, 5}, {0.8, 4}, {1.3, 5}} {
+		if got := a.At(tc.t); got != tc.want {
+			t.Errorf("at %v shows tile %d, want %d", tc.t, got, tc.want)
+		}
+	}
+}
+
+func TestChunks(t *testing.T) {
+	m := New(Orthogonal, 40, 20, 16, 16)
+	l := m.AddLayer("ground")
+	l.makeChunks()
+	if len(l.chunks) != 6 {
+		t.Fatalf("got %d chunks, want 6", len(l.chunks))
+	}
+	if last := l.chunks[5].bounds; last.X != 512 || last.Y != 256 || last.W != 128 || last.H != 64 {
+		t.Errorf("last chunk covers %+v", last)
+	}
+	for i := range l.chunks {
+		l.chunks[i].dirty = false
+	}
+	l.SetTile(35, 17, 1)
+	for i, c := range l.chunks {
+		if c.dirty != (i == 5) {
+			t.Errorf("chunk %d dirty = %v", i, c.dirty)
+		}
+	}
+}
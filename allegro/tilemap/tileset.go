@@ -0,0 +1,165 @@
+package tilemap
+
+import (
+	"github.com/dradtke/go-allegro/allegro"
+)
+
+// Tileset is a set of tile images: either a grid of tiles cut from one
+// bitmap, such as an atlas page or a region of one, or a collection of
+// separate bitmaps.
+type Tileset struct {
+	Name string
+
+	// FirstID is the Tile value of the tileset's first tile. It is assigned
+	// by Map.AddTileset if left at zero.
+	FirstID Tile
+
+	// Bitmap is the image that the tiles are cut from. It may be a
+	// sub-bitmap.
+	Bitmap *allegro.Bitmap
+
+	// TileWidth and TileHeight are the size of each tile in Bitmap, which
+	// may be larger than the map's grid, such as for tall isometric tiles.
+	// Margin is the space around the edge of Bitmap and Spacing the space
+	// between tiles.
+	TileWidth, TileHeight int
+	Margin, Spacing       int
+
+	// Images, if set, holds one bitmap per tile instead of cutting them from
+	// Bitmap. They can have different sizes.
+	Images []*allegro.Bitmap
+
+	// OffsetX and OffsetY shift where the tiles are drawn, in pixels.
+	OffsetX, OffsetY float32
+
+	// Animations maps local tile indices, starting from 0, to animations that
+	// replace them.
+	Animations map[int]*Animation
+
+	columns, count int
+}
+
+// Frame is one frame of a tile animation.
+type Frame struct {
+	// Tile is the local index, within the same tileset, of the tile to show.
+	Tile int
+
+	// Duration is how long the frame is shown, in seconds.
+	Duration float64
+}
+
+// Animation is a looping sequence of tiles.
+type Animation struct {
+	Frames []Frame
+}
+
+// At returns the local tile index shown at time t, in seconds.
+func (a *Animation) At(t float64) int {
+	var total float64
+	for _, f := range a.Frames {
+		total += f.Duration
+	}
+	if total <= 0 {
+		if len(a.Frames) == 0 {
+			return 0
+		}
+		return a.Frames[0].Tile
+	}
+	t -= total * float64(int(t/total))
+	for _, f := range a.Frames {
+		if t < f.Duration {
+			return f.Tile
+		}
+		t -= f.Duration
+	}
+	return a.Frames[len(a.Frames)-1].Tile
+}
+
+// NewTileset creates a tileset by cutting a bitmap into a grid of tiles.
+func NewTileset(bmp *allegro.Bitmap, tileWidth, tileHeight, margin, spacing int) *Tileset {
+	ts := &Tileset{
+		Bitmap:     bmp,
+		TileWidth:  tileWidth,
+		TileHeight: tileHeight,
+		Margin:     margin,
+		Spacing:    spacing,
+	}
+	ts.layout(bmp.Width(), bmp.Height())
+	return ts
+}
+
+// NewCollection creates a tileset from separate bitmaps, one per tile.
+func NewCollection(images []*allegro.Bitmap) *Tileset {
+	ts := &Tileset{Images: images, count: len(images)}
+	for _, img := range images {
+		if img == nil {
+			continue
+		}
+		if w := img.Width(); w > ts.TileWidth {
+			ts.TileWidth = w
+		}
+		if h := img.Height(); h > ts.TileHeight {
+			ts.TileHeight = h
+		}
+	}
+	return ts
+}
+
+func (ts *Tileset) layout(width, height int) {
+	if ts.TileWidth <= 0 || ts.TileHeight <= 0 {
+		return
+	}
+	ts.columns = (width - 2*ts.Margin + ts.Spacing) / (ts.TileWidth + ts.Spacing)
+	rows := (height - 2*ts.Margin + ts.Spacing) / (ts.TileHeight + ts.Spacing)
+	if ts.columns < 0 || rows < 0 {
+		ts.columns, rows = 0, 0
+	}
+	ts.count = ts.columns * rows
+}
+
+// Len returns the number of tiles in the tileset.
+func (ts *Tileset) Len() int {
+	return ts.count
+}
+
+// Animate makes a tile animated.
+func (ts *Tileset) Animate(index int, frames ...Frame) {
+	if ts.Animations == nil {
+		ts.Animations = make(map[int]*Animation)
+	}
+	ts.Animations[index] = &Animation{Frames: frames}
+}
+
+// Source returns the bitmap and region that a tile is drawn from. The bitmap
+// is nil if the index is out of range.
+func (ts *Tileset) Source(index int) (bmp *allegro.Bitmap, sx, sy, sw, sh float32) {
+	if index < 0 || index >= ts.count {
+		return nil, 0, 0, 0, 0
+	}
+	if ts.Images != nil {
+		img := ts.Images[index]
+		if img == nil {
+			return nil, 0, 0, 0, 0
+		}
+		return img, 0, 0, float32(img.Width()), float32(img.Height())
+	}
+	col, row := index%ts.columns, index/ts.columns
+	return ts.Bitmap,
+		float32(ts.Margin + col*(ts.TileWidth+ts.Spacing)),
+		float32(ts.Margin + row*(ts.TileHeight+ts.Spacing)),
+		float32(ts.TileWidth), float32(ts.TileHeight)
+}
+
+// animated reports whether a tile is animated.
+func (ts *Tileset) animated(index int) bool {
+	_, ok := ts.Animations[index]
+	return ok
+}
+
+// frame returns the tile shown in place of index at time t.
+func (ts *Tileset) frame(index int, t float64) int {
+	if a, ok := ts.Animations[index]; ok {
+		return a.At(t)
+	}
+	return index
+}
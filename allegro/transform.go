@@ -2,9 +2,25 @@ package allegro
 
 // #include <allegro5/allegro.h>
 import "C"
+import (
+	"unsafe"
+
+	"github.com/dradtke/go-allegro/allegro/transform"
+)
 
 type Transform C.ALLEGRO_TRANSFORM
 
+// TransformFromMatrix returns m as a Transform. The two share memory, so
+// changes to one are seen by the other.
+func TransformFromMatrix(m *transform.Matrix) *Transform {
+	return (*Transform)(unsafe.Pointer(m))
+}
+
+// Matrix returns a copy of t as a transform.Matrix.
+func (t *Transform) Matrix() transform.Matrix {
+	return *(*transform.Matrix)(unsafe.Pointer(t))
+}
+
 // Sets the transformation to be used for the the drawing operations on the
 // target bitmap (each bitmap maintains its own transformation). Every drawing
 // operation after this call will be transformed using this transformation.
@@ -49,6 +65,52 @@ func CurrentTransform() *Transform {
 	return (*Transform)(C.al_get_current_transform())
 }
 
+// Sets the transformation to be used for the the drawing operations on the
+// target bitmap (each bitmap maintains its own projection transformation).
+// Every drawing operation after this call will be transformed using this
+// transformation. To return default behavior, call this function with an
+// orthographic transform like so:
+//
+//	var t Transform
+//	t.Identity()
+//	t.Orthographic(0, 0, -1, float32(bitmap.Width()), float32(bitmap.Height()), 1)
+//	UseProjectionTransform(&t)
+//
+// The orthographic transformation above is the default projection transform.
+//
+// See https://liballeg.org/a5docs/5.2.6/transformations.html#al_use_projection_transform
+func UseProjectionTransform(trans *Transform) {
+	C.al_use_projection_transform((*C.ALLEGRO_TRANSFORM)(trans))
+}
+
+// If there is no target bitmap, this function returns NULL.
+//
+// Returns: A pointer to the current transformation.
+//
+// See https://liballeg.org/a5docs/5.2.6/transformations.html#al_get_current_projection_transform
+func CurrentProjectionTransform() *Transform {
+	return (*Transform)(C.al_get_current_projection_transform())
+}
+
+// Builds a transformation which can be used to transform 3D coordinates in
+// world space to camera space. This involves translation and a rotation. The
+// function expects three coordinate triplets: The camera's position, the
+// position the camera is looking at and an up vector. The up vector does not
+// need to be of unit length and also does not need to be perpendicular to
+// the view direction - it can usually just be the world up direction (most
+// commonly 0/1/0).
+//
+// See https://liballeg.org/a5docs/5.2.6/transformations.html#al_build_camera_transform
+func BuildCameraTransform(positionX, positionY, positionZ, lookX, lookY, lookZ, upX, upY, upZ float32) *Transform {
+	var t Transform
+	C.al_build_camera_transform((*C.ALLEGRO_TRANSFORM)(&t),
+		C.float(positionX), C.float(positionY), C.float(positionZ),
+		C.float(lookX), C.float(lookY), C.float(lookZ),
+		C.float(upX), C.float(upY), C.float(upZ),
+	)
+	return &t
+}
+
 // Makes a copy of a transformation.
 //
 // See https://liballeg.org/a5docs/5.2.6/transformations.html#al_copy_transform
@@ -88,13 +150,120 @@ func (t *Transform) Scale(sx, sy float32) {
 	C.al_scale_transform((*C.ALLEGRO_TRANSFORM)(t), C.float(sx), C.float(sy))
 }
 
+// Combines the given transformation with a transformation that translates
+// coordinates by the given vector.
+//
+// See https://liballeg.org/a5docs/5.2.6/transformations.html#al_translate_transform_3d
+func (t *Transform) Translate3D(x, y, z float32) {
+	C.al_translate_transform_3d((*C.ALLEGRO_TRANSFORM)(t), C.float(x), C.float(y), C.float(z))
+}
+
+// Combines the given transformation with a transformation that rotates
+// coordinates around the given vector by the given angle in radians.
+//
+// Note: The vector is assumed to be of unit length (otherwise it will also
+// incur a scale).
+//
+// See https://liballeg.org/a5docs/5.2.6/transformations.html#al_rotate_transform_3d
+func (t *Transform) Rotate3D(x, y, z, angle float32) {
+	C.al_rotate_transform_3d((*C.ALLEGRO_TRANSFORM)(t), C.float(x), C.float(y), C.float(z), C.float(angle))
+}
+
+// Combines the given transformation with a transformation that scales
+// coordinates by the given vector.
+//
+// See https://liballeg.org/a5docs/5.2.6/transformations.html#al_scale_transform_3d
+func (t *Transform) Scale3D(sx, sy, sz float32) {
+	C.al_scale_transform_3d((*C.ALLEGRO_TRANSFORM)(t), C.float(sx), C.float(sy), C.float(sz))
+}
+
+// Combines the given transformation with an orthographic transformation
+// which maps the screen rectangle to the given left/top and right/bottom. n
+// and f are the near and far clipping planes.
+//
+// See https://liballeg.org/a5docs/5.2.6/transformations.html#al_orthographic_transform
+func (t *Transform) Orthographic(left, top, n, right, bottom, f float32) {
+	C.al_orthographic_transform((*C.ALLEGRO_TRANSFORM)(t),
+		C.float(left), C.float(top), C.float(n),
+		C.float(right), C.float(bottom), C.float(f),
+	)
+}
+
+// Like al_orthographic_transform but honors perspective. If everything is at
+// a z-position of -near it will look the same as with an orthographic
+// transformation.
+//
+// See https://liballeg.org/a5docs/5.2.6/transformations.html#al_perspective_transform
+func (t *Transform) Perspective(left, top, n, right, bottom, f float32) {
+	C.al_perspective_transform((*C.ALLEGRO_TRANSFORM)(t),
+		C.float(left), C.float(top), C.float(n),
+		C.float(right), C.float(bottom), C.float(f),
+	)
+}
+
+// Transposes the matrix of the given transform. This can be used for
+// inversing a rotation transform.
+//
+// See https://liballeg.org/a5docs/5.2.6/transformations.html#al_transpose_transform
+func (t *Transform) Transpose() {
+	C.al_transpose_transform((*C.ALLEGRO_TRANSFORM)(t))
+}
+
+// At returns the matrix element m[i][j], laid out as in Allegro: i selects
+// the column and j the row, so the translation is held in m[3][0], m[3][1]
+// and m[3][2].
+func (t *Transform) At(i, j int) float32 {
+	return float32(t.m[i][j])
+}
+
+// Set sets the matrix element m[i][j]; see At.
+func (t *Transform) Set(i, j int, v float32) {
+	t.m[i][j] = C.float(v)
+}
+
 // Transform a pair of coordinates.
 //
 // See https://liballeg.org/a5docs/5.2.6/transformations.html#al_transform_coordinates
 func (t *Transform) Coordinates(x, y float32) (float32, float32) {
 	var cx, cy = C.float(x), C.float(y)
 	C.al_transform_coordinates((*C.ALLEGRO_TRANSFORM)(t), &cx, &cy)
-	return float32(x), float32(y)
+	return float32(cx), float32(cy)
+}
+
+// Transforms x, y, z by the given transform.
+//
+// See https://liballeg.org/a5docs/5.2.6/transformations.html#al_transform_coordinates_3d
+func (t *Transform) Coordinates3D(x, y, z float32) (float32, float32, float32) {
+	var cx, cy, cz = C.float(x), C.float(y), C.float(z)
+	C.al_transform_coordinates_3d((*C.ALLEGRO_TRANSFORM)(t), &cx, &cy, &cz)
+	return float32(cx), float32(cy), float32(cz)
+}
+
+// Transforms x, y, z as homogeneous coordinates. This is the same as using
+// al_transform_coordinates_4d with the w coordinate set to 1, then dividing
+// x, y, z by the resulting w. This will provide the same normalized
+// coordinates Allegro will draw to when a projective transform is in effect
+// as set with al_use_projection_transform. To get the actual pixel
+// coordinates from those translate and scale like so (w and h would be the
+// pixel dimensions of the target bitmap):
+//
+//	x = w / 2 + x * w / 2
+//	y = h / 2 - y * h / 2
+//
+// See https://liballeg.org/a5docs/5.2.6/transformations.html#al_transform_coordinates_3d_projective
+func (t *Transform) Coordinates3DProjective(x, y, z float32) (float32, float32, float32) {
+	var cx, cy, cz = C.float(x), C.float(y), C.float(z)
+	C.al_transform_coordinates_3d_projective((*C.ALLEGRO_TRANSFORM)(t), &cx, &cy, &cz)
+	return float32(cx), float32(cy), float32(cz)
+}
+
+// Transforms x, y, z, w by the given transform.
+//
+// See https://liballeg.org/a5docs/5.2.6/transformations.html#al_transform_coordinates_4d
+func (t *Transform) Coordinates4D(x, y, z, w float32) (float32, float32, float32, float32) {
+	var cx, cy, cz, cw = C.float(x), C.float(y), C.float(z), C.float(w)
+	C.al_transform_coordinates_4d((*C.ALLEGRO_TRANSFORM)(t), &cx, &cy, &cz, &cw)
+	return float32(cx), float32(cy), float32(cz), float32(cw)
 }
 
 // Compose (combine) two transformations by a matrix multiplication.
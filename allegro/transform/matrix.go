@@ -0,0 +1,242 @@
+// Package transform provides a pure-Go mirror of allegro.Transform.
+//
+// Matrix has the same memory layout as ALLEGRO_TRANSFORM and its methods
+// follow the same formulas as Allegro's, but none of them call into C or need
+// Allegro to be running, so they are safe to use from any goroutine. Convert
+// to an allegro.Transform with allegro.TransformFromMatrix when it's time to
+// draw.
+package transform
+
+import "math"
+
+// Matrix is a 4x4 transformation matrix laid out exactly like Allegro's: m[i]
+// is the i'th column, so the translation is held in m[3][0], m[3][1] and
+// m[3][2]. A Matrix can be converted to and from [4][4]float32 directly.
+type Matrix [4][4]float32
+
+// Identity returns the identity matrix.
+func Identity() Matrix {
+	return Matrix{{1, 0, 0, 0}, {0, 1, 0, 0}, {0, 0, 1, 0}, {0, 0, 0, 1}}
+}
+
+// Build returns the equivalent of an identity matrix that has been rotated,
+// scaled and translated, in that order; see allegro.BuildTransform.
+func Build(x, y, sx, sy, theta float32) Matrix {
+	s, c := sincos(theta)
+	return Matrix{
+		{sx * c, sy * s, 0, 0},
+		{sx * -s, sy * c, 0, 0},
+		{0, 0, 1, 0},
+		{x, y, 0, 1},
+	}
+}
+
+// BuildCamera returns a matrix transforming world space to the space of a
+// camera at the given position, looking towards look, with up as the
+// approximate up direction; see allegro.BuildCameraTransform.
+func BuildCamera(positionX, positionY, positionZ, lookX, lookY, lookZ, upX, upY, upZ float32) Matrix {
+	zx, zy, zz := normalize(positionX-lookX, positionY-lookY, positionZ-lookZ)
+	xx, xy, xz := normalize(upY*zz-upZ*zy, upZ*zx-upX*zz, upX*zy-upY*zx)
+	yx, yy, yz := zy*xz-zz*xy, zz*xx-zx*xz, zx*xy-zy*xx
+
+	m := Identity()
+	m.Translate3D(-positionX, -positionY, -positionZ)
+	m.Compose(&Matrix{
+		{xx, yx, zx, 0},
+		{xy, yy, zy, 0},
+		{xz, yz, zz, 0},
+		{0, 0, 0, 1},
+	})
+	return m
+}
+
+// Identity resets m to the identity matrix.
+func (m *Matrix) Identity() {
+	*m = Identity()
+}
+
+// Translate applies a translation to m.
+func (m *Matrix) Translate(x, y float32) {
+	m[3][0] += x
+	m[3][1] += y
+}
+
+// Translate3D applies a 3D translation to m.
+func (m *Matrix) Translate3D(x, y, z float32) {
+	m[3][0] += x
+	m[3][1] += y
+	m[3][2] += z
+}
+
+// Rotate applies a rotation by theta radians to m.
+func (m *Matrix) Rotate(theta float32) {
+	s, c := sincos(theta)
+	for _, i := range [...]int{0, 1, 3} {
+		x, y := m[i][0], m[i][1]
+		m[i][0] = x*c - y*s
+		m[i][1] = x*s + y*c
+	}
+}
+
+// Rotate3D applies a rotation of angle radians around the vector x, y, z,
+// which is assumed to be of unit length.
+func (m *Matrix) Rotate3D(x, y, z, angle float32) {
+	s, c := sincos(angle)
+	cc := 1 - c
+	m.Compose(&Matrix{
+		{cc*x*x + c, cc*x*y + z*s, cc*x*z - y*s, 0},
+		{cc*x*y - z*s, cc*y*y + c, cc*z*y + x*s, 0},
+		{cc*x*z + y*s, cc*y*z - x*s, cc*z*z + c, 0},
+		{0, 0, 0, 1},
+	})
+}
+
+// Scale applies a scale to m.
+func (m *Matrix) Scale(sx, sy float32) {
+	for _, i := range [...]int{0, 1, 3} {
+		m[i][0] *= sx
+		m[i][1] *= sy
+	}
+}
+
+// Scale3D applies a 3D scale to m.
+func (m *Matrix) Scale3D(sx, sy, sz float32) {
+	for i := range m {
+		m[i][0] *= sx
+		m[i][1] *= sy
+		m[i][2] *= sz
+	}
+}
+
+// Compose combines m with other, so that m's transformation is applied
+// first.
+func (m *Matrix) Compose(other *Matrix) {
+	var r Matrix
+	for i := range r {
+		for j := range r[i] {
+			r[i][j] = other[0][j]*m[i][0] + other[1][j]*m[i][1] + other[2][j]*m[i][2] + other[3][j]*m[i][3]
+		}
+	}
+	*m = r
+}
+
+// Invert inverts m, treating it as a 2D transformation as Allegro does. Use
+// CheckInverse first if m may be singular.
+func (m *Matrix) Invert() {
+	det := m[0][0]*m[1][1] - m[1][0]*m[0][1]
+	tx, ty := m[3][0], m[3][1]
+	m[3][0] = (m[1][0]*ty - tx*m[1][1]) / det
+	m[3][1] = (tx*m[0][1] - m[0][0]*ty) / det
+
+	m[0][0], m[1][1] = m[1][1]/det, m[0][0]/det
+	m[0][1] = -m[0][1] / det
+	m[1][0] = -m[1][0] / det
+}
+
+// CheckInverse reports whether m has an inverse, using the given tolerance;
+// see allegro.Transform.CheckInverse.
+func (m *Matrix) CheckInverse(tol float32) bool {
+	det := abs(m[0][0]*m[1][1] - m[1][0]*m[0][1])
+	c0 := abs(m[0][0]) + abs(m[0][1])
+	c1 := abs(m[1][0]) + abs(m[1][1])
+	c3 := abs(m[3][0]) + abs(m[3][1]) + 1
+	norm := float32(math.Max(math.Max(1, float64(c0)), math.Max(float64(c1), float64(c3))))
+	return det > tol*norm
+}
+
+// Transpose transposes m.
+func (m *Matrix) Transpose() {
+	for i := 0; i < 4; i++ {
+		for j := i + 1; j < 4; j++ {
+			m[i][j], m[j][i] = m[j][i], m[i][j]
+		}
+	}
+}
+
+// Orthographic combines m with an orthographic projection; see
+// allegro.Transform.Orthographic.
+func (m *Matrix) Orthographic(left, top, n, right, bottom, f float32) {
+	dx, dy, dz := right-left, top-bottom, f-n
+	p := Identity()
+	p[0][0] = 2 / dx
+	p[1][1] = 2 / dy
+	p[2][2] = 2 / dz
+	p[3][0] = -(right + left) / dx
+	p[3][1] = -(top + bottom) / dy
+	p[3][2] = -(f + n) / dz
+	m.Compose(&p)
+}
+
+// Perspective combines m with a perspective projection; see
+// allegro.Transform.Perspective.
+func (m *Matrix) Perspective(left, top, n, right, bottom, f float32) {
+	dx, dy, dz := right-left, top-bottom, f-n
+	p := Identity()
+	p[0][0] = 2 * n / dx
+	p[1][1] = 2 * n / dy
+	p[2][0] = (right + left) / dx
+	p[2][1] = (top + bottom) / dy
+	p[2][2] = -(f + n) / dz
+	p[2][3] = -1
+	p[3][2] = -2 * f * n / dz
+	p[3][3] = 0
+	m.Compose(&p)
+}
+
+// Coordinates transforms a pair of coordinates.
+func (m *Matrix) Coordinates(x, y float32) (float32, float32) {
+	return x*m[0][0] + y*m[1][0] + m[3][0],
+		x*m[0][1] + y*m[1][1] + m[3][1]
+}
+
+// Coordinates3D transforms a 3D point.
+func (m *Matrix) Coordinates3D(x, y, z float32) (float32, float32, float32) {
+	return x*m[0][0] + y*m[1][0] + z*m[2][0] + m[3][0],
+		x*m[0][1] + y*m[1][1] + z*m[2][1] + m[3][1],
+		x*m[0][2] + y*m[1][2] + z*m[2][2] + m[3][2]
+}
+
+// Coordinates4D transforms homogeneous coordinates.
+func (m *Matrix) Coordinates4D(x, y, z, w float32) (float32, float32, float32, float32) {
+	return x*m[0][0] + y*m[1][0] + z*m[2][0] + w*m[3][0],
+		x*m[0][1] + y*m[1][1] + z*m[2][1] + w*m[3][1],
+		x*m[0][2] + y*m[1][2] + z*m[2][2] + w*m[3][2],
+		x*m[0][3] + y*m[1][3] + z*m[2][3] + w*m[3][3]
+}
+
+// Coordinates3DProjective transforms a 3D point and divides the result by
+// its w coordinate, giving normalized device coordinates under a projection.
+func (m *Matrix) Coordinates3DProjective(x, y, z float32) (float32, float32, float32) {
+	x, y, z, w := m.Coordinates4D(x, y, z, 1)
+	return x / w, y / w, z / w
+}
+
+// Point is a 2D point, laid out like primitives.Point.
+type Point struct {
+	X, Y float32
+}
+
+// TransformPoints transforms each point in place.
+func (m *Matrix) TransformPoints(points []Point) {
+	for i := range points {
+		p := &points[i]
+		p.X, p.Y = m.Coordinates(p.X, p.Y)
+	}
+}
+
+func sincos(theta float32) (float32, float32) {
+	s, c := math.Sincos(float64(theta))
+	return float32(s), float32(c)
+}
+
+func normalize(x, y, z float32) (float32, float32, float32) {
+	l := float32(math.Sqrt(float64(x*x + y*y + z*z)))
+	if l == 0 {
+		return x, y, z
+	}
+	return x / l, y / l, z / l
+}
+
+func abs(v float32) float32 {
+	return float32(math.Abs(float64(v)))
+}
@@ -0,0 +1,69 @@
+package transform
+
+import (
+	"math"
+	"testing"
+)
+
+func near(a, b float32) bool {
+	return math.Abs(float64(a-b)) < 1e-5
+}
+
+func TestBuild(t *testing.T) {
+	want := Identity()
+	want.Rotate(0.5)
+	want.Scale(2, 3)
+	want.Translate(10, 20)
+	got := Build(10, 20, 2, 3, 0.5)
+	for i := range got {
+		for j := range got[i] {
+			if !near(got[i][j], want[i][j]) {
+				t.Fatalf("Build = %v, want %v", got, want)
+			}
+		}
+	}
+}
+
+func TestRotate3DMatchesRotate(t *testing.T) {
+	a, b := Build(5, 6, 1, 2, 0.3), Build(5, 6, 1, 2, 0.3)
+	a.Rotate(1.2)
+	b.Rotate3D(0, 0, 1, 1.2)
+	x1, y1 := a.Coordinates(3, 4)
+	x2, y2 := b.Coordinates(3, 4)
+	if !near(x1, x2) || !near(y1, y2) {
+		t.Errorf("Rotate gives (%v, %v), Rotate3D gives (%v, %v)", x1, y1, x2, y2)
+	}
+}
+
+func TestInvert(t *testing.T) {
+	m := Build(10, -4, 2, 0.5, 1)
+	if !m.CheckInverse(1e-7) {
+		t.Fatal("expected an inverse")
+	}
+	inv := m
+	inv.Invert()
+	points := []Point{{X: 1, Y: 2}, {X: -7, Y: 3}}
+	m.TransformPoints(points)
+	inv.TransformPoints(points)
+	if !near(points[0].X, 1) || !near(points[0].Y, 2) || !near(points[1].X, -7) || !near(points[1].Y, 3) {
+		t.Errorf("round trip gave %v", points)
+	}
+
+	var singular Matrix
+	if singular.CheckInverse(1e-7) {
+		t.Error("zero matrix reported as invertible")
+	}
+}
+
+func TestProjective(t *testing.T) {
+	m := Identity()
+	m.Perspective(-1, 1, 1, 1, -1, 100)
+	x, y, _ := m.Coordinates3DProjective(0.5, -0.5, -1)
+	if !near(x, 0.5) || !near(y, -0.5) {
+		t.Errorf("got (%v, %v) on the near plane", x, y)
+	}
+	x, y, _ = m.Coordinates3DProjective(0.5, -0.5, -2)
+	if !near(x, 0.25) || !near(y, -0.25) {
+		t.Errorf("got (%v, %v) at twice the distance", x, y)
+	}
+}
@@ -0,0 +1,27 @@
+package allegro_test
+
+import (
+	"testing"
+	"unsafe"
+
+	"github.com/dradtke/go-allegro/allegro"
+	"github.com/dradtke/go-allegro/allegro/transform"
+)
+
+func TestMatrixLayout(t *testing.T) {
+	if unsafe.Sizeof(transform.Matrix{}) != unsafe.Sizeof(allegro.Transform{}) {
+		t.Fatalf("Matrix is %d bytes, Transform is %d", unsafe.Sizeof(transform.Matrix{}), unsafe.Sizeof(allegro.Transform{}))
+	}
+}
+
+func TestTransformCoordinates(t *testing.T) {
+	tr := allegro.IdentityTransform()
+	tr.Translate(10, -5)
+	tr.Scale(2, 3)
+	if x, y := tr.Coordinates(1, 2); x != 22 || y != -9 {
+		t.Errorf("got (%v, %v), want (22, -9)", x, y)
+	}
+	if x, y, z := tr.Coordinates3D(1, 2, 4); x != 22 || y != -9 || z != 4 {
+		t.Errorf("got (%v, %v, %v), want (22, -9, 4)", x, y, z)
+	}
+}
@@ -0,0 +1,170 @@
+// Package viewport renders at a fixed logical resolution and scales the
+// result to fit a display, adding letterbox bars where the aspect ratios
+// differ.
+//
+//	v, err := viewport.New(display, 320, 180, viewport.Integer)
+//	...
+//	case allegro.DisplayResizeEvent:
+//		v.HandleEvent(e)
+//	case allegro.MouseAxesEvent:
+//		x, y := v.ToLogical(float32(e.X()), float32(e.Y()))
+//	...
+//	v.Draw(func() {
+//		// draw at 320x180
+//	})
+//	allegro.FlipDisplay()
+package viewport
+
+import (
+	"errors"
+	"math"
+
+	"github.com/dradtke/go-allegro/allegro"
+)
+
+// Scaling is the strategy used to fit the logical resolution to the display.
+type Scaling int
+
+const (
+	// Integer scales by the largest whole number that fits, which keeps
+	// pixel art crisp. If the display is smaller than the logical size, it
+	// falls back to Fit.
+	Integer Scaling = iota
+	// Fit scales by the largest factor that fits, keeping the aspect ratio.
+	Fit
+	// Stretch fills the whole display, distorting the aspect ratio if
+	// necessary.
+	Stretch
+)
+
+// Viewport is an offscreen bitmap of a fixed logical size that is scaled onto
+// a display's backbuffer.
+type Viewport struct {
+	// Scaling is the strategy used to fit the buffer to the display. Call
+	// Update after changing it.
+	Scaling Scaling
+
+	// BarColor is used to clear the parts of the display outside of the
+	// scaled buffer.
+	BarColor allegro.Color
+
+	display *allegro.Display
+	buffer  *allegro.Bitmap
+	width   int
+	height  int
+
+	x, y, w, h float32
+}
+
+// New creates a viewport with the given logical size for a display. The
+// buffer is created with the current new bitmap flags, so set MIN_LINEAR and
+// MAG_LINEAR beforehand if smooth scaling is wanted.
+func New(display *allegro.Display, width, height int, scaling Scaling) (*Viewport, error) {
+	if width <= 0 || height <= 0 {
+		return nil, errors.New("invalid viewport size")
+	}
+	buffer := allegro.CreateBitmap(width, height)
+	if buffer == nil {
+		return nil, errors.New("failed to create viewport buffer")
+	}
+	v := &Viewport{
+		Scaling:  scaling,
+		BarColor: allegro.MapRGB(0, 0, 0),
+		display:  display,
+		buffer:   buffer,
+		width:    width,
+		height:   height,
+	}
+	v.Update()
+	return v, nil
+}
+
+// Destroy frees the viewport's buffer.
+func (v *Viewport) Destroy() {
+	if v.buffer != nil {
+		v.buffer.Destroy()
+		v.buffer = nil
+	}
+}
+
+// Buffer returns the bitmap that the logical resolution is drawn to.
+func (v *Viewport) Buffer() *allegro.Bitmap {
+	return v.buffer
+}
+
+// Size returns the logical resolution.
+func (v *Viewport) Size() (int, int) {
+	return v.width, v.height
+}
+
+// Rect returns the area of the display covered by the scaled buffer.
+func (v *Viewport) Rect() (x, y, w, h float32) {
+	return v.x, v.y, v.w, v.h
+}
+
+// Update recalculates the scaled area from the display's current size.
+func (v *Viewport) Update() {
+	v.x, v.y, v.w, v.h = layout(v.display.Width(), v.display.Height(), v.width, v.height, v.Scaling)
+}
+
+// HandleEvent acknowledges a resize event for the viewport's display and
+// updates the scaled area. It reports whether the event was handled; other
+// events are ignored.
+func (v *Viewport) HandleEvent(event interface{}) bool {
+	e, ok := event.(allegro.DisplayResizeEvent)
+	if !ok || e.Source() != v.display {
+		return false
+	}
+	v.display.AcknowledgeResize()
+	v.Update()
+	return true
+}
+
+// Draw calls f with the buffer as the target bitmap, then draws the buffer
+// scaled onto the display's backbuffer, filling the rest with BarColor.
+func (v *Viewport) Draw(f func()) {
+	v.buffer.AsTarget(f)
+	v.display.Backbuffer().AsTarget(func() {
+		old := allegro.CurrentTransform().Copy()
+		allegro.UseTransform(allegro.IdentityTransform())
+		allegro.ClearToColor(v.BarColor)
+		v.buffer.DrawScaled(0, 0, float32(v.width), float32(v.height), v.x, v.y, v.w, v.h, allegro.FLIP_NONE)
+		allegro.UseTransform(old)
+	})
+}
+
+// ToLogical converts display coordinates, such as the mouse position, into
+// logical coordinates. The result lies outside of the logical size if the
+// point is over a letterbox bar.
+func (v *Viewport) ToLogical(x, y float32) (float32, float32) {
+	if v.w == 0 || v.h == 0 {
+		return 0, 0
+	}
+	return (x - v.x) * float32(v.width) / v.w, (y - v.y) * float32(v.height) / v.h
+}
+
+// ToDisplay converts logical coordinates into display coordinates.
+func (v *Viewport) ToDisplay(x, y float32) (float32, float32) {
+	return v.x + x*v.w/float32(v.width), v.y + y*v.h/float32(v.height)
+}
+
+// Contains reports whether the display coordinates x, y are over the scaled
+// buffer rather than a letterbox bar.
+func (v *Viewport) Contains(x, y float32) bool {
+	return x >= v.x && y >= v.y && x < v.x+v.w && y < v.y+v.h
+}
+
+// layout returns where a buffer of the given logical size goes on a display.
+func layout(dw, dh, lw, lh int, scaling Scaling) (x, y, w, h float32) {
+	if scaling == Stretch {
+		return 0, 0, float32(dw), float32(dh)
+	}
+	scale := math.Min(float64(dw)/float64(lw), float64(dh)/float64(lh))
+	if scaling == Integer && scale >= 1 {
+		scale = math.Floor(scale)
+	}
+	w, h = float32(float64(lw)*scale), float32(float64(lh)*scale)
+	x = float32(math.Floor(float64(float32(dw)-w) / 2))
+	y = float32(math.Floor(float64(float32(dh)-h) / 2))
+	return x, y, w, h
+}
@@ -0,0 +1,37 @@
+package viewport
+
+import "testing"
+
+func TestLayout(t *testing.T) {
+	tests := []struct {
+		dw, dh     int
+		scaling    Scaling
+		x, y, w, h float32
+	}{
+		{1920, 1080, Integer, 0, 0, 1920, 1080},
+		{1366, 768, Integer, 43, 24, 1280, 720},
+		{1366, 768, Fit, 0, 0, 1365.3334, 768},
+		{1000, 1000, Fit, 0, 218, 1000, 562.5},
+		{200, 100, Integer, 11, 0, 177.77777, 100},
+		{800, 600, Stretch, 0, 0, 800, 600},
+	}
+	for _, test := range tests {
+		x, y, w, h := layout(test.dw, test.dh, 320, 180, test.scaling)
+		if x != test.x || y != test.y || w != test.w || h != test.h {
+			t.Errorf("layout(%d, %d, %d) = %v, %v, %v, %v, want %v, %v, %v, %v",
+				test.dw, test.dh, test.scaling, x, y, w, h, test.x, test.y, test.w, test.h)
+		}
+	}
+}
+
+func TestToLogical(t *testing.T) {
+	v := &Viewport{width: 320, height: 180}
+	v.x, v.y, v.w, v.h = layout(1366, 768, 320, 180, Integer)
+	x, y := v.ToLogical(v.ToDisplay(100, 50))
+	if x != 100 || y != 50 {
+		t.Errorf("round trip gave (%v, %v)", x, y)
+	}
+	if v.Contains(100, 10) {
+		t.Error("letterbox bar reported as inside the viewport")
+	}
+}
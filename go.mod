@@ -3,6 +3,7 @@ module github.com/dradtke/go-allegro
 go 1.14
 
 require (
+	github.com/klauspost/compress v1.11.13
 	golang.org/x/net v0.0.0-20200506145744-7e3656a0809f // indirect
 	golang.org/x/sys v0.0.0-20200509044756-6aff5f38e54f
 )